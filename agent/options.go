@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/y0ug/llmhaven/chat"
+)
+
+// BeforeToolCallFunc is called before a tool is executed. Returning an error
+// skip the execution and the error is sent back to the model as tool result.
+type BeforeToolCallFunc func(ctx context.Context, call *chat.MessageContent) error
+
+// AfterToolCallFunc is called once a tool returned, err is the handler error if any
+type AfterToolCallFunc func(
+	ctx context.Context,
+	call *chat.MessageContent,
+	result *chat.MessageContent,
+	err error,
+)
+
+type Option func(*Runner)
+
+// WithMaxIterations sets how many round trip with the model are allowed
+// before giving up with ErrMaxIterations
+func WithMaxIterations(n int) Option {
+	return func(r *Runner) {
+		r.maxIterations = n
+	}
+}
+
+// WithToolTimeout sets the timeout applied to each tool call, 0 disable it
+func WithToolTimeout(d time.Duration) Option {
+	return func(r *Runner) {
+		r.toolTimeout = d
+	}
+}
+
+// WithMaxParallel limits the number of tool calls executed concurrently,
+// 1 run them sequentially and 0 means no limit
+func WithMaxParallel(n int) Option {
+	return func(r *Runner) {
+		r.maxParallel = n
+	}
+}
+
+// WithBeforeToolCall adds a hook fired before each tool call
+func WithBeforeToolCall(hooks ...BeforeToolCallFunc) Option {
+	return func(r *Runner) {
+		r.before = append(r.before, hooks...)
+	}
+}
+
+// WithAfterToolCall adds a hook fired after each tool call
+func WithAfterToolCall(hooks ...AfterToolCallFunc) Option {
+	return func(r *Runner) {
+		r.after = append(r.after, hooks...)
+	}
+}
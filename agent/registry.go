@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/y0ug/llmhaven/chat"
+)
+
+// Handler executes a tool call. The input is the raw JSON arguments sent by
+// the model, the returned string is used as the tool_result content.
+type Handler func(ctx context.Context, input json.RawMessage) (string, error)
+
+// Registry maps tool names to their definition and Go handler
+type Registry struct {
+	mu       sync.RWMutex
+	tools    map[string]chat.Tool
+	handlers map[string]Handler
+	order    []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		tools:    make(map[string]chat.Tool),
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Register adds a tool, registering the same name twice replace the previous one
+func (r *Registry) Register(tool chat.Tool, handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tools[tool.Name]; !ok {
+		r.order = append(r.order, tool.Name)
+	}
+	r.tools[tool.Name] = tool
+	r.handlers[tool.Name] = handler
+}
+
+// RegisterTool adds tools implementing chat.CallableTool like chat.NewTypedTool
+func (r *Registry) RegisterTool(tools ...chat.CallableTool) {
+	for _, tool := range tools {
+		r.Register(tool.Definition(), tool.Call)
+	}
+}
+
+// Handler returns the handler registered for name
+func (r *Registry) Handler(name string) (Handler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	h, ok := r.handlers[name]
+	return h, ok
+}
+
+// Tools returns the tool definitions in registration order, ready for
+// chat.WithTools
+func (r *Registry) Tools() []chat.Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tools := make([]chat.Tool, 0, len(r.order))
+	for _, name := range r.order {
+		tools = append(tools, r.tools[name])
+	}
+	return tools
+}
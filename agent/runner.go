@@ -0,0 +1,251 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/y0ug/llmhaven/chat"
+)
+
+var ErrMaxIterations = errors.New("agent: max iterations reached")
+
+// Runner drives the tool loop: send the conversation, execute the requested
+// tools, append the results and repeat until the model stop asking for tools.
+type Runner struct {
+	provider      chat.Provider
+	registry      *Registry
+	maxIterations int
+	maxParallel   int
+	toolTimeout   time.Duration
+	before        []BeforeToolCallFunc
+	after         []AfterToolCallFunc
+}
+
+// Result of a run, Messages hold the whole conversation including the final answer
+type Result struct {
+	Response   *chat.ChatResponse
+	Messages   []*chat.ChatMessage
+	Iterations int
+	Usage      chat.ChatUsage
+}
+
+func NewRunner(provider chat.Provider, registry *Registry, opts ...Option) *Runner {
+	r := &Runner{
+		provider:      provider,
+		registry:      registry,
+		maxIterations: 10,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run executes the loop with provider.Send
+func (r *Runner) Run(ctx context.Context, params chat.ChatParams) (*Result, error) {
+	return r.run(ctx, params, func(ctx context.Context, params chat.ChatParams) (*chat.ChatResponse, error) {
+		return r.provider.Send(ctx, params)
+	})
+}
+
+// RunStream executes the loop with provider.Stream, every event received is
+// forwarded to onEvent (can be nil)
+func (r *Runner) RunStream(
+	ctx context.Context,
+	params chat.ChatParams,
+	onEvent func(chat.EventStream),
+) (*Result, error) {
+	return r.run(ctx, params, func(ctx context.Context, params chat.ChatParams) (*chat.ChatResponse, error) {
+		stream, err := r.provider.Stream(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		defer stream.Close()
+
+		var msg *chat.ChatResponse
+		for stream.Next() {
+			evt := stream.Current()
+			if onEvent != nil {
+				onEvent(evt)
+			}
+			if evt.Type == "error" {
+				return nil, fmt.Errorf("stream error: %v", evt.Delta)
+			}
+			if evt.Message != nil {
+				msg = evt.Message
+			}
+		}
+		if err := stream.Err(); err != nil {
+			return nil, err
+		}
+		if msg == nil {
+			return nil, fmt.Errorf("stream ended without message")
+		}
+		return msg, nil
+	})
+}
+
+func (r *Runner) run(
+	ctx context.Context,
+	params chat.ChatParams,
+	send func(context.Context, chat.ChatParams) (*chat.ChatResponse, error),
+) (*Result, error) {
+	if len(params.Tools) == 0 && r.registry != nil {
+		params.Tools = r.registry.Tools()
+	}
+	// Don't modify the caller slice
+	params.Messages = append([]*chat.ChatMessage{}, params.Messages...)
+
+	result := &Result{}
+	for result.Iterations < r.maxIterations {
+		result.Iterations++
+
+		resp, err := send(ctx, params)
+		if err != nil {
+			return result, err
+		}
+		result.Response = resp
+		addUsage(&result.Usage, resp.Usage)
+
+		msg := resp.ToMessageParams()
+		if msg == nil {
+			result.Messages = params.Messages
+			return result, fmt.Errorf("response without choice")
+		}
+		params.Messages = append(params.Messages, msg)
+		result.Messages = params.Messages
+
+		calls := toolCalls(msg)
+		if len(calls) == 0 {
+			return result, nil
+		}
+
+		toolResults := r.executeAll(ctx, calls)
+		params.Messages = append(params.Messages, chat.NewMessage("user", toolResults...))
+		result.Messages = params.Messages
+	}
+	return result, ErrMaxIterations
+}
+
+func toolCalls(msg *chat.ChatMessage) []*chat.MessageContent {
+	calls := make([]*chat.MessageContent, 0)
+	for _, content := range msg.Content {
+		if content.Type == chat.ContentTypeToolUse {
+			calls = append(calls, content)
+		}
+	}
+	return calls
+}
+
+// executeAll runs the calls, results are returned in the same order
+func (r *Runner) executeAll(
+	ctx context.Context,
+	calls []*chat.MessageContent,
+) []*chat.MessageContent {
+	results := make([]*chat.MessageContent, len(calls))
+
+	limit := r.maxParallel
+	if limit <= 0 || limit > len(calls) {
+		limit = len(calls)
+	}
+	sem := make(chan struct{}, limit)
+
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, call *chat.MessageContent) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = r.execute(ctx, call)
+		}(i, call)
+	}
+	wg.Wait()
+	return results
+}
+
+func (r *Runner) execute(ctx context.Context, call *chat.MessageContent) *chat.MessageContent {
+	var (
+		output string
+		err    error
+	)
+
+	for _, hook := range r.before {
+		if err = hook(ctx, call); err != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		output, err = r.call(ctx, call)
+	}
+
+	var result *chat.MessageContent
+	if err != nil {
+		result = chat.NewToolErrorContent(call.ID, err.Error())
+	} else {
+		result = chat.NewToolResultContent(call.ID, output)
+	}
+
+	for _, hook := range r.after {
+		hook(ctx, call, result, err)
+	}
+	return result
+}
+
+func (r *Runner) call(ctx context.Context, call *chat.MessageContent) (string, error) {
+	var handler Handler
+	ok := false
+	if r.registry != nil {
+		handler, ok = r.registry.Handler(call.Name)
+	}
+	if !ok {
+		return "", fmt.Errorf("unknown tool: %s", call.Name)
+	}
+
+	if r.toolTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.toolTimeout)
+		defer cancel()
+	}
+
+	input := call.Input
+	if len(input) == 0 && len(call.InputJson) > 0 {
+		input = json.RawMessage(call.InputJson)
+	}
+
+	type ret struct {
+		output string
+		err    error
+	}
+	// The handler may ignore the context, we don't wait for it past the deadline
+	done := make(chan ret, 1)
+	go func() {
+		output, err := handler(ctx, input)
+		done <- ret{output, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return "", fmt.Errorf("tool %s: %w", call.Name, ctx.Err())
+	case r := <-done:
+		return r.output, r.err
+	}
+}
+
+func addUsage(total *chat.ChatUsage, usage *chat.ChatUsage) {
+	if usage == nil {
+		return
+	}
+	total.InputTokens += usage.InputTokens
+	total.InputAudioTokens += usage.InputAudioTokens
+	total.InputCachedTokens += usage.InputCachedTokens
+	total.InputCacheCreationTokens += usage.InputCacheCreationTokens
+	total.OutputTokens += usage.OutputTokens
+	total.OutputAudioTokens += usage.OutputAudioTokens
+	total.OutputReasoningTokens += usage.OutputReasoningTokens
+}
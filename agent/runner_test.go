@@ -0,0 +1,216 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/http/streaming"
+	"go.uber.org/mock/gomock"
+)
+
+func toolUseResponse(calls ...*chat.MessageContent) *chat.ChatResponse {
+	return &chat.ChatResponse{
+		Choice: []chat.ChatChoice{
+			{Role: "assistant", Content: calls, StopReason: "tool_use"},
+		},
+		Usage: &chat.ChatUsage{InputTokens: 10, OutputTokens: 5},
+	}
+}
+
+func textResponse(text string) *chat.ChatResponse {
+	return &chat.ChatResponse{
+		Choice: []chat.ChatChoice{
+			{
+				Role:       "assistant",
+				Content:    []*chat.MessageContent{chat.NewTextContent(text)},
+				StopReason: "end_turn",
+			},
+		},
+		Usage: &chat.ChatUsage{InputTokens: 20, OutputTokens: 3},
+	}
+}
+
+func newWeatherRegistry(calls *int32) *Registry {
+	registry := NewRegistry()
+	registry.Register(chat.Tool{Name: "get_weather"},
+		func(ctx context.Context, input json.RawMessage) (string, error) {
+			atomic.AddInt32(calls, 1)
+			var in struct {
+				Location string `json:"location"`
+			}
+			if err := json.Unmarshal(input, &in); err != nil {
+				return "", err
+			}
+			return "Sunny in " + in.Location, nil
+		})
+	return registry
+}
+
+func TestRunner_Run(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	provider := chat.NewMockProvider(ctrl)
+
+	var calls int32
+	registry := newWeatherRegistry(&calls)
+
+	gomock.InOrder(
+		provider.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, params chat.ChatParams) (*chat.ChatResponse, error) {
+				assert.Len(t, params.Tools, 1)
+				return toolUseResponse(
+					chat.NewToolUseContent("1", "get_weather", json.RawMessage(`{"location":"Paris"}`)),
+					chat.NewToolUseContent("2", "get_weather", json.RawMessage(`{"location":"Rome"}`)),
+					chat.NewToolUseContent("3", "unknown", json.RawMessage(`{}`)),
+				), nil
+			}),
+		provider.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, params chat.ChatParams) (*chat.ChatResponse, error) {
+				results := params.Messages[len(params.Messages)-1].Content
+				if assert.Len(t, results, 3) {
+					assert.Equal(t, "1", results[0].ToolUseID)
+					assert.Equal(t, "Sunny in Paris", results[0].Content)
+					assert.Equal(t, "Sunny in Rome", results[1].Content)
+					assert.True(t, results[2].IsError)
+				}
+				return textResponse("It's sunny"), nil
+			}),
+	)
+
+	var before, after int32
+	runner := NewRunner(provider, registry,
+		WithBeforeToolCall(func(ctx context.Context, call *chat.MessageContent) error {
+			atomic.AddInt32(&before, 1)
+			return nil
+		}),
+		WithAfterToolCall(func(ctx context.Context, call, result *chat.MessageContent, err error) {
+			atomic.AddInt32(&after, 1)
+		}),
+	)
+
+	params := chat.NewChatParams(chat.WithMessages(chat.NewUserMessage("Weather?")))
+	result, err := runner.Run(context.Background(), *params)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, 2, result.Iterations)
+	assert.Equal(t, "It's sunny", result.Response.Choice[0].Content[0].Text)
+	assert.Len(t, result.Messages, 4)
+	assert.Equal(t, 30, result.Usage.InputTokens)
+	assert.Equal(t, int32(2), calls)
+	assert.Equal(t, int32(3), before)
+	assert.Equal(t, int32(3), after)
+	assert.Len(t, params.Messages, 1, "caller messages must not be modified")
+}
+
+func TestRunner_MaxIterations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	provider := chat.NewMockProvider(ctrl)
+
+	var calls int32
+	provider.EXPECT().Send(gomock.Any(), gomock.Any()).Return(
+		toolUseResponse(
+			chat.NewToolUseContent("1", "get_weather", json.RawMessage(`{"location":"Paris"}`)),
+		), nil).Times(3)
+
+	runner := NewRunner(provider, newWeatherRegistry(&calls), WithMaxIterations(3))
+	_, err := runner.Run(context.Background(), chat.ChatParams{})
+	assert.True(t, errors.Is(err, ErrMaxIterations))
+	assert.Equal(t, int32(3), calls)
+}
+
+func TestRunner_ToolTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	provider := chat.NewMockProvider(ctrl)
+
+	registry := NewRegistry()
+	registry.Register(chat.Tool{Name: "slow"},
+		func(ctx context.Context, input json.RawMessage) (string, error) {
+			time.Sleep(time.Second)
+			return "done", nil
+		})
+
+	gomock.InOrder(
+		provider.EXPECT().Send(gomock.Any(), gomock.Any()).Return(
+			toolUseResponse(chat.NewToolUseContent("1", "slow", nil)), nil),
+		provider.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, params chat.ChatParams) (*chat.ChatResponse, error) {
+				result := params.Messages[len(params.Messages)-1].Content[0]
+				assert.True(t, result.IsError)
+				assert.Contains(t, result.Content, "deadline exceeded")
+				return textResponse("ok"), nil
+			}),
+	)
+
+	runner := NewRunner(provider, registry, WithToolTimeout(10*time.Millisecond))
+	_, err := runner.Run(context.Background(), chat.ChatParams{})
+	assert.NoError(t, err)
+}
+
+func TestRunner_RunStream(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	provider := chat.NewMockProvider(ctrl)
+
+	newStream := func(resp *chat.ChatResponse) streaming.Streamer[chat.EventStream] {
+		stream := streaming.NewMockStreamer[chat.EventStream](ctrl)
+		gomock.InOrder(
+			stream.EXPECT().Next().Return(true),
+			stream.EXPECT().Current().Return(chat.EventStream{Type: "text_delta", Delta: "..."}),
+			stream.EXPECT().Next().Return(true),
+			stream.EXPECT().Current().Return(chat.EventStream{Type: "message_stop", Message: resp}),
+			stream.EXPECT().Next().Return(false),
+			stream.EXPECT().Err().Return(nil),
+			stream.EXPECT().Close().Return(nil),
+		)
+		return stream
+	}
+
+	var calls int32
+	gomock.InOrder(
+		provider.EXPECT().Stream(gomock.Any(), gomock.Any()).Return(newStream(
+			toolUseResponse(
+				chat.NewToolUseContent("1", "get_weather", json.RawMessage(`{"location":"Paris"}`)),
+			)), nil),
+		provider.EXPECT().Stream(gomock.Any(), gomock.Any()).Return(
+			newStream(textResponse("It's sunny")), nil),
+	)
+
+	events := 0
+	runner := NewRunner(provider, newWeatherRegistry(&calls))
+	result, err := runner.RunStream(context.Background(), chat.ChatParams{},
+		func(evt chat.EventStream) { events++ })
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, 4, events)
+	assert.Equal(t, 2, result.Iterations)
+	assert.Equal(t, int32(1), calls)
+}
+
+func TestRegistry_RegisterTool(t *testing.T) {
+	type input struct {
+		Location string `json:"location"`
+	}
+	registry := NewRegistry()
+	registry.RegisterTool(chat.NewTypedTool("get_weather", "Get the weather",
+		func(ctx context.Context, in input) (string, error) {
+			return "Sunny in " + in.Location, nil
+		}))
+
+	tools := registry.Tools()
+	if assert.Len(t, tools, 1) {
+		assert.Equal(t, "get_weather", tools[0].Name)
+	}
+
+	handler, ok := registry.Handler("get_weather")
+	if assert.True(t, ok) {
+		out, err := handler(context.Background(), json.RawMessage(`{"location":"Paris"}`))
+		assert.NoError(t, err)
+		assert.Equal(t, "Sunny in Paris", out)
+	}
+}
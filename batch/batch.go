@@ -0,0 +1,110 @@
+// Package batch runs many independent chat requests as a job, using the
+// provider batch API when it has one and a local executor otherwise.
+package batch
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/y0ug/llmhaven/chat"
+)
+
+type Status string
+
+const (
+	StatusInProgress Status = "in_progress"
+	StatusCanceling  Status = "canceling"
+	StatusCompleted  Status = "completed"
+	StatusFailed     Status = "failed"
+	StatusCanceled   Status = "canceled"
+	StatusExpired    Status = "expired"
+)
+
+// Request custom id must be unique in the job
+type Request struct {
+	CustomID string          `json:"custom_id"`
+	Params   chat.ChatParams `json:"params"`
+}
+
+// Result holds the response or the error of a request
+type Result struct {
+	CustomID string             `json:"custom_id"`
+	Response *chat.ChatResponse `json:"response,omitempty"`
+	Error    string             `json:"error,omitempty"`
+}
+
+type Job struct {
+	ID        string `json:"id"`
+	Provider  string `json:"provider,omitempty"`
+	Status    Status `json:"status"`
+	Total     int    `json:"total"`
+	Completed int    `json:"completed"`
+	Failed    int    `json:"failed"`
+}
+
+// Done reports if the job reached a final status
+func (j *Job) Done() bool {
+	switch j.Status {
+	case StatusCompleted, StatusFailed, StatusCanceled, StatusExpired:
+		return true
+	}
+	return false
+}
+
+type BatchProvider interface {
+	Submit(ctx context.Context, requests []Request) (*Job, error)
+	Status(ctx context.Context, id string) (*Job, error)
+	// Results returns the results of the finished requests
+	Results(ctx context.Context, id string) ([]Result, error)
+	Cancel(ctx context.Context, id string) (*Job, error)
+}
+
+// Supporter is implemented by providers whose batch support depends on the
+// backend, OpenAI compatible APIs often don't have the batch endpoints
+type Supporter interface {
+	SupportsBatch() bool
+}
+
+// New returns the native batch API of the provider, or a local executor
+// sending the requests with the provider
+func New(provider chat.Provider, opts ...LocalOption) BatchProvider {
+	if bp, ok := provider.(BatchProvider); ok {
+		if s, ok := provider.(Supporter); !ok || s.SupportsBatch() {
+			return bp
+		}
+	}
+	return NewLocal(provider, opts...)
+}
+
+// Wait polls the job status until it's done
+func Wait(
+	ctx context.Context,
+	bp BatchProvider,
+	id string,
+	interval time.Duration,
+) (*Job, error) {
+	for {
+		job, err := bp.Status(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if job.Done() {
+			return job, nil
+		}
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// NewRequests creates the requests with the index as custom id
+func NewRequests(params ...chat.ChatParams) []Request {
+	requests := make([]Request, len(params))
+	for i, p := range params {
+		requests[i] = Request{CustomID: fmt.Sprintf("request-%d", i), Params: p}
+	}
+	return requests
+}
@@ -0,0 +1,381 @@
+package batch
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/y0ug/llmhaven/chat"
+)
+
+type LocalOption func(*Local)
+
+// WithConcurrency sets the number of requests sent in parallel, 4 by default
+func WithConcurrency(n int) LocalOption {
+	return func(l *Local) {
+		if n > 0 {
+			l.concurrency = n
+		}
+	}
+}
+
+// WithStateDir persists the jobs in dir so an interrupted job can be
+// resumed: the job and its requests in <id>.json, the results appended to
+// <id>.results.jsonl as they come
+func WithStateDir(dir string) LocalOption {
+	return func(l *Local) {
+		l.dir = dir
+	}
+}
+
+// Local runs the requests of a job with chat.Provider.Send in background
+// goroutines. Failed requests are recorded as results, requests interrupted
+// by a cancellation are sent again on Resume.
+type Local struct {
+	provider    chat.Provider
+	concurrency int
+	dir         string
+
+	mu   sync.Mutex
+	jobs map[string]*localJob
+}
+
+// localJob is the persisted state of a job, Results is rebuilt from the
+// progress file
+type localJob struct {
+	Job      Job       `json:"job"`
+	Requests []Request `json:"requests"`
+
+	Results  map[string]Result `json:"-"`
+	progress *os.File          // results file of the running job
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+func NewLocal(provider chat.Provider, opts ...LocalOption) *Local {
+	l := &Local{
+		provider:    provider,
+		concurrency: 4,
+		jobs:        make(map[string]*localJob),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+func (l *Local) Submit(ctx context.Context, requests []Request) (*Job, error) {
+	seen := make(map[string]bool, len(requests))
+	for _, r := range requests {
+		if seen[r.CustomID] {
+			return nil, fmt.Errorf("duplicate custom id %s", r.CustomID)
+		}
+		seen[r.CustomID] = true
+	}
+
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	j := &localJob{
+		Job: Job{
+			ID:       "local_" + hex.EncodeToString(b),
+			Provider: "local",
+			Status:   StatusInProgress,
+			Total:    len(requests),
+		},
+		Requests: requests,
+		Results:  make(map[string]Result),
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.save(j); err != nil {
+		return nil, err
+	}
+	l.jobs[j.Job.ID] = j
+	l.start(ctx, j)
+	job := j.Job
+	return &job, nil
+}
+
+// Resume restarts an interrupted or canceled job, the requests without
+// result are sent again. Jobs of a previous process are loaded from the
+// state dir.
+func (l *Local) Resume(ctx context.Context, id string) (*Job, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	j, err := l.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if j.cancel == nil && j.Job.Status != StatusCompleted {
+		j.Job.Status = StatusInProgress
+		l.start(ctx, j)
+	}
+	job := j.Job
+	return &job, nil
+}
+
+func (l *Local) Status(ctx context.Context, id string) (*Job, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	j, err := l.get(id)
+	if err != nil {
+		return nil, err
+	}
+	job := j.Job
+	return &job, nil
+}
+
+// Results returns the results in the requests order, the job can still be
+// running
+func (l *Local) Results(ctx context.Context, id string) ([]Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	j, err := l.get(id)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Result, 0, len(j.Results))
+	for _, r := range j.Requests {
+		if res, ok := j.Results[r.CustomID]; ok {
+			results = append(results, res)
+		}
+	}
+	return results, nil
+}
+
+func (l *Local) Cancel(ctx context.Context, id string) (*Job, error) {
+	l.mu.Lock()
+	j, err := l.get(id)
+	if err != nil {
+		l.mu.Unlock()
+		return nil, err
+	}
+	if j.Job.Done() {
+		job := j.Job
+		l.mu.Unlock()
+		return &job, nil
+	}
+	if j.cancel == nil {
+		// Not running in this process
+		j.Job.Status = StatusCanceled
+		err = l.save(j)
+		job := j.Job
+		l.mu.Unlock()
+		return &job, err
+	}
+	j.Job.Status = StatusCanceling
+	j.cancel()
+	done := j.done
+	l.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return l.Status(ctx, id)
+}
+
+// get returns the job from memory or from the state dir, l.mu must be held
+func (l *Local) get(id string) (*localJob, error) {
+	if j, ok := l.jobs[id]; ok {
+		return j, nil
+	}
+	if l.dir == "" {
+		return nil, fmt.Errorf("unknown job %s", id)
+	}
+	data, err := os.ReadFile(l.path(id))
+	if err != nil {
+		return nil, fmt.Errorf("error loading job %s: %w", id, err)
+	}
+	j := &localJob{}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, fmt.Errorf("error loading job %s: %w", id, err)
+	}
+	if err := l.loadResults(j); err != nil {
+		return nil, fmt.Errorf("error loading job %s: %w", id, err)
+	}
+	l.jobs[id] = j
+	return j, nil
+}
+
+// loadResults reads the progress file of j and counts its results
+func (l *Local) loadResults(j *localJob) error {
+	j.Results = make(map[string]Result)
+	j.Job.Completed, j.Job.Failed = 0, 0
+	f, err := os.Open(l.resultsPath(j.Job.ID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64<<20)
+	for scanner.Scan() {
+		var res Result
+		// The last line is truncated when the process died writing it
+		if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
+			continue
+		}
+		if _, ok := j.Results[res.CustomID]; ok {
+			continue
+		}
+		j.Results[res.CustomID] = res
+		if res.Error != "" {
+			j.Job.Failed++
+		} else {
+			j.Job.Completed++
+		}
+	}
+	return scanner.Err()
+}
+
+// start runs the pending requests, l.mu must be held
+func (l *Local) start(ctx context.Context, j *localJob) {
+	// The job outlives the Submit call
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	j.cancel = cancel
+	j.done = make(chan struct{})
+	if l.dir != "" {
+		// Without it the results are only kept in memory
+		j.progress, _ = os.OpenFile(l.resultsPath(j.Job.ID),
+			os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	}
+
+	pending := make([]Request, 0, len(j.Requests))
+	for _, r := range j.Requests {
+		if _, ok := j.Results[r.CustomID]; !ok {
+			pending = append(pending, r)
+		}
+	}
+
+	go func() {
+		defer close(j.done)
+		defer cancel()
+
+		sem := make(chan struct{}, l.concurrency)
+		var wg sync.WaitGroup
+		for _, r := range pending {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			wg.Add(1)
+			go func(r Request) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				resp, err := l.provider.Send(ctx, r.Params)
+				if err != nil && ctx.Err() != nil {
+					// Interrupted, sent again on resume
+					return
+				}
+				l.record(j, r.CustomID, resp, err)
+			}(r)
+		}
+		wg.Wait()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if ctx.Err() != nil {
+			j.Job.Status = StatusCanceled
+		} else {
+			j.Job.Status = StatusCompleted
+		}
+		j.cancel = nil
+		if j.progress != nil {
+			j.progress.Close()
+			j.progress = nil
+		}
+		_ = l.save(j)
+	}()
+}
+
+// record appends the result to the progress file, it's written outside of
+// l.mu to not serialize the workers
+func (l *Local) record(j *localJob, id string, resp *chat.ChatResponse, err error) {
+	res := Result{CustomID: id, Response: resp}
+	if err != nil {
+		res.Error = err.Error()
+		res.Response = nil
+	}
+	if j.progress != nil {
+		// A failed write only loses this result, it's sent again on resume
+		if line, err := json.Marshal(res); err == nil {
+			_, _ = j.progress.Write(append(line, '\n'))
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err != nil {
+		j.Job.Failed++
+	} else {
+		j.Job.Completed++
+	}
+	j.Results[id] = res
+}
+
+// save writes the job and its requests to the state dir, it's only called
+// when the status changes. l.mu must be held.
+func (l *Local) save(j *localJob) error {
+	if l.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(l.dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	// Written then renamed to not leave a truncated file
+	tmp := l.path(j.Job.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.path(j.Job.ID))
+}
+
+func (l *Local) path(id string) string {
+	return filepath.Join(l.dir, filepath.Base(id)+".json")
+}
+
+func (l *Local) resultsPath(id string) string {
+	return filepath.Join(l.dir, filepath.Base(id)+".results.jsonl")
+}
+
+// Wait blocks until the job running in this process is done
+func (l *Local) Wait(ctx context.Context, id string) (*Job, error) {
+	l.mu.Lock()
+	j, err := l.get(id)
+	if err != nil {
+		l.mu.Unlock()
+		return nil, err
+	}
+	done := j.done
+	l.mu.Unlock()
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return l.Status(ctx, id)
+}
+
+var _ BatchProvider = (*Local)(nil)
@@ -0,0 +1,169 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y0ug/llmhaven/chat"
+	"go.uber.org/mock/gomock"
+)
+
+func newParams(text string) chat.ChatParams {
+	return *chat.NewChatParams(chat.WithMessages(chat.NewUserMessage(text)))
+}
+
+func textOf(p chat.ChatParams) string {
+	return p.Messages[0].Content[0].Text
+}
+
+func TestLocal_Concurrency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	provider := chat.NewMockProvider(ctrl)
+
+	var running, maxRunning int32
+	provider.EXPECT().Send(gomock.Any(), gomock.Any()).Times(6).
+		DoAndReturn(func(ctx context.Context, p chat.ChatParams) (*chat.ChatResponse, error) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			if textOf(p) == "fail" {
+				return nil, errors.New("boom")
+			}
+			return &chat.ChatResponse{ID: textOf(p)}, nil
+		})
+
+	l := NewLocal(provider, WithConcurrency(2))
+	ctx := context.Background()
+	job, err := l.Submit(ctx, NewRequests(
+		newParams("a"), newParams("b"), newParams("fail"),
+		newParams("d"), newParams("e"), newParams("f"),
+	))
+	assert.NoError(t, err)
+	assert.Equal(t, 6, job.Total)
+
+	job, err = l.Wait(ctx, job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCompleted, job.Status)
+	assert.Equal(t, 5, job.Completed)
+	assert.Equal(t, 1, job.Failed)
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxRunning), int32(2))
+
+	results, err := l.Results(ctx, job.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, results, 6) {
+		// Requests order
+		assert.Equal(t, "request-0", results[0].CustomID)
+		assert.Equal(t, "a", results[0].Response.ID)
+		assert.Equal(t, "boom", results[2].Error)
+		assert.Nil(t, results[2].Response)
+	}
+}
+
+func TestLocal_DuplicateID(t *testing.T) {
+	l := NewLocal(chat.NewMockProvider(gomock.NewController(t)))
+	_, err := l.Submit(context.Background(), []Request{{CustomID: "a"}, {CustomID: "a"}})
+	assert.Error(t, err)
+}
+
+func TestLocal_CancelResume(t *testing.T) {
+	dir := t.TempDir()
+	ctrl := gomock.NewController(t)
+	provider := chat.NewMockProvider(ctrl)
+
+	release := make(chan struct{})
+	// a is answered, b blocks until canceled
+	provider.EXPECT().Send(gomock.Any(), gomock.Any()).Times(2).
+		DoAndReturn(func(ctx context.Context, p chat.ChatParams) (*chat.ChatResponse, error) {
+			if textOf(p) == "a" {
+				return &chat.ChatResponse{ID: "a"}, nil
+			}
+			close(release)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+
+	ctx := context.Background()
+	l := NewLocal(provider, WithConcurrency(1), WithStateDir(dir))
+	job, err := l.Submit(ctx, NewRequests(newParams("a"), newParams("b")))
+	assert.NoError(t, err)
+
+	<-release
+	job, err = l.Cancel(ctx, job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCanceled, job.Status)
+	assert.Equal(t, 1, job.Completed)
+
+	// The results are appended to the progress file, not to the job file
+	progress, err := os.ReadFile(filepath.Join(dir, job.ID+".results.jsonl"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(progress), "\n"))
+	assert.Contains(t, string(progress), `"custom_id":"request-0"`)
+	header, err := os.ReadFile(filepath.Join(dir, job.ID+".json"))
+	assert.NoError(t, err)
+	assert.NotContains(t, string(header), `"response"`)
+
+	// A new executor, as after a restart, loads the job and sends only b
+	provider2 := chat.NewMockProvider(ctrl)
+	provider2.EXPECT().Send(gomock.Any(), gomock.Any()).Times(1).
+		DoAndReturn(func(ctx context.Context, p chat.ChatParams) (*chat.ChatResponse, error) {
+			assert.Equal(t, "b", textOf(p))
+			return &chat.ChatResponse{ID: "b"}, nil
+		})
+	l2 := NewLocal(provider2, WithStateDir(dir))
+	status, err := l2.Status(ctx, job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCanceled, status.Status)
+
+	_, err = l2.Resume(ctx, job.ID)
+	assert.NoError(t, err)
+	job, err = l2.Wait(ctx, job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCompleted, job.Status)
+	assert.Equal(t, 2, job.Completed)
+
+	results, err := l2.Results(ctx, job.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, "a", results[0].Response.ID)
+		assert.Equal(t, "b", results[1].Response.ID)
+	}
+}
+
+// nativeProvider has a batch API, disabled by supported
+type nativeProvider struct {
+	*chat.MockProvider
+	*Local
+	supported bool
+}
+
+func (p *nativeProvider) SupportsBatch() bool {
+	return p.supported
+}
+
+func TestNew(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mock := chat.NewMockProvider(ctrl)
+
+	// No batch API
+	_, ok := New(mock).(*Local)
+	assert.True(t, ok)
+
+	native := &nativeProvider{MockProvider: mock, Local: NewLocal(mock), supported: true}
+	assert.Same(t, native, New(native))
+
+	native.supported = false
+	assert.NotSame(t, native, New(native))
+}
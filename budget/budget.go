@@ -0,0 +1,407 @@
+// Package budget enforces spend limits on a chat.Provider. The spend is
+// tracked per key (tenant, user, project) set in the context with WithKey.
+package budget
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/http/config"
+	"github.com/y0ug/llmhaven/http/streaming"
+	"github.com/y0ug/llmhaven/modelinfo"
+)
+
+// DefaultKey is used when the context has no key
+const DefaultKey = "default"
+
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// ExceededError is returned before a request whose worst case cost is
+// above the remaining budget of the key
+type ExceededError struct {
+	Key      string
+	Limit    float64
+	Spent    float64 // spend and in flight requests
+	Estimate float64 // worst case cost of the request
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("budget of %s exceeded: $%.4f spent of $%.4f, request up to $%.4f",
+		e.Key, e.Spent, e.Limit, e.Estimate)
+}
+
+func (e *ExceededError) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+type contextKey struct{}
+
+// WithKey sets the key the spend of the requests made with ctx is added to
+func WithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// KeyFromContext returns the key of ctx, DefaultKey when unset
+func KeyFromContext(ctx context.Context) string {
+	if key, ok := ctx.Value(contextKey{}).(string); ok && key != "" {
+		return key
+	}
+	return DefaultKey
+}
+
+type Option func(*Guard)
+
+// WithStore sets the spend store, a FileStore at DefaultPath by default. It
+// can be shared by several processes.
+func WithStore(store Store) Option {
+	return func(g *Guard) {
+		g.store = store
+	}
+}
+
+// WithLimit sets the budget in USD of key
+func WithLimit(key string, usd float64) Option {
+	return func(g *Guard) {
+		g.limits[key] = usd
+	}
+}
+
+// WithDefaultLimit sets the budget of the keys without limit, they are
+// unlimited otherwise
+func WithDefaultLimit(usd float64) Option {
+	return func(g *Guard) {
+		g.defaultLimit = &usd
+	}
+}
+
+// WithCounter sets the counter of the prompt tokens, the provider is used
+// when it's a chat.TokenCounter
+func WithCounter(counter chat.TokenCounter) Option {
+	return func(g *Guard) {
+		g.counter = counter
+	}
+}
+
+// Guard is a chat.Provider rejecting the requests over budget and adding
+// the cost of the responses to the store
+type Guard struct {
+	provider     chat.Provider
+	prices       modelinfo.Provider
+	store        Store
+	counter      chat.TokenCounter
+	limits       map[string]float64
+	defaultLimit *float64
+
+	mu       sync.Mutex
+	inFlight map[string]float64 // worst case of the requests not yet recorded
+}
+
+var (
+	_ chat.Provider     = (*Guard)(nil)
+	_ chat.TokenCounter = (*Guard)(nil)
+)
+
+// New wraps provider, prices are used for the estimate and the cost of the
+// responses without Cost
+func New(provider chat.Provider, prices modelinfo.Provider, opts ...Option) (*Guard, error) {
+	g := &Guard{
+		provider: provider,
+		prices:   prices,
+		limits:   map[string]float64{},
+		inFlight: map[string]float64{},
+	}
+	if counter, ok := provider.(chat.TokenCounter); ok {
+		g.counter = counter
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	if g.store == nil {
+		store, err := NewFileStore(DefaultPath())
+		if err != nil {
+			return nil, err
+		}
+		g.store = store
+	}
+	return g, nil
+}
+
+// Spent returns the recorded spend of key
+func (g *Guard) Spent(ctx context.Context, key string) (float64, error) {
+	return g.store.Spent(ctx, key)
+}
+
+// Remaining returns the budget left to key, ok is false when it's unlimited
+func (g *Guard) Remaining(ctx context.Context, key string) (float64, bool, error) {
+	limit, ok := g.limit(key)
+	if !ok {
+		return 0, false, nil
+	}
+	spent, err := g.store.Spent(ctx, key)
+	if err != nil {
+		return 0, true, err
+	}
+	return max(limit-spent, 0), true, nil
+}
+
+func (g *Guard) limit(key string) (float64, bool) {
+	if limit, ok := g.limits[key]; ok {
+		return limit, true
+	}
+	if g.defaultLimit != nil {
+		return *g.defaultLimit, true
+	}
+	return 0, false
+}
+
+// Estimate returns the worst case cost of params: the prompt tokens and
+// MaxTokens, or the max output tokens of the model, at the output price
+func (g *Guard) Estimate(ctx context.Context, params chat.ChatParams) (float64, error) {
+	info, ok := g.prices.Get(params.Model)
+	if !ok {
+		return 0, fmt.Errorf("budget: no pricing for model %s", params.Model)
+	}
+	prompt, err := g.promptTokens(ctx, params)
+	if err != nil {
+		return 0, err
+	}
+	output := params.MaxTokens
+	if output == 0 {
+		output = info.GetMaxOutputTokens()
+	}
+	return float64(prompt)*info.GetInputCostPerToken() +
+		float64(output)*info.GetOutputCostPerToken(), nil
+}
+
+func (g *Guard) promptTokens(ctx context.Context, params chat.ChatParams) (int64, error) {
+	if g.counter != nil {
+		n, err := g.counter.CountTokens(ctx, params)
+		if !errors.Is(err, chat.ErrCountTokensUnsupported) {
+			return n, err
+		}
+	}
+	return estimateTokens(params), nil
+}
+
+// estimateTokens is a rough count without tokenizer, 4 bytes per token
+func estimateTokens(params chat.ChatParams) int64 {
+	n := 0
+	for _, m := range params.Messages {
+		for _, c := range m.Content {
+			n += len(c.Text) + len(c.Content) + len(c.Input) + len(c.InputJson)
+			if c.Source != nil {
+				n += len(c.Source.Data)
+			}
+		}
+	}
+	for _, t := range params.Tools {
+		b, _ := json.Marshal(t)
+		n += len(b)
+	}
+	return int64((n + 3) / 4)
+}
+
+// reserve checks the budget of key and adds the estimate to the in flight
+// spend, the returned function releases it. The estimate is 0 when the key
+// has no limit.
+func (g *Guard) reserve(ctx context.Context, params chat.ChatParams) (float64, func(), error) {
+	key := KeyFromContext(ctx)
+	limit, limited := g.limit(key)
+	if !limited {
+		return 0, func() {}, nil
+	}
+	estimate, err := g.Estimate(ctx, params)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// The store is read under the lock, a request recording its cost in
+	// between is then counted as spent, in flight or both but never missed
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	spent, err := g.store.Spent(ctx, key)
+	if err != nil {
+		return 0, nil, err
+	}
+	spent += g.inFlight[key]
+	if spent+estimate > limit {
+		return 0, nil, &ExceededError{Key: key, Limit: limit, Spent: spent, Estimate: estimate}
+	}
+	g.inFlight[key] += estimate
+
+	var once sync.Once
+	return estimate, func() {
+		once.Do(func() {
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			g.inFlight[key] -= estimate
+			if g.inFlight[key] <= 0 {
+				delete(g.inFlight, key)
+			}
+		})
+	}, nil
+}
+
+// record adds the cost of resp to the key of ctx
+func (g *Guard) record(ctx context.Context, model string, resp *chat.ChatResponse) error {
+	if resp == nil {
+		return nil
+	}
+	cost := resp.Cost
+	if cost == nil {
+		info, ok := g.prices.Get(model)
+		if !ok {
+			info, ok = g.prices.Get(resp.Model)
+		}
+		if !ok {
+			return fmt.Errorf("budget: no pricing for model %s", model)
+		}
+		c := modelinfo.Cost(resp.Usage, info)
+		cost = &c
+	}
+	return g.store.Add(ctx, KeyFromContext(ctx), cost.Total)
+}
+
+func (g *Guard) Send(ctx context.Context, params chat.ChatParams) (*chat.ChatResponse, error) {
+	_, release, err := g.reserve(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	resp, err := g.provider.Send(ctx, params)
+	if err != nil {
+		return resp, err
+	}
+	if err := g.record(ctx, params.Model, resp); err != nil {
+		return resp, fmt.Errorf("error recording spend: %w", err)
+	}
+	return resp, nil
+}
+
+func (g *Guard) Stream(
+	ctx context.Context,
+	params chat.ChatParams,
+) (streaming.Streamer[chat.EventStream], error) {
+	estimate, release, err := g.reserve(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := g.provider.Stream(ctx, params)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &guardStream{
+		Streamer: stream,
+		guard:    g,
+		ctx:      ctx,
+		model:    params.Model,
+		estimate: estimate,
+		release:  release,
+	}, nil
+}
+
+// CountTokens is forwarded to the counter
+func (g *Guard) CountTokens(ctx context.Context, params chat.ChatParams) (int64, error) {
+	if g.counter == nil {
+		return 0, chat.ErrCountTokensUnsupported
+	}
+	return g.counter.CountTokens(ctx, params)
+}
+
+// RateLimits is forwarded when the provider keeps its rate limits, the
+// Router uses them
+func (g *Guard) RateLimits() config.RateLimit {
+	if reporter, ok := g.provider.(interface{ RateLimits() config.RateLimit }); ok {
+		return reporter.RateLimits()
+	}
+	return config.RateLimit{}
+}
+
+// guardStream records the cost on message_stop, the reservation is released
+// then. A stream closed or failing before is charged the usage seen so far,
+// or the estimate without usage.
+type guardStream struct {
+	streaming.Streamer[chat.EventStream]
+	guard    *Guard
+	ctx      context.Context
+	model    string
+	estimate float64
+	release  func()
+	last     *chat.ChatResponse // latest message with the usage so far
+	output   int                // bytes of the deltas
+	stopped  bool
+	err      error
+}
+
+func (s *guardStream) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	if !s.Streamer.Next() {
+		s.stop()
+		return false
+	}
+	evt := s.Streamer.Current()
+	if evt.Message != nil {
+		s.last = evt.Message
+	}
+	if delta, ok := evt.Delta.(string); ok {
+		s.output += len(delta)
+	}
+	if evt.Type == "message_stop" && !s.stopped {
+		s.stopped = true
+		if err := s.guard.record(s.ctx, s.model, evt.Message); err != nil {
+			s.err = fmt.Errorf("error recording spend: %w", err)
+		}
+		s.release()
+	}
+	return true
+}
+
+// stop charges a stream ending before message_stop, the output tokens are
+// at least the deltas received
+func (s *guardStream) stop() {
+	if s.stopped {
+		return
+	}
+	s.stopped = true
+	defer s.release()
+
+	// The spend is recorded even when the stream was cancelled
+	ctx := context.WithoutCancel(s.ctx)
+	var err error
+	if s.last != nil && s.last.Usage != nil {
+		resp := *s.last
+		usage := *s.last.Usage
+		usage.OutputTokens = max(usage.OutputTokens, (s.output+3)/4)
+		resp.Usage = &usage
+		if usage.OutputTokens != s.last.Usage.OutputTokens {
+			// The cost of the provider is from the old usage
+			resp.Cost = nil
+		}
+		err = s.guard.record(ctx, s.model, &resp)
+	} else if s.estimate > 0 {
+		err = s.guard.store.Add(ctx, KeyFromContext(ctx), s.estimate)
+	}
+	if err != nil && s.err == nil {
+		s.err = fmt.Errorf("error recording spend: %w", err)
+	}
+}
+
+func (s *guardStream) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.Streamer.Err()
+}
+
+func (s *guardStream) Close() error {
+	s.stop()
+	return s.Streamer.Close()
+}
@@ -0,0 +1,224 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/modelinfo"
+	"go.uber.org/mock/gomock"
+)
+
+// fixedCounter counts n tokens for every request
+type fixedCounter int64
+
+func (c fixedCounter) CountTokens(ctx context.Context, params chat.ChatParams) (int64, error) {
+	return int64(c), nil
+}
+
+func testPrices() *modelinfo.LitellmModelInfoMap {
+	return &modelinfo.LitellmModelInfoMap{
+		"model": {InputCostPerToken: 1e-3, OutputCostPerToken: 2e-3, MaxOutputTokens: 1000},
+	}
+}
+
+func response(input, output int) *chat.ChatResponse {
+	return &chat.ChatResponse{Usage: &chat.ChatUsage{InputTokens: input, OutputTokens: output}}
+}
+
+func TestGuard_Send(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mock := chat.NewMockProvider(ctrl)
+	store := NewMemoryStore()
+	g, err := New(mock, testPrices(),
+		WithStore(store),
+		WithCounter(fixedCounter(100)),
+		WithLimit("alice", 1),
+	)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	params := chat.ChatParams{Model: "model", MaxTokens: 200}
+	ctx := WithKey(context.Background(), "alice")
+
+	// Worst case 100*1e-3 + 200*2e-3 = 0.5
+	mock.EXPECT().Send(gomock.Any(), gomock.Any()).Return(response(100, 50), nil).Times(2)
+	_, err = g.Send(ctx, params)
+	assert.NoError(t, err)
+	spent, _ := g.Spent(ctx, "alice")
+	assert.InDelta(t, 0.2, spent, 1e-9)
+
+	_, err = g.Send(ctx, params)
+	assert.NoError(t, err)
+
+	// 0.4 spent, 0.6 left
+	remaining, limited, err := g.Remaining(ctx, "alice")
+	assert.NoError(t, err)
+	assert.True(t, limited)
+	assert.InDelta(t, 0.6, remaining, 1e-9)
+
+	// Without MaxTokens the max output of the model is used
+	_, err = g.Send(ctx, chat.ChatParams{Model: "model"})
+	var exceeded *ExceededError
+	if assert.ErrorAs(t, err, &exceeded) {
+		assert.Equal(t, "alice", exceeded.Key)
+		assert.InDelta(t, 2.1, exceeded.Estimate, 1e-9)
+	}
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+
+	// The default key is unlimited, a cost returned by the API is used
+	resp := response(1, 1)
+	resp.Cost = &chat.Cost{Total: 5}
+	mock.EXPECT().Send(gomock.Any(), gomock.Any()).Return(resp, nil)
+	_, err = g.Send(context.Background(), params)
+	assert.NoError(t, err)
+	spent, _ = g.Spent(ctx, DefaultKey)
+	assert.Equal(t, 5.0, spent)
+
+	// Unknown models can't be estimated
+	g.limits[DefaultKey] = 10
+	_, err = g.Send(context.Background(), chat.ChatParams{Model: "other"})
+	assert.Error(t, err)
+
+	// Failed requests are not recorded and release their reservation
+	mock.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil, errors.New("boom"))
+	_, err = g.Send(ctx, params)
+	assert.Error(t, err)
+	assert.Empty(t, g.inFlight)
+}
+
+type sliceStream struct {
+	events []chat.EventStream
+	i      int
+}
+
+func (s *sliceStream) Next() bool                { s.i++; return s.i <= len(s.events) }
+func (s *sliceStream) Current() chat.EventStream { return s.events[s.i-1] }
+func (s *sliceStream) Err() error                { return nil }
+func (s *sliceStream) Close() error              { return nil }
+
+func TestGuard_Stream(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mock := chat.NewMockProvider(ctrl)
+	g, err := New(mock, testPrices(),
+		WithStore(NewMemoryStore()),
+		WithCounter(fixedCounter(100)),
+		WithDefaultLimit(1),
+	)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	mock.EXPECT().Stream(gomock.Any(), gomock.Any()).Return(&sliceStream{events: []chat.EventStream{
+		{Type: "text_delta", Delta: "hi"},
+		{Type: "message_stop", Message: response(100, 10)},
+	}}, nil)
+	stream, err := g.Stream(context.Background(), chat.ChatParams{Model: "model", MaxTokens: 100})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	// The reservation blocks a concurrent request over the budget
+	_, err = g.Stream(context.Background(), chat.ChatParams{Model: "model", MaxTokens: 400})
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+
+	for stream.Next() {
+	}
+	assert.NoError(t, stream.Err())
+	assert.NoError(t, stream.Close())
+	spent, _ := g.Spent(context.Background(), DefaultKey)
+	assert.InDelta(t, 0.12, spent, 1e-9)
+	assert.Empty(t, g.inFlight)
+}
+
+func TestGuard_StreamClosedEarly(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mock := chat.NewMockProvider(ctrl)
+	g, err := New(mock, testPrices(),
+		WithStore(NewMemoryStore()),
+		WithCounter(fixedCounter(100)),
+		WithDefaultLimit(10),
+	)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	params := chat.ChatParams{Model: "model", MaxTokens: 300}
+	ctx := context.Background()
+
+	// The usage of message_start and the deltas are charged,
+	// 100*1e-3 + 100*2e-3
+	mock.EXPECT().Stream(gomock.Any(), gomock.Any()).Return(&sliceStream{events: []chat.EventStream{
+		{Type: "message_start", Message: response(100, 1)},
+		{Type: "text_delta", Delta: strings.Repeat("word", 100)},
+		{Type: "text_delta", Delta: "never read"},
+	}}, nil)
+	stream, err := g.Stream(ctx, params)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	stream.Next()
+	stream.Next()
+	assert.NoError(t, stream.Close())
+	spent, _ := g.Spent(ctx, DefaultKey)
+	assert.InDelta(t, 0.3, spent, 1e-9)
+	assert.Empty(t, g.inFlight)
+
+	// Without usage the estimate is kept, 100*1e-3 + 300*2e-3
+	mock.EXPECT().Stream(gomock.Any(), gomock.Any()).Return(&sliceStream{events: []chat.EventStream{
+		{Type: "text_delta", Delta: "hi"},
+	}}, nil)
+	stream, err = g.Stream(ctx, params)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for stream.Next() {
+	}
+	assert.NoError(t, stream.Close())
+	spent, _ = g.Spent(ctx, DefaultKey)
+	assert.InDelta(t, 1.0, spent, 1e-9)
+	assert.Empty(t, g.inFlight)
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spend.json")
+	store, err := NewFileStore(path)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	ctx := context.Background()
+	assert.NoError(t, store.Add(ctx, "a", 1.5))
+	assert.NoError(t, store.Add(ctx, "a", 0.5))
+	assert.NoError(t, store.Add(ctx, "b", 1))
+	assert.NoError(t, store.Reset(ctx, "b"))
+
+	store, err = NewFileStore(path)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	spent, _ := store.Spent(ctx, "a")
+	assert.Equal(t, 2.0, spent)
+	spent, _ = store.Spent(ctx, "b")
+	assert.Equal(t, 0.0, spent)
+
+	// Two stores on the same file, as two processes, don't overwrite their
+	// spend
+	other, err := NewFileStore(path)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(s Store) {
+			defer wg.Done()
+			assert.NoError(t, s.Add(ctx, "a", 1))
+		}([]Store{store, other}[i%2])
+	}
+	wg.Wait()
+	spent, _ = store.Spent(ctx, "a")
+	assert.Equal(t, 22.0, spent)
+}
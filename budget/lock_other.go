@@ -0,0 +1,9 @@
+//go:build !unix
+
+package budget
+
+// lockFile has no lock between processes on this platform, the FileStore
+// is only safe in a single process
+func lockFile(path string) (func(), error) {
+	return func() {}, nil
+}
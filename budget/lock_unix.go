@@ -0,0 +1,25 @@
+//go:build unix
+
+package budget
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive lock on path, it's released by the returned
+// function
+func lockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
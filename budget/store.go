@@ -0,0 +1,159 @@
+package budget
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store keeps the cumulative spend in USD of every key
+type Store interface {
+	Spent(ctx context.Context, key string) (float64, error)
+	Add(ctx context.Context, key string, amount float64) error
+	Reset(ctx context.Context, key string) error
+}
+
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*FileStore)(nil)
+)
+
+// MemoryStore keeps the spend in memory, it's lost on exit
+type MemoryStore struct {
+	mu    sync.Mutex
+	spent map[string]float64
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{spent: map[string]float64{}}
+}
+
+func (s *MemoryStore) Spent(ctx context.Context, key string) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.spent[key], nil
+}
+
+func (s *MemoryStore) Add(ctx context.Context, key string, amount float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.spent[key] += amount
+	return nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.spent, key)
+	return nil
+}
+
+// FileStore keeps the spend as JSON in a file shared by the processes using
+// it. Every call takes a lock on path.lock and reads the file again, the
+// spend added by the other processes is then counted.
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// DefaultPath returns llmhaven/spend.json in the user config directory
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "llmhaven", "spend.json")
+}
+
+// NewFileStore checks the file at path when it exists
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path}
+	if _, err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) Spent(ctx context.Context, key string) (float64, error) {
+	var amount float64
+	err := s.update(false, func(spent map[string]float64) {
+		amount = spent[key]
+	})
+	return amount, err
+}
+
+func (s *FileStore) Add(ctx context.Context, key string, amount float64) error {
+	return s.update(true, func(spent map[string]float64) {
+		spent[key] += amount
+	})
+}
+
+func (s *FileStore) Reset(ctx context.Context, key string) error {
+	return s.update(true, func(spent map[string]float64) {
+		delete(spent, key)
+	})
+}
+
+// update calls fn with the spend of the file under the lock, it's written
+// back when write is set
+func (s *FileStore) update(write bool, fn func(map[string]float64)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	unlock, err := lockFile(s.path + ".lock")
+	if err != nil {
+		return fmt.Errorf("error locking %s: %w", s.path, err)
+	}
+	defer unlock()
+
+	spent, err := s.load()
+	if err != nil {
+		return err
+	}
+	fn(spent)
+	if !write {
+		return nil
+	}
+	return s.save(spent)
+}
+
+// load reads the spend of the file, it's empty when there is none
+func (s *FileStore) load() (map[string]float64, error) {
+	spent := map[string]float64{}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return spent, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &spent); err != nil {
+		return nil, fmt.Errorf("error loading %s: %w", s.path, err)
+	}
+	if spent == nil {
+		spent = map[string]float64{}
+	}
+	return spent, nil
+}
+
+// save writes the spend, the file lock must be held
+func (s *FileStore) save(spent map[string]float64) error {
+	data, err := json.MarshalIndent(spent, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Written then renamed to not leave a truncated file
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
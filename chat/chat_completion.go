@@ -13,8 +13,51 @@ type ChatParams struct {
 	Messages    []*ChatMessage
 	Stream      bool
 	Tools       []Tool
-	ToolChoice  string // auto, any, tool
-	N           *int   // number of choice
+	ToolChoice  *ToolChoice
+	N           *int // number of choice
+
+	// Sampling, nil or empty values are not sent to the provider
+	TopP             *float64
+	TopK             *int
+	StopSequences    []string
+	Seed             *int
+	PresencePenalty  *float64
+	FrequencyPenalty *float64
+	LogitBias        map[string]int // token id => bias
+	User             string         // end-user identifier, metadata.user_id on Anthropic
+
+	// Reasoning enables extended thinking on models supporting it
+	Reasoning *Reasoning
+
+	// ResponseFormat ask the model to answer with JSON matching the schema
+	ResponseFormat *ResponseFormat
+	// ResponseRetries is the number of retry SendTyped does when the response
+	// don't validate, the validation error is fed back to the model
+	ResponseRetries int
+}
+
+// Reasoning configures extended thinking (Anthropic) or reasoning effort
+// (OpenAI o-series), when only one of the field is set the provider derive
+// the other one.
+type Reasoning struct {
+	BudgetTokens int    // Maximum tokens used for thinking
+	Effort       string // low, medium, high
+}
+
+const (
+	ReasoningEffortLow    = "low"
+	ReasoningEffortMedium = "medium"
+	ReasoningEffortHigh   = "high"
+)
+
+// ResponseFormat describes the expected JSON output of the model.
+// OpenAI compatible providers map it to response_format json_schema,
+// Anthropic to a forced call of a tool named Name.
+type ResponseFormat struct {
+	Name        string
+	Description string
+	Schema      interface{}
+	Strict      bool
 }
 
 type ChatResponse struct {
@@ -22,6 +65,12 @@ type ChatResponse struct {
 	Choice []ChatChoice `json:"choice,omitempty"`
 	Usage  *ChatUsage   `json:"usage,omitempty"`
 	Model  string       `json:"model,omitempty"`
+	// Provider is the backend which served the request, set by the
+	// providers composed of several ones like llmhaven.NewFallback
+	Provider string `json:"provider,omitempty"`
+	// Cost is set by the provider when the API returns it (OpenRouter) or
+	// computed from the pricing by modelinfo.NewCostProvider
+	Cost *Cost `json:"cost,omitempty"`
 }
 
 func (cm *ChatResponse) ToMessageParams() *ChatMessage {
@@ -53,8 +102,14 @@ type ChatChoice struct {
 	Role       string            `json:"role,omitempty"` // Always "assistant"
 	Content    []*MessageContent `json:"content,omitempty"`
 	StopReason string            `json:"stop_reason,omitempty"`
+
+	// Provider specific data like Gemini grounding or safety ratings
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// ChatUsage counts are totals, OutputTokens includes the reasoning tokens.
+// InputTokens includes the cached and cache creation tokens except on
+// Anthropic, it's then the uncached input tokens as returned by the API.
 type ChatUsage struct {
 	OutputTokens             int `json:"output_tokens"`
 	OutputAudioTokens        int `json:"output_audio_tokens"`
@@ -65,6 +120,30 @@ type ChatUsage struct {
 	InputCacheCreationTokens int `json:"input_cache_creation_tokens"`
 }
 
+// Cost of a request in USD. When the provider only returns the total the
+// breakdown is zero.
+type Cost struct {
+	Input       float64 `json:"input"`        // uncached input tokens
+	CachedInput float64 `json:"cached_input"` // cache read
+	CacheWrite  float64 `json:"cache_write"`  // cache creation
+	Output      float64 `json:"output"`       // output without reasoning
+	Reasoning   float64 `json:"reasoning"`
+	Total       float64 `json:"total"`
+}
+
+// Add sums the costs, used to total a conversation
+func (c *Cost) Add(other *Cost) {
+	if other == nil {
+		return
+	}
+	c.Input += other.Input
+	c.CachedInput += other.CachedInput
+	c.CacheWrite += other.CacheWrite
+	c.Output += other.Output
+	c.Reasoning += other.Reasoning
+	c.Total += other.Total
+}
+
 type ChatMessage struct {
 	Role    string            `json:"role"`
 	Content []*MessageContent `json:"content"`
@@ -76,6 +155,23 @@ func (cm *ChatMessage) SetCache() {
 	}
 }
 
+type ToolChoiceType string
+
+const (
+	ToolChoiceAuto     ToolChoiceType = "auto"     // the model decide
+	ToolChoiceNone     ToolChoiceType = "none"     // tools are disabled
+	ToolChoiceRequired ToolChoiceType = "required" // the model must call at least one tool
+	ToolChoiceTool     ToolChoiceType = "tool"     // the model must call the tool Name
+)
+
+// ToolChoice controls how the model use the tools
+type ToolChoice struct {
+	Type ToolChoiceType
+	Name string // Name of the tool to call when Type is ToolChoiceTool
+	// DisableParallel asks for at most one tool call per response
+	DisableParallel bool
+}
+
 type Tool struct {
 	Description *string     `json:"description,omitempty"`
 	InputSchema interface{} `json:"input_schema,omitempty"`
@@ -21,6 +21,12 @@ const (
 	ContentTypeDocument       MessageContentType = "document"
 	ContentTypeImage          MessageContentType = "image"
 
+	// Reasoning, thinking_delta and signature_delta are only used while streaming
+	ContentTypeThinking         MessageContentType = "thinking"
+	ContentTypeRedactedThinking MessageContentType = "redacted_thinking"
+	ContentTypeThinkingDelta    MessageContentType = "thinking_delta"
+	ContentTypeSignatureDelta   MessageContentType = "signature_delta"
+
 	// OpenAI
 	ContentTypeInputAudio MessageContentType = "input_audio"
 )
@@ -33,6 +39,12 @@ type MessageContent struct {
 	Text        string `json:"text,omitempty"`
 	PartialJson string `json:"partial_json,omitempty"`
 
+	// Relevant for thinking content, the signature must be sent back untouched
+	// in the next turn. Data hold the encrypted redacted_thinking.
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+
 	// Relevant for tool usage calls (like "function calls")
 	ID        string          `json:"id,omitempty"`    // Unique identifier for this tool call
 	Name      string          `json:"name,omitempty"`  // Name of the tool to call
@@ -42,6 +54,7 @@ type MessageContent struct {
 	// Relevant for tool results
 	ToolUseID    string        `json:"tool_use_id,omitempty"`   // ID of the tool call this result is for
 	Content      string        `json:"content,omitempty"`       // Result returned from the tool
+	IsError      bool          `json:"is_error,omitempty"`      // Set when the tool failed, Content hold the error
 	Source       *AIContentSrc `json:"source,omitempty"`        // Source of the content if type document/image
 	CacheControl *CacheControl `json:"cache_control,omitempty"` // Used to set cache
 }
@@ -64,10 +77,18 @@ func NewCacheControlEphemeral() *CacheControl {
 	}
 }
 
+const (
+	SourceTypeBase64 = "base64" // Data hold the content
+	SourceTypeURL    = "url"    // URL of the content, fetched by the provider
+	SourceTypeFile   = "file"   // FileID of a file uploaded to the provider
+)
+
 type AIContentSrc struct {
-	Type      string `json:"type"`       // base64
-	MediaType string `json:"media_type"` // "application/pdf" "image/jpeg" etc..
-	Data      []byte `json:"data"`
+	Type      string `json:"type"`                 // base64, url or file
+	MediaType string `json:"media_type,omitempty"` // "application/pdf" "image/jpeg" etc..
+	Data      []byte `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+	FileID    string `json:"file_id,omitempty"`
 }
 
 func NewSourceContent(sourceType string, mediaType string, data []byte) *MessageContent {
@@ -104,6 +125,10 @@ func (c MessageContent) String() string {
 		return c.Text
 	case ContentTypeText:
 		return c.Text
+	case ContentTypeThinking, ContentTypeThinkingDelta:
+		return c.Thinking
+	case ContentTypeRedactedThinking:
+		return "[redacted thinking]"
 	case ContentTypeToolUse:
 		args, _ := json.Marshal(c.Input)
 		return fmt.Sprintf("%s:%s => %s", c.ID, c.Name, string(args))
@@ -150,6 +175,30 @@ func NewToolResultContent(toolUseID, content string) *MessageContent {
 	}
 }
 
+// NewToolErrorContent creates a tool result content message flagged as an error
+func NewToolErrorContent(toolUseID, message string) *MessageContent {
+	return &MessageContent{
+		Type:      ContentTypeToolResult,
+		ToolUseID: toolUseID,
+		Content:   message,
+		IsError:   true,
+	}
+}
+
+// NewThinkingContent creates a thinking content message
+func NewThinkingContent(thinking, signature string) *MessageContent {
+	return &MessageContent{
+		Type:      ContentTypeThinking,
+		Thinking:  thinking,
+		Signature: signature,
+	}
+}
+
+// IsThinking returns true for thinking and redacted_thinking content
+func (c MessageContent) IsThinking() bool {
+	return c.Type == ContentTypeThinking || c.Type == ContentTypeRedactedThinking
+}
+
 // NewTextContent creates a text content message
 func NewTextContent(text string) *MessageContent {
 	return &MessageContent{
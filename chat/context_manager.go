@@ -0,0 +1,424 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/y0ug/llmhaven/http/streaming"
+)
+
+// ErrContextOverflow is returned when the messages can't fit in the context
+// window, the last turn alone is too large
+var ErrContextOverflow = errors.New("messages exceed the context window")
+
+// FitFunc reports if the messages fit in the context window
+type FitFunc func(ctx context.Context, messages []*ChatMessage) (bool, error)
+
+// ContextStrategy shortens the messages of params until fits reports true.
+// The system messages and the last turn must be kept.
+type ContextStrategy interface {
+	Fit(ctx context.Context, params ChatParams, fits FitFunc) ([]*ChatMessage, error)
+}
+
+// ContextStrategyFunc is a function implementing ContextStrategy
+type ContextStrategyFunc func(ctx context.Context, params ChatParams, fits FitFunc) ([]*ChatMessage, error)
+
+func (f ContextStrategyFunc) Fit(
+	ctx context.Context,
+	params ChatParams,
+	fits FitFunc,
+) ([]*ChatMessage, error) {
+	return f(ctx, params, fits)
+}
+
+// ContextManager fits ChatParams.Messages in the input window of the model
+// before they are sent. The tokens are counted with Counter, MaxTokens of
+// the request and Margin are kept free.
+type ContextManager struct {
+	Counter        TokenCounter
+	MaxInputTokens int
+	Margin         int // offline counts are approximations
+	Strategy       ContextStrategy
+}
+
+// NewContextManager creates a manager dropping the oldest turns by default
+func NewContextManager(
+	counter TokenCounter,
+	maxInputTokens int,
+	opts ...func(*ContextManager),
+) *ContextManager {
+	m := &ContextManager{
+		Counter:        counter,
+		MaxInputTokens: maxInputTokens,
+		Strategy:       DropOldest(),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// ContextWindowGetter is implemented by modelinfo.Getter
+type ContextWindowGetter interface {
+	GetMaxTokens() int
+	GetMaxInputTokens() int
+}
+
+// NewContextManagerForModel reads the window from the model info, the max
+// tokens is used when the max input tokens is unknown
+func NewContextManagerForModel(
+	counter TokenCounter,
+	info ContextWindowGetter,
+	opts ...func(*ContextManager),
+) (*ContextManager, error) {
+	if info == nil {
+		return nil, fmt.Errorf("no model info")
+	}
+	n := info.GetMaxInputTokens()
+	if n == 0 {
+		n = info.GetMaxTokens()
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("context window of the model is unknown")
+	}
+	return NewContextManager(counter, n, opts...), nil
+}
+
+func WithContextStrategy(strategy ContextStrategy) func(*ContextManager) {
+	return func(m *ContextManager) {
+		m.Strategy = strategy
+	}
+}
+
+func WithContextMargin(tokens int) func(*ContextManager) {
+	return func(m *ContextManager) {
+		m.Margin = tokens
+	}
+}
+
+// Fit returns params with messages fitting in the window, params is
+// returned unchanged when it already fits
+func (m *ContextManager) Fit(ctx context.Context, params ChatParams) (ChatParams, error) {
+	if m.Counter == nil {
+		return params, ErrCountTokensUnsupported
+	}
+	fits := m.fitFunc(params)
+	ok, err := fits(ctx, params.Messages)
+	if err != nil || ok {
+		return params, err
+	}
+	messages, err := m.Strategy.Fit(ctx, params, fits)
+	if err != nil {
+		return params, err
+	}
+	params.Messages = messages
+	return params, nil
+}
+
+// fitFunc reports if the messages sent with params fit in the window
+func (m *ContextManager) fitFunc(params ChatParams) FitFunc {
+	budget := m.MaxInputTokens - params.MaxTokens - m.Margin
+	return func(ctx context.Context, messages []*ChatMessage) (bool, error) {
+		p := params
+		p.Messages = messages
+		n, err := m.Counter.CountTokens(ctx, p)
+		if err != nil {
+			return false, err
+		}
+		return n <= int64(budget), nil
+	}
+}
+
+// Wrap returns a provider fitting the messages before each request. When
+// the manager has no Counter the provider is used if it's a TokenCounter.
+func (m *ContextManager) Wrap(provider Provider) Provider {
+	if m.Counter == nil {
+		if counter, ok := provider.(TokenCounter); ok {
+			c := *m
+			c.Counter = counter
+			m = &c
+		}
+	}
+	return &contextProvider{Provider: provider, manager: m}
+}
+
+type contextProvider struct {
+	Provider
+	manager *ContextManager
+}
+
+func (p *contextProvider) Send(ctx context.Context, params ChatParams) (*ChatResponse, error) {
+	params, err := p.manager.Fit(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return p.Provider.Send(ctx, params)
+}
+
+func (p *contextProvider) Stream(
+	ctx context.Context,
+	params ChatParams,
+) (streaming.Streamer[EventStream], error) {
+	params, err := p.manager.Fit(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return p.Provider.Stream(ctx, params)
+}
+
+func (p *contextProvider) CountTokens(ctx context.Context, params ChatParams) (int64, error) {
+	return p.manager.Counter.CountTokens(ctx, params)
+}
+
+// splitTurns splits the messages in the system messages and the turns. A
+// turn starts with a user message which isn't a tool result, a tool_use and
+// its tool_result are always in the same turn.
+func splitTurns(messages []*ChatMessage) ([]*ChatMessage, [][]*ChatMessage) {
+	var system []*ChatMessage
+	var turns [][]*ChatMessage
+	for _, m := range messages {
+		switch {
+		case m.Role == "system":
+			system = append(system, m)
+		case len(turns) == 0 || (m.Role == "user" && !hasToolResult(m)):
+			turns = append(turns, []*ChatMessage{m})
+		default:
+			turns[len(turns)-1] = append(turns[len(turns)-1], m)
+		}
+	}
+	return system, turns
+}
+
+func hasToolResult(m *ChatMessage) bool {
+	for _, c := range m.Content {
+		if c.Type == ContentTypeToolResult {
+			return true
+		}
+	}
+	return false
+}
+
+func joinTurns(system []*ChatMessage, turns [][]*ChatMessage) []*ChatMessage {
+	messages := append([]*ChatMessage{}, system...)
+	for _, turn := range turns {
+		messages = append(messages, turn...)
+	}
+	return messages
+}
+
+// dropOldest looks for the fewest oldest turns to drop, fits is monotonic
+// so it's a binary search
+func dropOldest(
+	ctx context.Context,
+	system []*ChatMessage,
+	turns [][]*ChatMessage,
+	fits FitFunc,
+) ([]*ChatMessage, error) {
+	if len(turns) == 0 {
+		return nil, ErrContextOverflow
+	}
+	var err error
+	n := sort.Search(len(turns), func(i int) bool {
+		if err != nil {
+			return true
+		}
+		var ok bool
+		ok, err = fits(ctx, joinTurns(system, turns[i:]))
+		return ok
+	})
+	if err != nil {
+		return nil, err
+	}
+	if n == len(turns) {
+		return nil, ErrContextOverflow
+	}
+	return joinTurns(system, turns[n:]), nil
+}
+
+// DropOldest drops the oldest turns, the system messages are kept
+func DropOldest() ContextStrategy {
+	return ContextStrategyFunc(func(
+		ctx context.Context,
+		params ChatParams,
+		fits FitFunc,
+	) ([]*ChatMessage, error) {
+		system, turns := splitTurns(params.Messages)
+		return dropOldest(ctx, system, turns, fits)
+	})
+}
+
+// KeepLast keeps the system messages and the last n turns, older turns are
+// dropped when they still don't fit. n is at least 1, the last turn.
+func KeepLast(n int) ContextStrategy {
+	n = max(n, 1)
+	return ContextStrategyFunc(func(
+		ctx context.Context,
+		params ChatParams,
+		fits FitFunc,
+	) ([]*ChatMessage, error) {
+		system, turns := splitTurns(params.Messages)
+		if len(turns) > n {
+			turns = turns[len(turns)-n:]
+		}
+		return dropOldest(ctx, system, turns, fits)
+	})
+}
+
+// SummaryPrompt is the instruction sent with the turns to summarise
+const SummaryPrompt = "Summarize the following conversation between a user and an assistant. " +
+	"Keep the facts, decisions, open questions and tool results needed to continue it. " +
+	"Reply with the summary only."
+
+// Summarize replaces the turns before the last keep with a summary written
+// by model, usually a cheaper one. The summary is added as a system message.
+// The last summary is reused while the summarised turns don't change. The
+// oldest turns are left out of the summary request when it doesn't fit, see
+// WithSummaryContext.
+func Summarize(
+	provider Provider,
+	model string,
+	keep int,
+	opts ...func(*summarizeStrategy),
+) ContextStrategy {
+	s := &summarizeStrategy{provider: provider, model: model, keep: keep}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// WithSummaryContext sets the window of the summary model, the summary
+// request must fit in the window of the conversation by default
+func WithSummaryContext(m *ContextManager) func(*summarizeStrategy) {
+	return func(s *summarizeStrategy) {
+		s.manager = m
+	}
+}
+
+type summarizeStrategy struct {
+	provider Provider
+	model    string
+	keep     int
+	manager  *ContextManager
+
+	mu         sync.Mutex
+	transcript string
+	summary    string
+}
+
+func (s *summarizeStrategy) Fit(
+	ctx context.Context,
+	params ChatParams,
+	fits FitFunc,
+) ([]*ChatMessage, error) {
+	system, turns := splitTurns(params.Messages)
+	keep := max(s.keep, 1)
+	if len(turns) <= keep {
+		return dropOldest(ctx, system, turns, fits)
+	}
+
+	older, recent := turns[:len(turns)-keep], turns[len(turns)-keep:]
+	older, err := s.fitSummary(ctx, older, fits)
+	if err != nil {
+		return nil, err
+	}
+	if len(older) == 0 {
+		// Even the last older turn is too large to be summarised
+		return dropOldest(ctx, system, recent, fits)
+	}
+	summary, err := s.summarize(ctx, Transcript(joinTurns(nil, older)))
+	if err != nil {
+		return nil, fmt.Errorf("error summarizing the conversation: %w", err)
+	}
+	system = withSummary(system, "Summary of the earlier conversation:\n"+summary)
+	return dropOldest(ctx, system, recent, fits)
+}
+
+// withSummary adds the summary to the last system message, some providers
+// only take one system prompt
+func withSummary(system []*ChatMessage, summary string) []*ChatMessage {
+	if len(system) == 0 {
+		return []*ChatMessage{NewSystemMessage(summary)}
+	}
+	last := *system[len(system)-1]
+	last.Content = append(last.Content[:len(last.Content):len(last.Content)],
+		NewTextContent(summary))
+	return append(system[:len(system)-1:len(system)-1], &last)
+}
+
+func (s *summarizeStrategy) request(transcript string) ChatParams {
+	return *NewChatParams(
+		WithModel(s.model),
+		WithMessages(
+			NewSystemMessage(SummaryPrompt),
+			NewUserMessage(transcript),
+		),
+	)
+}
+
+// fitSummary drops the oldest turns until the summary request fits, in the
+// window of the summary model or of the conversation
+func (s *summarizeStrategy) fitSummary(
+	ctx context.Context,
+	turns [][]*ChatMessage,
+	fits FitFunc,
+) ([][]*ChatMessage, error) {
+	if s.manager != nil && s.manager.Counter != nil {
+		fits = s.manager.fitFunc(s.request(""))
+	}
+	var err error
+	n := sort.Search(len(turns), func(i int) bool {
+		if err != nil {
+			return true
+		}
+		var ok bool
+		ok, err = fits(ctx, s.request(Transcript(joinTurns(nil, turns[i:]))).Messages)
+		return ok
+	})
+	if err != nil {
+		return nil, err
+	}
+	return turns[n:], nil
+}
+
+func (s *summarizeStrategy) summarize(ctx context.Context, transcript string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if transcript == s.transcript {
+		return s.summary, nil
+	}
+
+	resp, err := s.provider.Send(ctx, s.request(transcript))
+	if err != nil {
+		return "", err
+	}
+	var summary strings.Builder
+	if len(resp.Choice) > 0 {
+		for _, c := range resp.Choice[0].Content {
+			if c.Type == ContentTypeText {
+				summary.WriteString(c.Text)
+			}
+		}
+	}
+	s.transcript, s.summary = transcript, summary.String()
+	return s.summary, nil
+}
+
+// Transcript renders the messages as text, one "role: content" line per
+// content. Thinking and binary content are skipped.
+func Transcript(messages []*ChatMessage) string {
+	var b strings.Builder
+	for _, m := range messages {
+		for _, c := range m.Content {
+			switch c.Type {
+			case ContentTypeText, ContentTypeToolUse, ContentTypeToolResult:
+				fmt.Fprintf(&b, "%s: %s\n", m.Role, c.String())
+			}
+		}
+	}
+	return b.String()
+}
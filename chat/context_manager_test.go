@@ -0,0 +1,162 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// messageCounter counts 10 tokens per message
+type messageCounter struct {
+	calls int
+}
+
+func (c *messageCounter) CountTokens(ctx context.Context, params ChatParams) (int64, error) {
+	c.calls++
+	return int64(10 * len(params.Messages)), nil
+}
+
+func conversation() []*ChatMessage {
+	return []*ChatMessage{
+		NewSystemMessage("system"),
+		NewUserMessage("q1"),
+		NewMessage("assistant", NewTextContent("a1")),
+		NewUserMessage("q2"),
+		NewMessage("assistant", NewToolUseContent("1", "search", json.RawMessage(`{}`))),
+		NewMessage("user", NewToolResultContent("1", "result")),
+		NewMessage("assistant", NewTextContent("a2")),
+		NewUserMessage("q3"),
+	}
+}
+
+func TestContextManager_DropOldest(t *testing.T) {
+	counter := &messageCounter{}
+	messages := conversation()
+
+	m := NewContextManager(counter, 80)
+	params, err := m.Fit(context.Background(), ChatParams{Messages: messages})
+	assert.NoError(t, err)
+	assert.Equal(t, messages, params.Messages)
+
+	m = NewContextManager(counter, 70)
+	params, err = m.Fit(context.Background(), ChatParams{Messages: messages})
+	assert.NoError(t, err)
+	assert.Equal(t, append(messages[:1:1], messages[3:]...), params.Messages)
+
+	// The tool_use and tool_result are dropped together
+	m = NewContextManager(counter, 50)
+	params, err = m.Fit(context.Background(), ChatParams{Messages: messages})
+	assert.NoError(t, err)
+	assert.Equal(t, []*ChatMessage{messages[0], messages[7]}, params.Messages)
+
+	// MaxTokens is kept for the output
+	_, err = m.Fit(context.Background(), ChatParams{Messages: messages, MaxTokens: 35})
+	assert.ErrorIs(t, err, ErrContextOverflow)
+}
+
+func TestContextManager_KeepLast(t *testing.T) {
+	messages := conversation()
+	m := NewContextManager(&messageCounter{}, 70, WithContextStrategy(KeepLast(1)))
+	params, err := m.Fit(context.Background(), ChatParams{Messages: messages})
+	assert.NoError(t, err)
+	assert.Equal(t, []*ChatMessage{messages[0], messages[7]}, params.Messages)
+
+	// At least the last turn is kept
+	for _, n := range []int{0, -1} {
+		m = NewContextManager(&messageCounter{}, 70, WithContextStrategy(KeepLast(n)))
+		params, err = m.Fit(context.Background(), ChatParams{Messages: messages})
+		assert.NoError(t, err)
+		assert.Equal(t, []*ChatMessage{messages[0], messages[7]}, params.Messages)
+	}
+}
+
+// textCounter counts a token per byte of text
+type textCounter struct{}
+
+func (textCounter) CountTokens(ctx context.Context, params ChatParams) (int64, error) {
+	n := 0
+	for _, m := range params.Messages {
+		for _, c := range m.Content {
+			n += len(c.Text)
+		}
+	}
+	return int64(n), nil
+}
+
+func TestContextManager_SummarizeFit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	summarizer := NewMockProvider(ctrl)
+	summarizer.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, params ChatParams) (*ChatResponse, error) {
+			// q1 is left out, the request must fit in the summary window
+			transcript := params.Messages[1].Content[0].Text
+			assert.NotContains(t, transcript, "q1")
+			assert.Contains(t, transcript, "q2")
+			n, _ := textCounter{}.CountTokens(context.Background(), params)
+			assert.LessOrEqual(t, n, int64(len(SummaryPrompt)+80))
+			return responseWith(NewTextContent("they talked")), nil
+		})
+
+	summaryWindow := NewContextManager(textCounter{}, len(SummaryPrompt)+80)
+	m := NewContextManager(&messageCounter{}, 30, WithContextStrategy(
+		Summarize(summarizer, "cheap", 1, WithSummaryContext(summaryWindow))))
+	params, err := m.Fit(context.Background(), ChatParams{Messages: conversation()})
+	assert.NoError(t, err)
+	assert.Len(t, params.Messages, 2)
+}
+
+func TestContextManager_Summarize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	summarizer := NewMockProvider(ctrl)
+	summarizer.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, params ChatParams) (*ChatResponse, error) {
+			assert.Equal(t, "cheap", params.Model)
+			transcript := params.Messages[1].Content[0].Text
+			assert.Contains(t, transcript, "user: q1\n")
+			assert.Contains(t, transcript, "user: Result[1]: result\n")
+			assert.NotContains(t, transcript, "q3")
+			return responseWith(NewTextContent("they talked")), nil
+		}).Times(1)
+
+	messages := conversation()
+	m := NewContextManager(&messageCounter{}, 30,
+		WithContextStrategy(Summarize(summarizer, "cheap", 1)))
+	for i := 0; i < 2; i++ {
+		params, err := m.Fit(context.Background(), ChatParams{Messages: messages})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		// The summary is added to the system message
+		if assert.Len(t, params.Messages, 2) {
+			assert.Equal(t, "system", params.Messages[0].Role)
+			if assert.Len(t, params.Messages[0].Content, 2) {
+				assert.Equal(t, "system", params.Messages[0].Content[0].Text)
+				assert.Contains(t, params.Messages[0].Content[1].Text, "they talked")
+			}
+			assert.Equal(t, messages[7], params.Messages[1])
+		}
+		assert.Len(t, messages[0].Content, 1)
+	}
+}
+
+func TestContextManager_Wrap(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	provider := NewMockProvider(ctrl)
+	provider.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, params ChatParams) (*ChatResponse, error) {
+			assert.Len(t, params.Messages, 2)
+			return &ChatResponse{}, nil
+		})
+
+	wrapped := NewContextManager(&messageCounter{}, 20).Wrap(provider)
+	_, err := wrapped.Send(context.Background(), ChatParams{Messages: conversation()})
+	assert.NoError(t, err)
+
+	// Without counter the mock isn't a TokenCounter
+	wrapped = NewContextManager(nil, 20).Wrap(provider)
+	_, err = wrapped.Send(context.Background(), ChatParams{Messages: conversation()})
+	assert.ErrorIs(t, err, ErrCountTokensUnsupported)
+}
@@ -0,0 +1,31 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrUnsupportedParam = errors.New("parameter unsupported by provider")
+
+// ErrCountTokensUnsupported is returned by wrappers of a provider which
+// isn't a TokenCounter
+var ErrCountTokensUnsupported = errors.New("token counting unsupported by provider")
+
+// UnsupportedParamError is returned by the providers mapping when a
+// ChatParams field can't be translated instead of silently dropping it
+type UnsupportedParamError struct {
+	Provider string
+	Param    string
+}
+
+func NewUnsupportedParamError(provider, param string) *UnsupportedParamError {
+	return &UnsupportedParamError{Provider: provider, Param: param}
+}
+
+func (e *UnsupportedParamError) Error() string {
+	return fmt.Sprintf("%s: %s is unsupported by provider", e.Provider, e.Param)
+}
+
+func (e *UnsupportedParamError) Unwrap() error {
+	return ErrUnsupportedParam
+}
@@ -52,6 +52,94 @@ func WithTools(tools ...Tool) func(*ChatParams) {
 	}
 }
 
+// WithTopP sets the nucleus sampling for BaseChatMessageNewParams
+func WithTopP(topP float64) func(*ChatParams) {
+	return func(p *ChatParams) {
+		p.TopP = &topP
+	}
+}
+
+// WithTopK sets the top-k sampling for BaseChatMessageNewParams
+func WithTopK(topK int) func(*ChatParams) {
+	return func(p *ChatParams) {
+		p.TopK = &topK
+	}
+}
+
+// WithStopSequences sets the stop sequences for BaseChatMessageNewParams
+func WithStopSequences(stop ...string) func(*ChatParams) {
+	return func(p *ChatParams) {
+		p.StopSequences = stop
+	}
+}
+
+// WithSeed sets the seed for BaseChatMessageNewParams
+func WithSeed(seed int) func(*ChatParams) {
+	return func(p *ChatParams) {
+		p.Seed = &seed
+	}
+}
+
+// WithPresencePenalty sets the presence penalty for BaseChatMessageNewParams
+func WithPresencePenalty(penalty float64) func(*ChatParams) {
+	return func(p *ChatParams) {
+		p.PresencePenalty = &penalty
+	}
+}
+
+// WithFrequencyPenalty sets the frequency penalty for BaseChatMessageNewParams
+func WithFrequencyPenalty(penalty float64) func(*ChatParams) {
+	return func(p *ChatParams) {
+		p.FrequencyPenalty = &penalty
+	}
+}
+
+// WithLogitBias sets the logit bias (token id => bias) for BaseChatMessageNewParams
+func WithLogitBias(bias map[string]int) func(*ChatParams) {
+	return func(p *ChatParams) {
+		p.LogitBias = bias
+	}
+}
+
+// WithUser sets the end-user identifier for BaseChatMessageNewParams
+func WithUser(user string) func(*ChatParams) {
+	return func(p *ChatParams) {
+		p.User = user
+	}
+}
+
+// WithReasoningBudget enables reasoning with a budget of thinking tokens
+func WithReasoningBudget(tokens int) func(*ChatParams) {
+	return func(p *ChatParams) {
+		if p.Reasoning == nil {
+			p.Reasoning = &Reasoning{}
+		}
+		p.Reasoning.BudgetTokens = tokens
+	}
+}
+
+// WithReasoningEffort enables reasoning with an effort of low, medium or high
+func WithReasoningEffort(effort string) func(*ChatParams) {
+	return func(p *ChatParams) {
+		if p.Reasoning == nil {
+			p.Reasoning = &Reasoning{}
+		}
+		p.Reasoning.Effort = effort
+	}
+}
+
+// WithToolChoice sets the tool choice for BaseChatMessageNewParams
+func WithToolChoice(choice ToolChoice) func(*ChatParams) {
+	return func(p *ChatParams) {
+		p.ToolChoice = &choice
+	}
+}
+
+// WithToolChoiceTool forces the model to call the tool name
+func WithToolChoiceTool(name string) func(*ChatParams) {
+	return WithToolChoice(ToolChoice{Type: ToolChoiceTool, Name: name})
+}
+
 func NewMessage(role string, content ...*MessageContent) *ChatMessage {
 	return &ChatMessage{
 		Role:    role,
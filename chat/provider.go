@@ -19,3 +19,9 @@ type Provider interface {
 		params ChatParams,
 	) (streaming.Streamer[EventStream], error)
 }
+
+// TokenCounter is implemented by the providers able to count the input
+// tokens of a request, with the API or an offline tokenizer
+type TokenCounter interface {
+	CountTokens(ctx context.Context, params ChatParams) (int64, error)
+}
@@ -4,7 +4,7 @@ import "github.com/y0ug/llmhaven/http/streaming"
 
 // EventStream represents a normalized stream event across providers
 type EventStream struct {
-	Type    string // text_delta, message_start, message_stop, etc
+	Type    string // text_delta, thinking_delta, message_start, message_stop, etc
 	Delta   interface{}
 	Message *ChatResponse
 }
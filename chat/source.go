@@ -0,0 +1,106 @@
+package chat
+
+import (
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// NewImageFromFile reads an image and sniff its media type
+func NewImageFromFile(filename string) (*MessageContent, error) {
+	return newSourceFromFile(ContentTypeImage, filename)
+}
+
+// NewImageFromURL creates an image the provider will download
+func NewImageFromURL(u string) *MessageContent {
+	return newSourceFromURL(ContentTypeImage, u)
+}
+
+// NewImageFromFileID creates an image from a file uploaded to the provider,
+// OpenAI Chat Completions only accepts it as url or base64
+func NewImageFromFileID(fileID string) *MessageContent {
+	return newSourceFromFileID(ContentTypeImage, fileID)
+}
+
+// NewDocumentFromFile reads a document (PDF, text) and sniff its media type
+func NewDocumentFromFile(filename string) (*MessageContent, error) {
+	return newSourceFromFile(ContentTypeDocument, filename)
+}
+
+// NewDocumentFromURL creates a document the provider will download
+func NewDocumentFromURL(u string) *MessageContent {
+	return newSourceFromURL(ContentTypeDocument, u)
+}
+
+// NewDocumentFromFileID creates a document from a file uploaded to the provider
+func NewDocumentFromFileID(fileID string) *MessageContent {
+	return newSourceFromFileID(ContentTypeDocument, fileID)
+}
+
+func newSourceFromFile(contentType MessageContentType, filename string) (*MessageContent, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &MessageContent{
+		Type: contentType,
+		Source: &AIContentSrc{
+			Type:      SourceTypeBase64,
+			MediaType: DetectMediaType(data, filename),
+			Data:      data,
+		},
+	}, nil
+}
+
+func newSourceFromURL(contentType MessageContentType, u string) *MessageContent {
+	// The extension is the only hint we have without downloading it
+	mediaType := ""
+	if parsed, err := url.Parse(u); err == nil {
+		mediaType = mediaTypeByExtension(path.Ext(parsed.Path))
+	}
+	return &MessageContent{
+		Type: contentType,
+		Source: &AIContentSrc{
+			Type:      SourceTypeURL,
+			MediaType: mediaType,
+			URL:       u,
+		},
+	}
+}
+
+func newSourceFromFileID(contentType MessageContentType, fileID string) *MessageContent {
+	return &MessageContent{
+		Type: contentType,
+		Source: &AIContentSrc{
+			Type:   SourceTypeFile,
+			FileID: fileID,
+		},
+	}
+}
+
+// DetectMediaType sniffs the content, the extension of filename is used
+// when the content is plain text or unknown (markdown, csv, etc.)
+func DetectMediaType(data []byte, filename string) string {
+	mediaType := http.DetectContentType(data)
+	if i := strings.IndexByte(mediaType, ';'); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	if mediaType == "application/octet-stream" || mediaType == "text/plain" {
+		if t := mediaTypeByExtension(filepath.Ext(filename)); t != "" {
+			return t
+		}
+	}
+	return mediaType
+}
+
+func mediaTypeByExtension(ext string) string {
+	t := mime.TypeByExtension(strings.ToLower(ext))
+	if i := strings.IndexByte(t, ';'); i >= 0 {
+		t = t[:i]
+	}
+	return t
+}
@@ -0,0 +1,50 @@
+package chat
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewImageFromFile(t *testing.T) {
+	dir := t.TempDir()
+	png := filepath.Join(dir, "image.bin")
+	data := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	assert.NoError(t, os.WriteFile(png, data, 0o600))
+
+	content, err := NewImageFromFile(png)
+	if assert.NoError(t, err) {
+		assert.Equal(t, ContentTypeImage, content.Type)
+		assert.Equal(t, SourceTypeBase64, content.Source.Type)
+		assert.Equal(t, "image/png", content.Source.MediaType)
+		assert.Equal(t, data, content.Source.Data)
+	}
+
+	_, err = NewImageFromFile(filepath.Join(dir, "missing.png"))
+	assert.Error(t, err)
+}
+
+func TestDetectMediaType(t *testing.T) {
+	assert.Equal(t, "application/pdf", DetectMediaType([]byte("%PDF-1.7\n"), "doc"))
+	assert.Equal(t, "application/json", DetectMediaType([]byte(`{"a":1}`), "data.json"))
+	assert.Equal(t, "text/plain", DetectMediaType([]byte("hello"), "notes"))
+}
+
+func TestNewDocumentFromURL(t *testing.T) {
+	content := NewDocumentFromURL("https://example.com/files/report.pdf?dl=1")
+	assert.Equal(t, ContentTypeDocument, content.Type)
+	assert.Equal(t, SourceTypeURL, content.Source.Type)
+	assert.Equal(t, "application/pdf", content.Source.MediaType)
+
+	// Serialized as the Anthropic url and file sources
+	b, err := json.Marshal(content.Source)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type":"url","media_type":"application/pdf","url":"https://example.com/files/report.pdf?dl=1"}`, string(b))
+
+	b, err = json.Marshal(NewImageFromFileID("file_123").Source)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type":"file","file_id":"file_123"}`, string(b))
+}
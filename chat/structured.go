@@ -0,0 +1,130 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// WithResponseSchema asks for a JSON response matching the schema of T
+func WithResponseSchema[T any]() func(*ChatParams) {
+	return func(p *ChatParams) {
+		p.ResponseFormat = NewResponseFormat[T]()
+	}
+}
+
+// WithResponseRetries sets how many times SendTyped retries on invalid output
+func WithResponseRetries(n int) func(*ChatParams) {
+	return func(p *ChatParams) {
+		p.ResponseRetries = n
+	}
+}
+
+// NewResponseFormat creates a ResponseFormat from T, named after the type
+func NewResponseFormat[T any]() *ResponseFormat {
+	return &ResponseFormat{
+		Name:   responseFormatName[T](),
+		Schema: reflectSchema[T](),
+	}
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+func responseFormatName[T any]() string {
+	name := reflect.TypeOf((*T)(nil)).Elem().Name()
+	name = strings.Trim(invalidNameChars.ReplaceAllString(name, "_"), "_")
+	if name == "" {
+		return "response"
+	}
+	return name
+}
+
+// SendTyped sends the request and decodes the response into T. When params
+// has no ResponseFormat one is generated from T. The response is validated
+// against the schema, on failure the error is sent back to the model up to
+// params.ResponseRetries times.
+func SendTyped[T any](
+	ctx context.Context,
+	provider Provider,
+	params ChatParams,
+) (T, *ChatResponse, error) {
+	var zero T
+
+	if params.ResponseFormat == nil {
+		params.ResponseFormat = NewResponseFormat[T]()
+	}
+	schema := reflectSchema[T]()
+	params.Messages = append([]*ChatMessage{}, params.Messages...)
+
+	for attempt := 0; ; attempt++ {
+		resp, err := provider.Send(ctx, params)
+		if err != nil {
+			return zero, resp, err
+		}
+
+		data, toolCall := extractStructured(resp, params.ResponseFormat.Name)
+		v, err := decodeStrict[T](data, schema)
+		if err == nil {
+			return v, resp, nil
+		}
+		err = fmt.Errorf("response doesn't match schema: %w", err)
+		if attempt >= params.ResponseRetries {
+			return zero, resp, err
+		}
+
+		feedback := fmt.Sprintf(
+			"%s. Reply again with only the JSON matching the schema.",
+			err.Error(),
+		)
+		params.Messages = append(params.Messages, resp.ToMessageParams())
+		if toolCall != nil {
+			// A tool_use must be followed by its tool_result
+			params.Messages = append(params.Messages,
+				NewMessage("user", NewToolErrorContent(toolCall.ID, feedback)))
+		} else {
+			params.Messages = append(params.Messages, NewUserMessage(feedback))
+		}
+	}
+}
+
+// extractStructured returns the JSON of the response, from the tool call
+// named name if any otherwise from the text content
+func extractStructured(resp *ChatResponse, name string) (json.RawMessage, *MessageContent) {
+	if resp == nil || len(resp.Choice) == 0 {
+		return nil, nil
+	}
+
+	var text strings.Builder
+	for _, content := range resp.Choice[0].Content {
+		switch content.Type {
+		case ContentTypeToolUse:
+			if content.Name == name {
+				if len(content.Input) == 0 {
+					return json.RawMessage(content.InputJson), content
+				}
+				return content.Input, content
+			}
+		case ContentTypeText:
+			text.WriteString(content.Text)
+		}
+	}
+	return stripCodeFence([]byte(text.String())), nil
+}
+
+// stripCodeFence removes the ```json fence some models add around the output
+func stripCodeFence(b []byte) []byte {
+	b = bytes.TrimSpace(b)
+	if !bytes.HasPrefix(b, []byte("```")) {
+		return b
+	}
+	b = b[3:]
+	if i := bytes.IndexByte(b, '\n'); i >= 0 {
+		b = b[i+1:]
+	}
+	b = bytes.TrimSuffix(bytes.TrimSpace(b), []byte("```"))
+	return bytes.TrimSpace(b)
+}
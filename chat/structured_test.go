@@ -0,0 +1,92 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+type cityInfo struct {
+	Name       string `json:"name"`
+	Population int    `json:"population"`
+}
+
+func responseWith(content ...*MessageContent) *ChatResponse {
+	return &ChatResponse{
+		Choice: []ChatChoice{{Role: "assistant", Content: content}},
+	}
+}
+
+func TestSendTyped(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		provider := NewMockProvider(ctrl)
+		provider.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, params ChatParams) (*ChatResponse, error) {
+				if assert.NotNil(t, params.ResponseFormat) {
+					assert.Equal(t, "cityInfo", params.ResponseFormat.Name)
+				}
+				return responseWith(NewTextContent(
+					"```json\n{\"name\":\"Paris\",\"population\":2100000}\n```",
+				)), nil
+			})
+
+		v, _, err := SendTyped[cityInfo](context.Background(), provider, ChatParams{})
+		assert.NoError(t, err)
+		assert.Equal(t, cityInfo{Name: "Paris", Population: 2100000}, v)
+	})
+
+	t.Run("tool", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		provider := NewMockProvider(ctrl)
+		provider.EXPECT().Send(gomock.Any(), gomock.Any()).Return(
+			responseWith(NewToolUseContent("1", "cityInfo",
+				json.RawMessage(`{"name":"Rome","population":2800000}`))), nil)
+
+		params := NewChatParams(WithResponseSchema[cityInfo]())
+		v, _, err := SendTyped[cityInfo](context.Background(), provider, *params)
+		assert.NoError(t, err)
+		assert.Equal(t, "Rome", v.Name)
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		provider := NewMockProvider(ctrl)
+		gomock.InOrder(
+			provider.EXPECT().Send(gomock.Any(), gomock.Any()).Return(
+				responseWith(NewToolUseContent("1", "cityInfo",
+					json.RawMessage(`{"name":"Rome"}`))), nil),
+			provider.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, params ChatParams) (*ChatResponse, error) {
+					if assert.Len(t, params.Messages, 2) {
+						feedback := params.Messages[1].Content[0]
+						assert.Equal(t, ContentTypeToolResult, feedback.Type)
+						assert.Equal(t, "1", feedback.ToolUseID)
+						assert.True(t, feedback.IsError)
+						assert.Contains(t, feedback.Content, "population")
+					}
+					return responseWith(NewToolUseContent("2", "cityInfo",
+						json.RawMessage(`{"name":"Rome","population":2800000}`))), nil
+				}),
+		)
+
+		params := NewChatParams(WithResponseSchema[cityInfo](), WithResponseRetries(1))
+		v, _, err := SendTyped[cityInfo](context.Background(), provider, *params)
+		assert.NoError(t, err)
+		assert.Equal(t, 2800000, v.Population)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		provider := NewMockProvider(ctrl)
+		provider.EXPECT().Send(gomock.Any(), gomock.Any()).Return(
+			responseWith(NewTextContent(`{"name":"Rome","country":"Italy"}`)), nil)
+
+		_, resp, err := SendTyped[cityInfo](context.Background(), provider, ChatParams{})
+		assert.Error(t, err)
+		assert.NotNil(t, resp)
+	})
+}
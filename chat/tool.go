@@ -0,0 +1,166 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/invopop/jsonschema"
+)
+
+// CallableTool is a tool that can be described to the model and executed locally
+type CallableTool interface {
+	Definition() Tool
+	Call(ctx context.Context, input json.RawMessage) (string, error)
+}
+
+// TypedTool binds a Go function to a Tool, the input schema is reflected
+// from In and the result Out is marshalled into the tool_result
+type TypedTool[In any, Out any] struct {
+	tool   Tool
+	schema *jsonschema.Schema
+	fn     func(context.Context, In) (Out, error)
+}
+
+var _ CallableTool = (*TypedTool[struct{}, string])(nil)
+
+// NewTypedTool creates a tool from a Go function
+func NewTypedTool[In any, Out any](
+	name string,
+	description string,
+	fn func(context.Context, In) (Out, error),
+) *TypedTool[In, Out] {
+	schema := reflectSchema[In]()
+	tool := Tool{
+		Name:        name,
+		InputSchema: schema,
+	}
+	if description != "" {
+		tool.Description = &description
+	}
+	return &TypedTool[In, Out]{
+		tool:   tool,
+		schema: schema,
+		fn:     fn,
+	}
+}
+
+// Definition returns the Tool to send to the model
+func (t *TypedTool[In, Out]) Definition() Tool {
+	return t.tool
+}
+
+// Decode validates the tool call arguments against the schema and decode them
+func (t *TypedTool[In, Out]) Decode(call *MessageContent) (In, error) {
+	input := call.Input
+	if len(input) == 0 {
+		input = json.RawMessage(call.InputJson)
+	}
+	return decodeStrict[In](input, t.schema)
+}
+
+// Call decodes the input, runs the function and marshal its result
+func (t *TypedTool[In, Out]) Call(ctx context.Context, input json.RawMessage) (string, error) {
+	in, err := decodeStrict[In](input, t.schema)
+	if err != nil {
+		return "", err
+	}
+	out, err := t.fn(ctx, in)
+	if err != nil {
+		return "", err
+	}
+
+	// Don't quote plain strings, it's easier to read for the model
+	if s, ok := any(out).(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(out)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling tool result: %w", err)
+	}
+	return string(b), nil
+}
+
+// Execute runs the tool for the call and returns the tool_result content,
+// errors are reported to the model with is_error set
+func (t *TypedTool[In, Out]) Execute(ctx context.Context, call *MessageContent) *MessageContent {
+	input := call.Input
+	if len(input) == 0 {
+		input = json.RawMessage(call.InputJson)
+	}
+	out, err := t.Call(ctx, input)
+	if err != nil {
+		return NewToolErrorContent(call.ID, err.Error())
+	}
+	return NewToolResultContent(call.ID, out)
+}
+
+// GenerateSchema reflects the JSON schema of T as expected by Tool.InputSchema
+func GenerateSchema[T any]() interface{} {
+	return reflectSchema[T]()
+}
+
+func reflectSchema[T any]() *jsonschema.Schema {
+	reflector := jsonschema.Reflector{
+		AllowAdditionalProperties: false,
+		DoNotReference:            true,
+	}
+	var v T
+	return reflector.Reflect(v)
+}
+
+// decodeStrict decodes data into T, rejecting unknown fields and missing
+// required properties
+func decodeStrict[T any](data json.RawMessage, schema *jsonschema.Schema) (T, error) {
+	var v T
+	if len(bytes.TrimSpace(data)) == 0 {
+		data = json.RawMessage("{}")
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return v, fmt.Errorf("invalid json: %w", err)
+	}
+	if err := validateRequired(schema, raw, ""); err != nil {
+		return v, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&v); err != nil {
+		return v, fmt.Errorf("invalid input: %w", err)
+	}
+	return v, nil
+}
+
+func validateRequired(schema *jsonschema.Schema, value interface{}, path string) error {
+	if schema == nil {
+		return nil
+	}
+	switch val := value.(type) {
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, ok := val[name]; !ok {
+				return fmt.Errorf("missing required property %q", path+name)
+			}
+		}
+		if schema.Properties == nil {
+			return nil
+		}
+		for pair := schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+			if v, ok := val[pair.Key]; ok {
+				if err := validateRequired(pair.Value, v, path+pair.Key+"."); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		for i, v := range val {
+			if err := validateRequired(schema.Items, v, fmt.Sprintf("%s%d.", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
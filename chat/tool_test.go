@@ -0,0 +1,85 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type weatherInput struct {
+	Location string `json:"location" jsonschema_description:"The location to look up."`
+	Unit     string `json:"unit,omitempty"`
+}
+
+type weatherOutput struct {
+	Temperature float64 `json:"temperature"`
+}
+
+func TestTypedTool(t *testing.T) {
+	tool := NewTypedTool("get_weather", "Get the weather",
+		func(ctx context.Context, in weatherInput) (weatherOutput, error) {
+			return weatherOutput{Temperature: 21.5}, nil
+		})
+
+	def := tool.Definition()
+	assert.Equal(t, "get_weather", def.Name)
+	assert.Equal(t, "Get the weather", *def.Description)
+	schema, err := json.Marshal(def.InputSchema)
+	assert.NoError(t, err)
+	assert.Contains(t, string(schema), `"required":["location"]`)
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr string
+	}{
+		{name: "valid", input: `{"location":"Paris"}`, want: `{"temperature":21.5}`},
+		{name: "optional", input: `{"location":"Paris","unit":"c"}`, want: `{"temperature":21.5}`},
+		{name: "missing required", input: `{"unit":"c"}`, wantErr: `missing required property "location"`},
+		{name: "unknown field", input: `{"location":"Paris","foo":1}`, wantErr: `unknown field "foo"`},
+		{name: "wrong type", input: `{"location":42}`, wantErr: "cannot unmarshal"},
+		{name: "empty", input: ``, wantErr: "missing required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tool.Call(context.Background(), json.RawMessage(tt.input))
+			if tt.wantErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.wantErr)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTypedTool_Execute(t *testing.T) {
+	tool := NewTypedTool("echo", "",
+		func(ctx context.Context, in weatherInput) (string, error) {
+			return in.Location, nil
+		})
+	assert.Nil(t, tool.Definition().Description)
+
+	call := &MessageContent{
+		Type:      ContentTypeToolUse,
+		ID:        "call_1",
+		Name:      "echo",
+		InputJson: []byte(`{"location":"Paris"}`),
+	}
+	in, err := tool.Decode(call)
+	assert.NoError(t, err)
+	assert.Equal(t, "Paris", in.Location)
+
+	result := tool.Execute(context.Background(), call)
+	assert.Equal(t, NewToolResultContent("call_1", "Paris"), result)
+
+	call.InputJson = []byte(`{}`)
+	result = tool.Execute(context.Background(), call)
+	assert.True(t, result.IsError)
+	assert.Equal(t, "call_1", result.ToolUseID)
+}
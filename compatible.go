@@ -0,0 +1,53 @@
+package llmhaven
+
+import (
+	"os"
+
+	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/http/options"
+	"github.com/y0ug/llmhaven/providers/openai"
+)
+
+// ProviderFactory creates a provider, opts are applied after the defaults
+type ProviderFactory func(opts ...options.RequestOption) chat.Provider
+
+// NewOpenAICompatible returns a factory for an OpenAI compatible API. The
+// API key is read from envKey when set, quirks describe the differences
+// with the OpenAI API.
+func NewOpenAICompatible(
+	name string,
+	baseURL string,
+	envKey string,
+	quirks ...openai.Quirk,
+) ProviderFactory {
+	return func(opts ...options.RequestOption) chat.Provider {
+		defaults := []options.RequestOption{
+			options.WithBaseURL(baseURL),
+		}
+		if envKey != "" {
+			if o, ok := os.LookupEnv(envKey); ok {
+				defaults = append(defaults, options.WithAuthToken(o))
+			}
+		}
+		return openai.NewCompatible(name, quirks, append(defaults, opts...)...)
+	}
+}
+
+var (
+	NewGroq = NewOpenAICompatible(
+		"groq", "https://api.groq.com/openai/v1/", "GROQ_API_KEY",
+		openai.WithReasoningField("reasoning"), openai.WithUnsupported("logit_bias"))
+	NewTogether = NewOpenAICompatible(
+		"together", "https://api.together.xyz/v1/", "TOGETHER_API_KEY",
+		openai.WithMaxTokensField(), openai.WithTopK())
+	NewMistral = NewOpenAICompatible(
+		"mistral", "https://api.mistral.ai/v1/", "MISTRAL_API_KEY",
+		openai.WithMaxTokensField(), openai.WithNoStreamOptions(), openai.WithNoBatch(),
+		openai.WithRandomSeedField(), openai.WithUnsupported("logit_bias"))
+	NewVLLM = NewOpenAICompatible(
+		"vllm", "http://localhost:8000/v1/", "VLLM_API_KEY",
+		openai.WithMaxTokensField(), openai.WithNoBatch(), openai.WithTopK())
+	NewLMStudio = NewOpenAICompatible(
+		"lmstudio", "http://localhost:1234/v1/", "LMSTUDIO_API_KEY",
+		openai.WithMaxTokensField(), openai.WithNoBatch(), openai.WithTopK())
+)
@@ -0,0 +1,86 @@
+package llmhaven
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/y0ug/llmhaven/embedding"
+	"github.com/y0ug/llmhaven/http/options"
+	"github.com/y0ug/llmhaven/providers/gemini"
+	"github.com/y0ug/llmhaven/providers/ollama"
+	"github.com/y0ug/llmhaven/providers/openai"
+	"github.com/y0ug/llmhaven/providers/openrouter"
+)
+
+// EmbedderFactory creates an embedder, opts are applied after the defaults
+type EmbedderFactory func(opts ...options.RequestOption) embedding.Embedder
+
+var embedders = map[string]EmbedderFactory{}
+
+func init() {
+	RegisterEmbedder("openai", openai.NewEmbedder)
+	RegisterEmbedder("gemini", gemini.NewEmbedder)
+	RegisterEmbedder("openrouter", openrouter.NewEmbedder)
+	RegisterEmbedder("ollama", ollama.NewEmbedder)
+}
+
+// RegisterEmbedder makes an embedder available in NewEmbedder, the provider
+// aliases apply to it
+func RegisterEmbedder(name string, factory EmbedderFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	embedders[strings.ToLower(name)] = factory
+}
+
+// Embedders returns the sorted names of the registered embedders
+func Embedders() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(embedders))
+	for name := range embedders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewEmbedder creates the embedder of a provider, name can be
+// "provider/model" to set the default model
+func NewEmbedder(name string, requestOpts ...options.RequestOption,
+) (embedding.Embedder, error) {
+	providerName, model, _ := strings.Cut(name, "/")
+
+	registryMu.RLock()
+	providerName = strings.ToLower(providerName)
+	if target, ok := aliases[providerName]; ok {
+		providerName = target
+	}
+	factory, ok := embedders[providerName]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("embedder %s not found", providerName)
+	}
+
+	e := factory(requestOpts...)
+	if model != "" {
+		e = &modelEmbedder{Embedder: e, model: model}
+	}
+	return e, nil
+}
+
+// modelEmbedder binds an embedder to a model
+type modelEmbedder struct {
+	embedding.Embedder
+	model string
+}
+
+func (e *modelEmbedder) Embed(
+	ctx context.Context,
+	inputs []string,
+	opts ...func(*embedding.EmbedParams),
+) ([][]float32, embedding.Usage, error) {
+	opts = append([]func(*embedding.EmbedParams){embedding.WithModel(e.model)}, opts...)
+	return e.Embedder.Embed(ctx, inputs, opts...)
+}
@@ -0,0 +1,99 @@
+// Package embedding defines the Embedder implemented by the providers with
+// an embeddings API.
+package embedding
+
+import (
+	"context"
+	"fmt"
+)
+
+type Usage struct {
+	InputTokens int `json:"input_tokens"`
+}
+
+type EmbedParams struct {
+	Model string
+	// Dimensions of the output vectors, 0 keeps the model default. Only the
+	// models trained for it support a reduced size.
+	Dimensions int
+	// TaskType optimizes the embedding for a use on Gemini, ignored by the
+	// other providers
+	TaskType string
+	// BatchSize is the max number of inputs per request, 0 uses the limit of
+	// the provider
+	BatchSize int
+}
+
+// Gemini task types
+const (
+	TaskRetrievalQuery     = "RETRIEVAL_QUERY"
+	TaskRetrievalDocument  = "RETRIEVAL_DOCUMENT"
+	TaskSemanticSimilarity = "SEMANTIC_SIMILARITY"
+	TaskClassification     = "CLASSIFICATION"
+	TaskClustering         = "CLUSTERING"
+)
+
+type Embedder interface {
+	// Embed returns a vector per input, in the inputs order
+	Embed(ctx context.Context, inputs []string, opts ...func(*EmbedParams)) ([][]float32, Usage, error)
+}
+
+func NewEmbedParams(opts ...func(*EmbedParams)) *EmbedParams {
+	p := &EmbedParams{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func WithModel(model string) func(*EmbedParams) {
+	return func(p *EmbedParams) {
+		p.Model = model
+	}
+}
+
+func WithDimensions(n int) func(*EmbedParams) {
+	return func(p *EmbedParams) {
+		p.Dimensions = n
+	}
+}
+
+func WithTaskType(taskType string) func(*EmbedParams) {
+	return func(p *EmbedParams) {
+		p.TaskType = taskType
+	}
+}
+
+func WithBatchSize(n int) func(*EmbedParams) {
+	return func(p *EmbedParams) {
+		p.BatchSize = n
+	}
+}
+
+// InBatches calls embed with at most size inputs and concatenates the
+// vectors, the usage is summed
+func InBatches(
+	ctx context.Context,
+	inputs []string,
+	size int,
+	embed func(ctx context.Context, batch []string) ([][]float32, Usage, error),
+) ([][]float32, Usage, error) {
+	var usage Usage
+	if size <= 0 {
+		size = len(inputs)
+	}
+	vectors := make([][]float32, 0, len(inputs))
+	for start := 0; start < len(inputs); start += size {
+		end := min(start+size, len(inputs))
+		batch, u, err := embed(ctx, inputs[start:end])
+		if err != nil {
+			return nil, usage, err
+		}
+		if len(batch) != end-start {
+			return nil, usage, fmt.Errorf("got %d embeddings for %d inputs", len(batch), end-start)
+		}
+		vectors = append(vectors, batch...)
+		usage.InputTokens += u.InputTokens
+	}
+	return vectors, usage, nil
+}
@@ -0,0 +1,52 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInBatches(t *testing.T) {
+	var sizes []int
+	embed := func(ctx context.Context, batch []string) ([][]float32, Usage, error) {
+		sizes = append(sizes, len(batch))
+		vectors := make([][]float32, len(batch))
+		for i, s := range batch {
+			vectors[i] = []float32{float32(len(s))}
+		}
+		return vectors, Usage{InputTokens: len(batch)}, nil
+	}
+
+	inputs := []string{"a", "bb", "ccc", "dddd", "eeeee"}
+	vectors, usage, err := InBatches(context.Background(), inputs, 2, embed)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 2, 1}, sizes)
+	assert.Equal(t, 5, usage.InputTokens)
+	if assert.Len(t, vectors, 5) {
+		assert.Equal(t, float32(3), vectors[2][0])
+		assert.Equal(t, float32(5), vectors[4][0])
+	}
+
+	// A backend returning less vectors than inputs
+	_, _, err = InBatches(context.Background(), inputs, 0,
+		func(ctx context.Context, batch []string) ([][]float32, Usage, error) {
+			return [][]float32{{1}}, Usage{}, nil
+		})
+	assert.Error(t, err)
+
+	_, _, err = InBatches(context.Background(), inputs, 2,
+		func(ctx context.Context, batch []string) ([][]float32, Usage, error) {
+			return nil, Usage{}, errors.New("boom")
+		})
+	assert.EqualError(t, err, "boom")
+}
+
+func TestNewEmbedParams(t *testing.T) {
+	p := NewEmbedParams(WithModel("m"), WithDimensions(256),
+		WithTaskType(TaskRetrievalQuery), WithBatchSize(10))
+	assert.Equal(t, &EmbedParams{
+		Model: "m", Dimensions: 256, TaskType: TaskRetrievalQuery, BatchSize: 10,
+	}, p)
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/y0ug/llmhaven"
 	"github.com/y0ug/llmhaven/chat"
 	"github.com/y0ug/llmhaven/http/options"
-	"github.com/y0ug/llmhaven/providers/anthropic"
 )
 
 func ApiCountToken(
@@ -25,7 +24,7 @@ func ApiCountToken(
 		return 0, fmt.Errorf("Failed to create provider: %v", err)
 	}
 
-	if llm, ok := llm.(*anthropic.Provider); ok {
+	if llm, ok := llm.(chat.TokenCounter); ok {
 		tokens, err := llm.CountTokens(ctxRequest, params)
 		if err != nil {
 			return 0, fmt.Errorf("Failed to count tokens: %v", err)
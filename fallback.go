@@ -0,0 +1,187 @@
+package llmhaven
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/y0ug/llmhaven/chat"
+	llmerrors "github.com/y0ug/llmhaven/http/errors"
+	"github.com/y0ug/llmhaven/http/options"
+	"github.com/y0ug/llmhaven/http/streaming"
+	"github.com/y0ug/llmhaven/modelinfo"
+)
+
+// ProviderWithModel is an entry of a composed provider, Model replaces
+// ChatParams.Model for its requests
+type ProviderWithModel struct {
+	Name     string // reported in ChatResponse.Provider
+	Provider chat.Provider
+	Model    string
+}
+
+// NewProviderWithModel creates the entry of a "provider/model" string
+func NewProviderWithModel(
+	model string,
+	requestOpts ...options.RequestOption,
+) (ProviderWithModel, error) {
+	m, err := modelinfo.Get(model, nil)
+	if err != nil {
+		return ProviderWithModel{}, err
+	}
+	provider, err := New(m.Provider, requestOpts...)
+	if err != nil {
+		return ProviderWithModel{}, err
+	}
+	return ProviderWithModel{Name: m.Provider, Provider: provider, Model: m.Name}, nil
+}
+
+// String returns name/model
+func (p ProviderWithModel) String() string {
+	return p.Name + "/" + p.Model
+}
+
+func (p ProviderWithModel) params(params chat.ChatParams) chat.ChatParams {
+	if p.Model != "" {
+		params.Model = p.Model
+	}
+	return params
+}
+
+// Fallback sends the request to its entries in order, the next one is
+// tried when the error is retryable (rate limit, overloaded, server error)
+type Fallback struct {
+	entries []ProviderWithModel
+}
+
+var _ chat.Provider = (*Fallback)(nil)
+
+// NewFallback creates a provider failing over to the next entry on a
+// retryable error. ChatResponse.Provider is set to the entry which served
+// the request.
+func NewFallback(providers ...ProviderWithModel) *Fallback {
+	return &Fallback{entries: providers}
+}
+
+func (f *Fallback) Send(ctx context.Context, params chat.ChatParams) (*chat.ChatResponse, error) {
+	var errs []error
+	for i, entry := range f.entries {
+		resp, err := entry.Provider.Send(ctx, entry.params(params))
+		if err == nil {
+			if resp != nil {
+				resp.Provider = entry.String()
+			}
+			return resp, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", entry, err))
+		if !llmerrors.IsRetryable(err) || i == len(f.entries)-1 {
+			break
+		}
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("fallback has no provider")
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Stream fails over only until the first event, once an event was read the
+// error of the stream is returned
+func (f *Fallback) Stream(
+	ctx context.Context,
+	params chat.ChatParams,
+) (streaming.Streamer[chat.EventStream], error) {
+	s := &fallbackStream{ctx: ctx, entries: f.entries, params: params}
+	if !s.open() {
+		return nil, s.err
+	}
+	return s, nil
+}
+
+type fallbackStream struct {
+	ctx     context.Context
+	entries []ProviderWithModel
+	params  chat.ChatParams
+
+	i       int // index of the current entry
+	stream  streaming.Streamer[chat.EventStream]
+	current chat.EventStream
+	started bool
+	errs    []error
+	err     error
+}
+
+// open starts the stream of the first entry from s.i which accepts it
+func (s *fallbackStream) open() bool {
+	s.stream = nil
+	for ; s.i < len(s.entries); s.i++ {
+		entry := s.entries[s.i]
+		stream, err := entry.Provider.Stream(s.ctx, entry.params(s.params))
+		if err == nil {
+			s.stream, s.err = stream, nil
+			return true
+		}
+		if !s.failed(err) {
+			return false
+		}
+	}
+	if s.err == nil {
+		s.err = errors.New("fallback has no provider")
+	}
+	return false
+}
+
+// failed records the error of the current entry and reports if the next
+// one can be tried
+func (s *fallbackStream) failed(err error) bool {
+	s.errs = append(s.errs, fmt.Errorf("%s: %w", s.entries[s.i], err))
+	s.err = errors.Join(s.errs...)
+	return llmerrors.IsRetryable(err) && s.i < len(s.entries)-1
+}
+
+func (s *fallbackStream) Next() bool {
+	for s.stream != nil {
+		if s.stream.Next() {
+			evt := s.stream.Current()
+			s.started = true
+			if evt.Message != nil {
+				evt.Message.Provider = s.entries[s.i].String()
+			}
+			s.current = evt
+			return true
+		}
+
+		// An overloaded API can answer 200 then fail, the provider
+		// returns an error with the status of the failure
+		err := s.stream.Err()
+		if err == nil {
+			return false
+		}
+		if s.started || !s.failed(err) {
+			if s.started {
+				s.err = err
+			}
+			return false
+		}
+		s.stream.Close()
+		s.i++
+		if !s.open() {
+			return false
+		}
+	}
+	return false
+}
+
+func (s *fallbackStream) Current() chat.EventStream {
+	return s.current
+}
+
+func (s *fallbackStream) Err() error {
+	return s.err
+}
+
+func (s *fallbackStream) Close() error {
+	if s.stream == nil {
+		return nil
+	}
+	return s.stream.Close()
+}
@@ -0,0 +1,175 @@
+package llmhaven
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y0ug/llmhaven/chat"
+	llmerrors "github.com/y0ug/llmhaven/http/errors"
+	"github.com/y0ug/llmhaven/http/options"
+	"github.com/y0ug/llmhaven/providers/anthropic"
+	"go.uber.org/mock/gomock"
+)
+
+// statusError is an API error without request, APIErrorBase.Error needs it
+type statusError int
+
+func (e statusError) Error() string      { return http.StatusText(int(e)) }
+func (e statusError) GetStatusCode() int { return int(e) }
+
+func apiError(code int) error {
+	return statusError(code)
+}
+
+type sliceStream struct {
+	events []chat.EventStream
+	err    error
+	i      int
+}
+
+func (s *sliceStream) Next() bool {
+	s.i++
+	return s.i <= len(s.events)
+}
+func (s *sliceStream) Current() chat.EventStream { return s.events[s.i-1] }
+func (s *sliceStream) Err() error                { return s.err }
+func (s *sliceStream) Close() error              { return nil }
+
+func TestFallback_Send(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	primary := chat.NewMockProvider(ctrl)
+	secondary := chat.NewMockProvider(ctrl)
+	f := NewFallback(
+		ProviderWithModel{Name: "anthropic", Provider: primary, Model: "claude"},
+		ProviderWithModel{Name: "openai", Provider: secondary, Model: "gpt-4o"},
+	)
+
+	primary.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, params chat.ChatParams) (*chat.ChatResponse, error) {
+			assert.Equal(t, "claude", params.Model)
+			return nil, apiError(llmerrors.StatusOverloaded)
+		})
+	secondary.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, params chat.ChatParams) (*chat.ChatResponse, error) {
+			assert.Equal(t, "gpt-4o", params.Model)
+			return &chat.ChatResponse{}, nil
+		})
+	resp, err := f.Send(context.Background(), chat.ChatParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, "openai/gpt-4o", resp.Provider)
+
+	// Not retryable, the next entry isn't tried
+	primary.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil, apiError(http.StatusBadRequest))
+	_, err = f.Send(context.Background(), chat.ChatParams{})
+	assert.Equal(t, http.StatusBadRequest, llmerrors.StatusCode(err))
+
+	// Every entry failed
+	primary.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil, apiError(http.StatusTooManyRequests))
+	secondary.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil, apiError(http.StatusBadGateway))
+	_, err = f.Send(context.Background(), chat.ChatParams{})
+	assert.ErrorContains(t, err, "anthropic/claude")
+	assert.ErrorContains(t, err, "openai/gpt-4o")
+}
+
+func TestFallback_Stream(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	primary := chat.NewMockProvider(ctrl)
+	secondary := chat.NewMockProvider(ctrl)
+	f := NewFallback(
+		ProviderWithModel{Name: "anthropic", Provider: primary, Model: "claude"},
+		ProviderWithModel{Name: "openai", Provider: secondary, Model: "gpt-4o"},
+	)
+	ok := func() *sliceStream {
+		return &sliceStream{events: []chat.EventStream{
+			{Type: "text_delta", Delta: "hi", Message: &chat.ChatResponse{}},
+			{Type: "message_stop", Message: &chat.ChatResponse{}},
+		}}
+	}
+	t.Run("error before the first event", func(t *testing.T) {
+		primary.EXPECT().Stream(gomock.Any(), gomock.Any()).Return(
+			&sliceStream{err: apiError(http.StatusInternalServerError)}, nil)
+		secondary.EXPECT().Stream(gomock.Any(), gomock.Any()).Return(ok(), nil)
+
+		stream, err := f.Stream(context.Background(), chat.ChatParams{})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		var last chat.EventStream
+		n := 0
+		for stream.Next() {
+			last = stream.Current()
+			n++
+		}
+		assert.NoError(t, stream.Err())
+		assert.Equal(t, 2, n)
+		assert.Equal(t, "openai/gpt-4o", last.Message.Provider)
+	})
+
+	t.Run("anthropic error event", func(t *testing.T) {
+		// Anthropic answers 200 then sends the error event
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "event: error\n"+
+				`data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`+"\n\n")
+		}))
+		defer server.Close()
+		anthropicProvider := anthropic.New(
+			options.WithBaseURL(server.URL+"/"), options.WithApiKey("x-api-key", "test"))
+		f := NewFallback(
+			ProviderWithModel{Name: "anthropic", Provider: anthropicProvider, Model: "claude"},
+			ProviderWithModel{Name: "openai", Provider: secondary, Model: "gpt-4o"},
+		)
+		secondary.EXPECT().Stream(gomock.Any(), gomock.Any()).Return(ok(), nil)
+
+		stream, err := f.Stream(context.Background(), *chat.NewChatParams(
+			chat.WithMaxTokens(100), chat.WithMessages(chat.NewUserMessage("hi"))))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.True(t, stream.Next())
+		assert.Equal(t, "text_delta", stream.Current().Type)
+		assert.Equal(t, "openai/gpt-4o", stream.Current().Message.Provider)
+	})
+
+	t.Run("error event not retryable", func(t *testing.T) {
+		primary.EXPECT().Stream(gomock.Any(), gomock.Any()).Return(
+			&sliceStream{events: []chat.EventStream{{Type: "error"}}}, nil)
+
+		stream, err := f.Stream(context.Background(), chat.ChatParams{})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.True(t, stream.Next())
+		assert.Equal(t, "error", stream.Current().Type)
+	})
+
+	t.Run("open error", func(t *testing.T) {
+		primary.EXPECT().Stream(gomock.Any(), gomock.Any()).Return(
+			nil, apiError(llmerrors.StatusOverloaded))
+		secondary.EXPECT().Stream(gomock.Any(), gomock.Any()).Return(
+			nil, apiError(http.StatusUnauthorized))
+
+		_, err := f.Stream(context.Background(), chat.ChatParams{})
+		assert.ErrorContains(t, err, "anthropic/claude")
+		assert.ErrorContains(t, err, "openai/gpt-4o: Unauthorized")
+	})
+
+	t.Run("no fail over once started", func(t *testing.T) {
+		s := ok()
+		s.err = apiError(http.StatusInternalServerError)
+		primary.EXPECT().Stream(gomock.Any(), gomock.Any()).Return(s, nil)
+
+		stream, err := f.Stream(context.Background(), chat.ChatParams{})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		for stream.Next() {
+		}
+		assert.True(t, errors.Is(stream.Err(), s.err))
+	})
+}
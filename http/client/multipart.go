@@ -0,0 +1,64 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"sort"
+	"strings"
+)
+
+// MultipartFile is a file field of a multipart/form-data body
+type MultipartFile struct {
+	FieldName   string
+	Filename    string
+	ContentType string // application/octet-stream when empty
+	Content     io.Reader
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// NewMultipartBody encodes the fields and files, it returns the body and its
+// content type to use with options.WithRequestBody. The body is buffered so
+// the request can be retried.
+func NewMultipartBody(fields map[string]string, files ...MultipartFile) ([]byte, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	// Sorted to get a stable body
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := w.WriteField(k, fields[k]); err != nil {
+			return nil, "", err
+		}
+	}
+
+	for _, f := range files {
+		contentType := f.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			quoteEscaper.Replace(f.FieldName), quoteEscaper.Replace(f.Filename)))
+		h.Set("Content-Type", contentType)
+		part, err := w.CreatePart(h)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := io.Copy(part, f.Content); err != nil {
+			return nil, "", fmt.Errorf("error writing %s: %w", f.Filename, err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
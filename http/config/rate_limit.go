@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"sync"
@@ -15,52 +16,128 @@ type RateLimit struct {
 	RemainingTokens   int
 	ResetRequests     time.Duration
 	ResetTokens       time.Duration
+	UpdatedAt         time.Time // zero until a response had the headers
 	mu                sync.RWMutex
 }
 
-// Update updates the RateLimit fields based on the provided headers.
+// RateLimitParser updates rl from the headers of a response, the headers
+// of each provider have their own format
+type RateLimitParser func(rl *RateLimit, headers http.Header) error
+
+var (
+	ParseOpenAIRateLimit    RateLimitParser = (*RateLimit).Update
+	ParseAnthropicRateLimit RateLimitParser = (*RateLimit).UpdateAnthropic
+)
+
+// Update updates the RateLimit fields based on the provided headers. The
+// X-Ratelimit-* headers of OpenAI are used, the resets are durations like
+// "6m0s" or seconds. Nothing is changed when the headers are missing.
 func (rl *RateLimit) Update(headers http.Header) error {
-	limitRequests, err := strconv.Atoi(headers.Get("X-Ratelimit-Limit-Requests"))
-	if err != nil {
-		return err
+	if headers.Get("X-Ratelimit-Limit-Requests") == "" &&
+		headers.Get("X-Ratelimit-Limit-Tokens") == "" {
+		return nil
 	}
-
-	limitTokens, err := strconv.Atoi(headers.Get("X-Ratelimit-Limit-Tokens"))
-	if err != nil {
-		return err
+	var v rateLimitValues
+	v.parseInt(&v.limitRequests, headers, "X-Ratelimit-Limit-Requests")
+	v.parseInt(&v.limitTokens, headers, "X-Ratelimit-Limit-Tokens")
+	v.parseInt(&v.remainingRequests, headers, "X-Ratelimit-Remaining-Requests")
+	v.parseInt(&v.remainingTokens, headers, "X-Ratelimit-Remaining-Tokens")
+	v.parseDuration(&v.resetRequests, headers, "X-Ratelimit-Reset-Requests")
+	v.parseDuration(&v.resetTokens, headers, "X-Ratelimit-Reset-Tokens")
+	if v.err != nil {
+		return v.err
 	}
+	rl.set(v)
+	return nil
+}
 
-	remainingRequests, err := strconv.Atoi(headers.Get("X-Ratelimit-Remaining-Requests"))
-	if err != nil {
-		return err
+// UpdateAnthropic updates the RateLimit fields from the anthropic-ratelimit-*
+// headers, the resets are RFC 3339 times
+func (rl *RateLimit) UpdateAnthropic(headers http.Header) error {
+	if headers.Get("Anthropic-Ratelimit-Requests-Limit") == "" &&
+		headers.Get("Anthropic-Ratelimit-Tokens-Limit") == "" {
+		return nil
+	}
+	var v rateLimitValues
+	now := time.Now()
+	v.parseInt(&v.limitRequests, headers, "Anthropic-Ratelimit-Requests-Limit")
+	v.parseInt(&v.limitTokens, headers, "Anthropic-Ratelimit-Tokens-Limit")
+	v.parseInt(&v.remainingRequests, headers, "Anthropic-Ratelimit-Requests-Remaining")
+	v.parseInt(&v.remainingTokens, headers, "Anthropic-Ratelimit-Tokens-Remaining")
+	v.parseTime(&v.resetRequests, headers, "Anthropic-Ratelimit-Requests-Reset", now)
+	v.parseTime(&v.resetTokens, headers, "Anthropic-Ratelimit-Tokens-Reset", now)
+	if v.err != nil {
+		return v.err
 	}
+	rl.set(v)
+	return nil
+}
+
+func (rl *RateLimit) set(v rateLimitValues) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.LimitRequests = v.limitRequests
+	rl.LimitTokens = v.limitTokens
+	rl.RemainingRequests = v.remainingRequests
+	rl.RemainingTokens = v.remainingTokens
+	rl.ResetRequests = v.resetRequests
+	rl.ResetTokens = v.resetTokens
+	rl.UpdatedAt = time.Now()
+}
+
+// rateLimitValues keeps the first parsing error, a missing header is zero
+type rateLimitValues struct {
+	limitRequests     int
+	limitTokens       int
+	remainingRequests int
+	remainingTokens   int
+	resetRequests     time.Duration
+	resetTokens       time.Duration
+	err               error
+}
 
-	remainingTokens, err := strconv.Atoi(headers.Get("X-Ratelimit-Remaining-Tokens"))
+func (v *rateLimitValues) parseInt(dst *int, headers http.Header, key string) {
+	s := headers.Get(key)
+	if s == "" || v.err != nil {
+		return
+	}
+	n, err := strconv.Atoi(s)
 	if err != nil {
-		return err
+		v.err = fmt.Errorf("invalid %s: %w", key, err)
+		return
 	}
+	*dst = n
+}
 
-	resetRequestsSec, err := strconv.ParseFloat(headers.Get("X-Ratelimit-Reset-Requests"), 64)
+func (v *rateLimitValues) parseDuration(dst *time.Duration, headers http.Header, key string) {
+	s := headers.Get(key)
+	if s == "" || v.err != nil {
+		return
+	}
+	if sec, err := strconv.ParseFloat(s, 64); err == nil {
+		*dst = time.Duration(sec * float64(time.Second))
+		return
+	}
+	d, err := time.ParseDuration(s)
 	if err != nil {
-		return err
+		v.err = fmt.Errorf("invalid %s: %w", key, err)
+		return
 	}
+	*dst = d
+}
 
-	resetTokensSec, err := strconv.ParseFloat(headers.Get("X-Ratelimit-Reset-Tokens"), 64)
+func (v *rateLimitValues) parseTime(dst *time.Duration, headers http.Header, key string, now time.Time) {
+	s := headers.Get(key)
+	if s == "" || v.err != nil {
+		return
+	}
+	t, err := time.Parse(time.RFC3339, s)
 	if err != nil {
-		return err
+		v.err = fmt.Errorf("invalid %s: %w", key, err)
+		return
 	}
-
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	rl.LimitRequests = limitRequests
-	rl.LimitTokens = limitTokens
-	rl.RemainingRequests = remainingRequests
-	rl.RemainingTokens = remainingTokens
-	rl.ResetRequests = time.Duration(resetRequestsSec * float64(time.Second))
-	rl.ResetTokens = time.Duration(resetTokensSec * float64(time.Second))
-
-	return nil
+	*dst = max(t.Sub(now), 0)
 }
 
 // GetSnapshot returns a copy of the current RateLimit status.
@@ -74,5 +151,20 @@ func (rl *RateLimit) GetSnapshot() RateLimit {
 		RemainingTokens:   rl.RemainingTokens,
 		ResetRequests:     rl.ResetRequests,
 		ResetTokens:       rl.ResetTokens,
+		UpdatedAt:         rl.UpdatedAt,
+	}
+}
+
+// Exhausted reports if no request or token is left at now, the limits are
+// back once the reset passed. Without UpdatedAt the reset is ignored.
+func (rl *RateLimit) Exhausted(now time.Time) bool {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	pending := func(reset time.Duration) bool {
+		return rl.UpdatedAt.IsZero() || now.Before(rl.UpdatedAt.Add(reset))
+	}
+	if rl.LimitRequests > 0 && rl.RemainingRequests == 0 && pending(rl.ResetRequests) {
+		return true
 	}
+	return rl.LimitTokens > 0 && rl.RemainingTokens == 0 && pending(rl.ResetTokens)
 }
@@ -41,3 +41,84 @@ func TestRateLimitUpdate(t *testing.T) {
 		t.Errorf("Expected ResetTokens 7ms, got %v", rl.ResetTokens)
 	}
 }
+
+func TestRateLimitUpdate_GoDuration(t *testing.T) {
+	rl := &RateLimit{}
+
+	headers := http.Header{}
+	headers.Set("X-Ratelimit-Limit-Tokens", "200000")
+	headers.Set("X-Ratelimit-Remaining-Tokens", "0")
+	headers.Set("X-Ratelimit-Reset-Requests", "1m30s")
+	headers.Set("X-Ratelimit-Reset-Tokens", "20ms")
+
+	if err := rl.Update(headers); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if rl.ResetRequests != 90*time.Second {
+		t.Errorf("Expected ResetRequests 1m30s, got %v", rl.ResetRequests)
+	}
+	if rl.ResetTokens != 20*time.Millisecond {
+		t.Errorf("Expected ResetTokens 20ms, got %v", rl.ResetTokens)
+	}
+	if rl.UpdatedAt.IsZero() {
+		t.Error("Expected UpdatedAt to be set")
+	}
+	if !rl.Exhausted(rl.UpdatedAt) {
+		t.Error("Expected no token left before the reset")
+	}
+	if rl.Exhausted(rl.UpdatedAt.Add(time.Second)) {
+		t.Error("Expected tokens back after the reset")
+	}
+
+	headers.Set("X-Ratelimit-Reset-Tokens", "soon")
+	if err := rl.Update(headers); err == nil {
+		t.Error("Expected an error for an invalid reset")
+	}
+}
+
+func TestRateLimitUpdate_NoHeaders(t *testing.T) {
+	rl := &RateLimit{LimitRequests: 10, RemainingRequests: 5}
+	if err := rl.Update(http.Header{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := rl.UpdateAnthropic(http.Header{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if rl.RemainingRequests != 5 || !rl.UpdatedAt.IsZero() {
+		t.Errorf("Expected the rate limit unchanged, got %+v", rl.GetSnapshot())
+	}
+}
+
+func TestRateLimitUpdateAnthropic(t *testing.T) {
+	rl := &RateLimit{}
+
+	now := time.Now()
+	headers := http.Header{}
+	headers.Set("anthropic-ratelimit-requests-limit", "50")
+	headers.Set("anthropic-ratelimit-requests-remaining", "49")
+	headers.Set("anthropic-ratelimit-requests-reset", now.Add(time.Minute).UTC().Format(time.RFC3339))
+	headers.Set("anthropic-ratelimit-tokens-limit", "40000")
+	headers.Set("anthropic-ratelimit-tokens-remaining", "39000")
+	headers.Set("anthropic-ratelimit-tokens-reset", now.Add(-time.Minute).UTC().Format(time.RFC3339))
+
+	if err := rl.UpdateAnthropic(headers); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if rl.LimitRequests != 50 || rl.RemainingRequests != 49 {
+		t.Errorf("Expected 49 of 50 requests, got %d of %d", rl.RemainingRequests, rl.LimitRequests)
+	}
+	if rl.LimitTokens != 40000 || rl.RemainingTokens != 39000 {
+		t.Errorf("Expected 39000 of 40000 tokens, got %d of %d", rl.RemainingTokens, rl.LimitTokens)
+	}
+	if rl.ResetRequests <= 58*time.Second || rl.ResetRequests > time.Minute {
+		t.Errorf("Expected ResetRequests about 1m, got %v", rl.ResetRequests)
+	}
+	if rl.ResetTokens != 0 {
+		t.Errorf("Expected a passed reset to be 0, got %v", rl.ResetTokens)
+	}
+
+	headers.Set("anthropic-ratelimit-requests-reset", "60")
+	if err := rl.UpdateAnthropic(headers); err == nil {
+		t.Error("Expected an error for a reset which is not RFC 3339")
+	}
+}
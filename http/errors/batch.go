@@ -0,0 +1,41 @@
+package errors
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BatchError holds the errors of the failed items of a batch, keyed by the
+// item id. The results of the other items are still returned with it.
+type BatchError struct {
+	Items map[string]error
+}
+
+func (e *BatchError) Error() string {
+	ids := make([]string, 0, len(e.Items))
+	for id := range e.Items {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d batch items failed", len(ids))
+	for i, id := range ids {
+		if i == 3 {
+			fmt.Fprintf(&b, "; ... %d more", len(ids)-i)
+			break
+		}
+		fmt.Fprintf(&b, "; %s: %s", id, e.Items[id])
+	}
+	return b.String()
+}
+
+// Unwrap allows errors.Is and errors.As on the item errors
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Items))
+	for _, err := range e.Items {
+		errs = append(errs, err)
+	}
+	return errs
+}
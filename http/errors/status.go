@@ -0,0 +1,37 @@
+package errors
+
+import (
+	"errors"
+	"net/http"
+)
+
+// StatusOverloaded is returned by Anthropic when the API is overloaded
+const StatusOverloaded = 529
+
+func (r *APIErrorBase) GetStatusCode() int {
+	return r.StatusCode
+}
+
+// StatusCode returns the HTTP status of the APIError in the chain of err, 0
+// when there is none
+func StatusCode(err error) int {
+	var apiErr interface{ GetStatusCode() int }
+	if errors.As(err, &apiErr) {
+		return apiErr.GetStatusCode()
+	}
+	return 0
+}
+
+// IsRetryable reports if err is an APIError which may succeed later or on
+// another provider: timeout, conflict, rate limit, server errors and
+// overloaded
+func IsRetryable(err error) bool {
+	switch code := StatusCode(err); {
+	case code == http.StatusRequestTimeout, code == http.StatusConflict,
+		code == http.StatusTooManyRequests:
+		return true
+	case code >= http.StatusInternalServerError:
+		return true
+	}
+	return false
+}
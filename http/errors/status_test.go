@@ -0,0 +1,25 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetryable(t *testing.T) {
+	newErr := func(code int) error {
+		return fmt.Errorf("wrapped: %w", &APIErrorBase{StatusCode: code})
+	}
+	assert.Equal(t, 429, StatusCode(newErr(429)))
+	assert.Equal(t, 0, StatusCode(errors.New("boom")))
+
+	assert.True(t, IsRetryable(newErr(StatusOverloaded)))
+	assert.True(t, IsRetryable(newErr(http.StatusTooManyRequests)))
+	assert.True(t, IsRetryable(newErr(http.StatusBadGateway)))
+	assert.False(t, IsRetryable(newErr(http.StatusBadRequest)))
+	assert.False(t, IsRetryable(newErr(http.StatusUnauthorized)))
+	assert.False(t, IsRetryable(errors.New("boom")))
+}
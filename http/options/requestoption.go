@@ -239,3 +239,27 @@ func WithApiKey(headerName string, value string) RequestOption {
 		return r.Apply(WithHeader(r.APIKeyHeaderName, r.APIKey))
 	}
 }
+
+// WithRateLimit returns a RequestOption that updates rl from the headers of
+// every response with parse, e.g. config.ParseOpenAIRateLimit. The headers
+// which can't be parsed are ignored. onUpdate, when set, is called with a
+// snapshot after each response.
+func WithRateLimit(
+	rl *config.RateLimit,
+	parse config.RateLimitParser,
+	onUpdate ...func(*config.RateLimit),
+) RequestOption {
+	return WithMiddleware(func(req *http.Request, next MiddlewareNext) (*http.Response, error) {
+		resp, err := next(req)
+		if resp == nil {
+			return resp, err
+		}
+		if parse(rl, resp.Header) == nil {
+			for _, f := range onUpdate {
+				snapshot := rl.GetSnapshot()
+				f(&snapshot)
+			}
+		}
+		return resp, err
+	})
+}
@@ -0,0 +1,58 @@
+package streaming
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+func init() {
+	RegisterDecoder("application/x-ndjson", NewDecoderNDJSON)
+}
+
+// Lines can be large with tool calls or base64 content
+const maxLineSize = 4 * 1024 * 1024
+
+// NewDecoderNDJSON decodes newline delimited JSON, every non empty line is
+// returned as the Data of an Event without Type
+func NewDecoderNDJSON(rc io.ReadCloser) Decoder[Event] {
+	scanner := bufio.NewScanner(rc)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return &ndjsonDecoder{rc: rc, scn: scanner}
+}
+
+type ndjsonDecoder struct {
+	evt Event
+	rc  io.ReadCloser
+	scn *bufio.Scanner
+	err error
+}
+
+func (s *ndjsonDecoder) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	for s.scn.Scan() {
+		line := bytes.TrimSpace(s.scn.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		// The scanner reuse its buffer
+		s.evt = Event{Data: append([]byte(nil), line...)}
+		return true
+	}
+	s.err = s.scn.Err()
+	return false
+}
+
+func (s *ndjsonDecoder) Current() Event {
+	return s.evt
+}
+
+func (s *ndjsonDecoder) Close() error {
+	return s.rc.Close()
+}
+
+func (s *ndjsonDecoder) Err() error {
+	return s.err
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"io"
+	"mime"
 	"net/http"
 	"strings"
 )
@@ -14,7 +15,11 @@ func NewDecoderSSE(res *http.Response) Decoder[Event] {
 	}
 
 	var decoder Decoder[Event]
-	contentType := res.Header.Get("content-type")
+	// Ignore parameters like charset
+	contentType := strings.ToLower(res.Header.Get("content-type"))
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	}
 	if t, ok := decoderTypes[contentType]; ok {
 		decoder = t(res.Body)
 	} else {
@@ -0,0 +1,119 @@
+package modelinfo
+
+import (
+	"context"
+
+	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/http/config"
+	"github.com/y0ug/llmhaven/http/streaming"
+)
+
+// Cost computes the cost of the usage from the per token prices of the
+// model. Cache read and write use the input price when the model has no
+// cache price, the reasoning tokens are billed as output. InputTokens
+// includes the cache tokens except on Anthropic.
+func Cost(usage *chat.ChatUsage, info Getter) chat.Cost {
+	var c chat.Cost
+	if usage == nil || info == nil {
+		return c
+	}
+	input := info.GetInputCostPerToken()
+	output := info.GetOutputCostPerToken()
+	cacheRead, cacheWrite := input, input
+	if v := info.GetCacheReadInputTokenCost(); v != nil {
+		cacheRead = *v
+	}
+	if v := info.GetCacheCreationInputTokenCost(); v != nil {
+		cacheWrite = *v
+	}
+
+	uncached := usage.InputTokens
+	// Anthropic input_tokens doesn't count the cache, the other APIs do
+	if info.GetLiteLLMProvider() != "anthropic" {
+		uncached = max(uncached-usage.InputCachedTokens-usage.InputCacheCreationTokens, 0)
+	}
+	c.Input = float64(uncached) * input
+	c.CachedInput = float64(usage.InputCachedTokens) * cacheRead
+	c.CacheWrite = float64(usage.InputCacheCreationTokens) * cacheWrite
+	c.Output = float64(max(usage.OutputTokens-usage.OutputReasoningTokens, 0)) * output
+	c.Reasoning = float64(usage.OutputReasoningTokens) * output
+	c.Total = c.Input + c.CachedInput + c.CacheWrite + c.Output + c.Reasoning
+	return c
+}
+
+// NewCostProvider returns a provider setting ChatResponse.Cost on every
+// response and on the message of the message_stop event. The pricing is
+// looked up with the requested model then the model of the response. A
+// cost returned by the API is kept.
+func NewCostProvider(provider chat.Provider, prices Provider) chat.Provider {
+	return &costProvider{Provider: provider, prices: prices}
+}
+
+type costProvider struct {
+	chat.Provider
+	prices Provider
+}
+
+func (p *costProvider) setCost(model string, resp *chat.ChatResponse) {
+	if resp == nil || resp.Cost != nil || resp.Usage == nil {
+		return
+	}
+	info, ok := p.prices.Get(model)
+	if !ok {
+		info, ok = p.prices.Get(resp.Model)
+	}
+	if !ok {
+		return
+	}
+	cost := Cost(resp.Usage, info)
+	resp.Cost = &cost
+}
+
+func (p *costProvider) Send(ctx context.Context, params chat.ChatParams) (*chat.ChatResponse, error) {
+	resp, err := p.Provider.Send(ctx, params)
+	p.setCost(params.Model, resp)
+	return resp, err
+}
+
+func (p *costProvider) Stream(
+	ctx context.Context,
+	params chat.ChatParams,
+) (streaming.Streamer[chat.EventStream], error) {
+	stream, err := p.Provider.Stream(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return &costStream{Streamer: stream, provider: p, model: params.Model}, nil
+}
+
+// CountTokens is forwarded when the provider is a chat.TokenCounter
+func (p *costProvider) CountTokens(ctx context.Context, params chat.ChatParams) (int64, error) {
+	counter, ok := p.Provider.(chat.TokenCounter)
+	if !ok {
+		return 0, chat.ErrCountTokensUnsupported
+	}
+	return counter.CountTokens(ctx, params)
+}
+
+// RateLimits is forwarded when the provider keeps its rate limits, the
+// Router uses them
+func (p *costProvider) RateLimits() config.RateLimit {
+	if reporter, ok := p.Provider.(interface{ RateLimits() config.RateLimit }); ok {
+		return reporter.RateLimits()
+	}
+	return config.RateLimit{}
+}
+
+type costStream struct {
+	streaming.Streamer[chat.EventStream]
+	provider *costProvider
+	model    string
+}
+
+func (s *costStream) Current() chat.EventStream {
+	evt := s.Streamer.Current()
+	if evt.Type == "message_stop" {
+		s.provider.setCost(s.model, evt.Message)
+	}
+	return evt
+}
@@ -0,0 +1,112 @@
+package modelinfo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y0ug/llmhaven/chat"
+	"go.uber.org/mock/gomock"
+)
+
+func testPrices() LitellmModelInfoMap {
+	cacheRead, cacheWrite := 0.1e-6, 1.25e-6
+	return LitellmModelInfoMap{
+		"model-a": {
+			InputCostPerToken:           1e-6,
+			OutputCostPerToken:          4e-6,
+			CacheReadInputTokenCost:     &cacheRead,
+			CacheCreationInputTokenCost: &cacheWrite,
+		},
+		"model-b": {InputCostPerToken: 2e-6, OutputCostPerToken: 2e-6},
+	}
+}
+
+func TestCost(t *testing.T) {
+	prices := testPrices()
+	info, _ := prices.Get("model-a")
+	c := Cost(&chat.ChatUsage{
+		InputTokens:              1000,
+		InputCachedTokens:        200,
+		InputCacheCreationTokens: 100,
+		OutputTokens:             500,
+		OutputReasoningTokens:    100,
+	}, info)
+	assert.InDelta(t, 700e-6, c.Input, 1e-12)
+	assert.InDelta(t, 20e-6, c.CachedInput, 1e-12)
+	assert.InDelta(t, 125e-6, c.CacheWrite, 1e-12)
+	assert.InDelta(t, 1600e-6, c.Output, 1e-12)
+	assert.InDelta(t, 400e-6, c.Reasoning, 1e-12)
+	assert.InDelta(t, 2845e-6, c.Total, 1e-12)
+
+	// Without cache prices the input price is used
+	info, _ = prices.Get("model-b")
+	c = Cost(&chat.ChatUsage{InputTokens: 10, InputCachedTokens: 5, OutputTokens: 1}, info)
+	assert.InDelta(t, 22e-6, c.Total, 1e-12)
+
+	assert.Equal(t, chat.Cost{}, Cost(nil, info))
+
+	// Anthropic input tokens don't include the cache
+	info = &LitellmModelInfo{InputCostPerToken: 1e-6, LiteLLMProvider: "anthropic"}
+	c = Cost(&chat.ChatUsage{InputTokens: 10, InputCachedTokens: 100, InputCacheCreationTokens: 20}, info)
+	assert.InDelta(t, 10e-6, c.Input, 1e-12)
+	assert.InDelta(t, 130e-6, c.Total, 1e-12)
+}
+
+type sliceStream struct {
+	events []chat.EventStream
+	i      int
+}
+
+func (s *sliceStream) Next() bool                { s.i++; return s.i <= len(s.events) }
+func (s *sliceStream) Current() chat.EventStream { return s.events[s.i-1] }
+func (s *sliceStream) Err() error                { return nil }
+func (s *sliceStream) Close() error              { return nil }
+
+func TestCostProvider(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mock := chat.NewMockProvider(ctrl)
+	prices := testPrices()
+	provider := NewCostProvider(mock, &prices)
+
+	usage := &chat.ChatUsage{InputTokens: 10, OutputTokens: 1}
+	gomock.InOrder(
+		mock.EXPECT().Send(gomock.Any(), gomock.Any()).Return(
+			&chat.ChatResponse{Model: "model-b-2025", Usage: usage}, nil),
+		// The cost returned by the API is kept
+		mock.EXPECT().Send(gomock.Any(), gomock.Any()).Return(
+			&chat.ChatResponse{Usage: usage, Cost: &chat.Cost{Total: 1}}, nil),
+		// Unknown model
+		mock.EXPECT().Send(gomock.Any(), gomock.Any()).Return(
+			&chat.ChatResponse{Model: "other", Usage: usage}, nil),
+	)
+
+	resp, err := provider.Send(context.Background(), chat.ChatParams{Model: "model-b"})
+	assert.NoError(t, err)
+	if assert.NotNil(t, resp.Cost) {
+		assert.InDelta(t, 22e-6, resp.Cost.Total, 1e-12)
+	}
+	resp, _ = provider.Send(context.Background(), chat.ChatParams{Model: "model-b"})
+	assert.Equal(t, 1.0, resp.Cost.Total)
+	resp, _ = provider.Send(context.Background(), chat.ChatParams{Model: "unknown"})
+	assert.Nil(t, resp.Cost)
+
+	mock.EXPECT().Stream(gomock.Any(), gomock.Any()).Return(&sliceStream{events: []chat.EventStream{
+		{Type: "text_delta", Delta: "hi", Message: &chat.ChatResponse{}},
+		{Type: "message_stop", Message: &chat.ChatResponse{Model: "model-a", Usage: usage}},
+	}}, nil)
+	stream, err := provider.Stream(context.Background(), chat.ChatParams{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	var last chat.EventStream
+	for stream.Next() {
+		last = stream.Current()
+		if last.Type == "text_delta" {
+			assert.Nil(t, last.Message.Cost)
+		}
+	}
+	if assert.NotNil(t, last.Message.Cost) {
+		assert.InDelta(t, 14e-6, last.Message.Cost.Total, 1e-12)
+	}
+}
@@ -66,7 +66,7 @@ func Get(modelStr string, infoProviders Provider) (*Model, error) {
 		// For models without explicit provider prefix, try to get info if providers available
 		if infoProviders != nil {
 			info, ok := infoProviders.Get(modelStr)
-			if !ok {
+			if ok {
 				if info.GetLiteLLMProvider() != "" {
 					provider = info.GetLiteLLMProvider()
 				}
@@ -5,32 +5,15 @@ import (
 
 	"github.com/y0ug/llmhaven/chat"
 	"github.com/y0ug/llmhaven/http/options"
-	"github.com/y0ug/llmhaven/providers/anthropic"
-	"github.com/y0ug/llmhaven/providers/deepseek"
-	"github.com/y0ug/llmhaven/providers/gemini"
-	"github.com/y0ug/llmhaven/providers/openai"
-	"github.com/y0ug/llmhaven/providers/openrouter"
 )
 
-// New provider factory
+// New provider factory, providerName is a name or an alias given to
+// Register or RegisterAlias
 func New(providerName string, requestOpts ...options.RequestOption,
 ) (chat.Provider, error) {
-	var provider chat.Provider
-	switch providerName {
-	case "anthropic":
-		provider = anthropic.New(requestOpts...)
-	case "openrouter":
-		provider = openrouter.New(requestOpts...)
-	case "openai":
-		provider = openai.New(requestOpts...)
-	case "gemini":
-		provider = gemini.New(requestOpts...)
-	case "deepseek":
-		provider = deepseek.New(requestOpts...)
-	}
-
-	if provider == nil {
+	factory, ok := lookup(providerName)
+	if !ok {
 		return nil, fmt.Errorf("provider %s not found", providerName)
 	}
-	return provider, nil
+	return factory(requestOpts...), nil
 }
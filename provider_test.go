@@ -12,7 +12,6 @@ import (
 	"testing"
 	"time"
 
-	"github.com/invopop/jsonschema"
 	"github.com/stretchr/testify/assert"
 	"github.com/y0ug/llmhaven/chat"
 	"github.com/y0ug/llmhaven/http/options"
@@ -26,16 +25,7 @@ type GetWeatherInput struct {
 	Location string `json:"location" jsonschema_description:"The location to look up."`
 }
 
-var GetWeatherInputSchema = GenerateSchema[GetWeatherInput]()
-
-func GenerateSchema[T any]() interface{} {
-	reflector := jsonschema.Reflector{
-		AllowAdditionalProperties: false,
-		DoNotReference:            true,
-	}
-	var v T
-	return reflector.Reflect(v)
-}
+var GetWeatherInputSchema = chat.GenerateSchema[GetWeatherInput]()
 
 func GetWeather(location string) string {
 	conditions := []string{
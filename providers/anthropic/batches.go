@@ -0,0 +1,237 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/http/client"
+	"github.com/y0ug/llmhaven/http/errors"
+	"github.com/y0ug/llmhaven/http/options"
+	"github.com/y0ug/llmhaven/http/streaming"
+)
+
+// BatchService wraps the v1/messages/batches endpoints, batches are
+// processed asynchronously within 24 hours
+type BatchService struct {
+	*client.BaseClient
+}
+
+func NewBatchService(opts ...options.RequestOption) *BatchService {
+	return &BatchService{
+		BaseClient: &client.BaseClient{
+			Options:  opts,
+			NewError: NewError,
+		},
+	}
+}
+
+// Batch processing status
+const (
+	BatchStatusInProgress = "in_progress"
+	BatchStatusCanceling  = "canceling"
+	BatchStatusEnded      = "ended"
+)
+
+type MessageBatch struct {
+	ID                string             `json:"id"`
+	Type              string             `json:"type"` // always message_batch
+	ProcessingStatus  string             `json:"processing_status"`
+	RequestCounts     BatchRequestCounts `json:"request_counts"`
+	CreatedAt         time.Time          `json:"created_at"`
+	ExpiresAt         time.Time          `json:"expires_at"`
+	EndedAt           *time.Time         `json:"ended_at,omitempty"`
+	CancelInitiatedAt *time.Time         `json:"cancel_initiated_at,omitempty"`
+	ArchivedAt        *time.Time         `json:"archived_at,omitempty"`
+	ResultsURL        string             `json:"results_url,omitempty"` // set once ended
+}
+
+type BatchRequestCounts struct {
+	Processing int `json:"processing"`
+	Succeeded  int `json:"succeeded"`
+	Errored    int `json:"errored"`
+	Canceled   int `json:"canceled"`
+	Expired    int `json:"expired"`
+}
+
+// BatchRequest custom_id must be unique in the batch, results are not in
+// the request order
+type BatchRequest struct {
+	CustomID string           `json:"custom_id"`
+	Params   MessageNewParams `json:"params"`
+}
+
+type BatchNewParams struct {
+	Requests []BatchRequest `json:"requests"`
+}
+
+type BatchListParams struct {
+	Limit    int
+	BeforeID string
+	AfterID  string
+}
+
+type BatchList struct {
+	Data    []MessageBatch `json:"data"`
+	FirstID string         `json:"first_id,omitempty"`
+	LastID  string         `json:"last_id,omitempty"`
+	HasMore bool           `json:"has_more"`
+}
+
+// BatchResult is a line of the results, Result.Type is succeeded, errored,
+// canceled or expired
+type BatchResult struct {
+	CustomID string `json:"custom_id"`
+	Result   struct {
+		Type    string          `json:"type"`
+		Message *Message        `json:"message,omitempty"`
+		Error   json.RawMessage `json:"error,omitempty"`
+	} `json:"result"`
+}
+
+// ToChatResponse returns the message of a succeeded request, an error
+// otherwise
+func (r *BatchResult) ToChatResponse() (*chat.ChatResponse, error) {
+	switch r.Result.Type {
+	case "succeeded":
+		if r.Result.Message == nil {
+			return nil, fmt.Errorf("batch result %s without message", r.CustomID)
+		}
+		return AnthropicMessageToChatMessage(r.Result.Message), nil
+	case "errored":
+		return nil, fmt.Errorf("batch request errored: %s", r.Result.Error)
+	}
+	return nil, fmt.Errorf("batch request %s", r.Result.Type)
+}
+
+func (svc *BatchService) New(
+	ctx context.Context,
+	params BatchNewParams,
+	opts ...options.RequestOption,
+) (res *MessageBatch, err error) {
+	for _, r := range params.Requests {
+		if usesFiles(r.Params) {
+			opts = append(opts, WithBetaFiles())
+			break
+		}
+	}
+	err = svc.Post(ctx, "v1/messages/batches", params, &res, opts...)
+	return
+}
+
+// NewFromChat creates a batch from chat params, customIDs[i] is the id of
+// params[i]
+func (svc *BatchService) NewFromChat(
+	ctx context.Context,
+	customIDs []string,
+	params []chat.ChatParams,
+	opts ...options.RequestOption,
+) (*MessageBatch, error) {
+	if len(customIDs) != len(params) {
+		return nil, fmt.Errorf("got %d custom ids for %d params", len(customIDs), len(params))
+	}
+	batch := BatchNewParams{Requests: make([]BatchRequest, len(params))}
+	for i, p := range params {
+		paramsProvider, err := BaseChatMessageNewParamsToAnthropic(p)
+		if err != nil {
+			return nil, fmt.Errorf("request %s: %w", customIDs[i], err)
+		}
+		batch.Requests[i] = BatchRequest{CustomID: customIDs[i], Params: paramsProvider}
+	}
+	return svc.New(ctx, batch, opts...)
+}
+
+func (svc *BatchService) Retrieve(
+	ctx context.Context,
+	id string,
+	opts ...options.RequestOption,
+) (res *MessageBatch, err error) {
+	err = svc.Get(ctx, "v1/messages/batches/"+url.PathEscape(id), nil, &res, opts...)
+	return
+}
+
+func (svc *BatchService) List(
+	ctx context.Context,
+	params BatchListParams,
+	opts ...options.RequestOption,
+) (res *BatchList, err error) {
+	if params.Limit > 0 {
+		opts = append(opts, options.WithQuery("limit", strconv.Itoa(params.Limit)))
+	}
+	if params.BeforeID != "" {
+		opts = append(opts, options.WithQuery("before_id", params.BeforeID))
+	}
+	if params.AfterID != "" {
+		opts = append(opts, options.WithQuery("after_id", params.AfterID))
+	}
+	err = svc.Get(ctx, "v1/messages/batches", nil, &res, opts...)
+	return
+}
+
+// Cancel starts the cancellation, the batch is canceling until the requests
+// in flight are done
+func (svc *BatchService) Cancel(
+	ctx context.Context,
+	id string,
+	opts ...options.RequestOption,
+) (res *MessageBatch, err error) {
+	path := "v1/messages/batches/" + url.PathEscape(id) + "/cancel"
+	err = svc.Post(ctx, path, nil, &res, opts...)
+	return
+}
+
+// Results streams the JSONL results of an ended batch
+func (svc *BatchService) Results(
+	ctx context.Context,
+	id string,
+	opts ...options.RequestOption,
+) (streaming.Streamer[BatchResult], error) {
+	var raw *http.Response
+	path := "v1/messages/batches/" + url.PathEscape(id) + "/results"
+	err := svc.Get(ctx, path, nil, &raw, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting batch results: %w", err)
+	}
+	return streaming.NewStream(
+		streaming.NewDecoderNDJSON(raw.Body),
+		streaming.NewGenericStreamHandler[BatchResult](),
+	), nil
+}
+
+// ChatResults reads all the results keyed by custom id. When requests failed
+// the responses of the others are returned with an *errors.BatchError.
+func (svc *BatchService) ChatResults(
+	ctx context.Context,
+	id string,
+	opts ...options.RequestOption,
+) (map[string]*chat.ChatResponse, error) {
+	stream, err := svc.Results(ctx, id, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	results := make(map[string]*chat.ChatResponse)
+	failed := make(map[string]error)
+	for stream.Next() {
+		r := stream.Current()
+		resp, err := r.ToChatResponse()
+		if err != nil {
+			failed[r.CustomID] = err
+			continue
+		}
+		results[r.CustomID] = resp
+	}
+	if err := stream.Err(); err != nil {
+		return results, err
+	}
+	if len(failed) > 0 {
+		return results, &errors.BatchError{Items: failed}
+	}
+	return results, nil
+}
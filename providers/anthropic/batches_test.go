@@ -0,0 +1,102 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y0ug/llmhaven/chat"
+	llmerrors "github.com/y0ug/llmhaven/http/errors"
+)
+
+func TestBatchService_NewFromChat(t *testing.T) {
+	var body BatchNewParams
+	c := newTestFileClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/messages/batches", r.URL.Path)
+		assert.Empty(t, r.Header.Get("anthropic-beta"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msgbatch_1","type":"message_batch","processing_status":"in_progress",
+			"request_counts":{"processing":2},"created_at":"2025-01-01T00:00:00Z"}`))
+	})
+
+	params := []chat.ChatParams{
+		*chat.NewChatParams(chat.WithModel("claude-3-5-haiku-latest"), chat.WithMaxTokens(100),
+			chat.WithMessages(chat.NewUserMessage("a"))),
+		*chat.NewChatParams(chat.WithModel("claude-3-5-haiku-latest"), chat.WithMaxTokens(100),
+			chat.WithMessages(chat.NewUserMessage("b"))),
+	}
+	batch, err := c.Batches.NewFromChat(context.Background(), []string{"a", "b"}, params)
+	assert.NoError(t, err)
+	assert.Equal(t, "msgbatch_1", batch.ID)
+	assert.Equal(t, BatchStatusInProgress, batch.ProcessingStatus)
+	assert.Equal(t, 2, batch.RequestCounts.Processing)
+
+	if assert.Len(t, body.Requests, 2) {
+		assert.Equal(t, "b", body.Requests[1].CustomID)
+		assert.Equal(t, "claude-3-5-haiku-latest", body.Requests[1].Params.Model)
+	}
+
+	_, err = c.Batches.NewFromChat(context.Background(), []string{"a"}, params)
+	assert.Error(t, err)
+}
+
+func TestBatchService_RetrieveListCancel(t *testing.T) {
+	c := newTestFileClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/messages/batches":
+			assert.Equal(t, "10", r.URL.Query().Get("limit"))
+			w.Write([]byte(`{"data":[{"id":"msgbatch_1"}],"has_more":true,"last_id":"msgbatch_1"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/messages/batches/msgbatch_1":
+			w.Write([]byte(`{"id":"msgbatch_1","processing_status":"ended","ended_at":"2025-01-01T01:00:00Z"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/messages/batches/msgbatch_1/cancel":
+			w.Write([]byte(`{"id":"msgbatch_1","processing_status":"canceling"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+	ctx := context.Background()
+
+	list, err := c.Batches.List(ctx, BatchListParams{Limit: 10})
+	assert.NoError(t, err)
+	assert.True(t, list.HasMore)
+	assert.Len(t, list.Data, 1)
+
+	batch, err := c.Batches.Retrieve(ctx, "msgbatch_1")
+	assert.NoError(t, err)
+	assert.Equal(t, BatchStatusEnded, batch.ProcessingStatus)
+	assert.NotNil(t, batch.EndedAt)
+
+	batch, err = c.Batches.Cancel(ctx, "msgbatch_1")
+	assert.NoError(t, err)
+	assert.Equal(t, BatchStatusCanceling, batch.ProcessingStatus)
+}
+
+func TestBatchService_ChatResults(t *testing.T) {
+	c := newTestFileClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages/batches/msgbatch_1/results", r.URL.Path)
+		w.Header().Set("Content-Type", "application/binary")
+		w.Write([]byte(`{"custom_id":"b","result":{"type":"succeeded","message":{"id":"msg_b","role":"assistant","content":[{"type":"text","text":"B"}],"stop_reason":"end_turn","usage":{"input_tokens":3,"output_tokens":1}}}}
+{"custom_id":"a","result":{"type":"errored","error":{"type":"invalid_request_error","message":"bad"}}}
+
+{"custom_id":"c","result":{"type":"expired"}}
+`))
+	})
+
+	results, err := c.Batches.ChatResults(context.Background(), "msgbatch_1")
+	var batchErr *llmerrors.BatchError
+	if assert.True(t, errors.As(err, &batchErr)) {
+		assert.Len(t, batchErr.Items, 2)
+		assert.Contains(t, batchErr.Items["a"].Error(), "invalid_request_error")
+		assert.Contains(t, batchErr.Items["c"].Error(), "expired")
+	}
+	if assert.Contains(t, results, "b") {
+		assert.Equal(t, "B", results["b"].Choice[0].Content[0].Text)
+		assert.Equal(t, 3, results["b"].Usage.InputTokens)
+	}
+}
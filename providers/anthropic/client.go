@@ -4,17 +4,25 @@ import (
 	"os"
 
 	"github.com/y0ug/llmhaven/http/client"
+	"github.com/y0ug/llmhaven/http/config"
 	"github.com/y0ug/llmhaven/http/options"
 )
 
 type Client struct {
 	*client.BaseClient
 	Message *MessageService
+	Files   *FileService
+	Batches *BatchService
+
+	// RateLimit is updated from the headers of every response
+	RateLimit *config.RateLimit
 }
 
 func NewClient(opts ...options.RequestOption) (r *Client) {
+	rl := &config.RateLimit{}
 	defaults := []options.RequestOption{
 		WithEnvironmentProduction(), WithApiVersionAnthropic(),
+		options.WithRateLimit(rl, config.ParseAnthropicRateLimit),
 	}
 	if o, ok := os.LookupEnv("ANTHROPIC_API_KEY"); ok {
 		defaults = append(defaults, options.WithApiKey("x-api-key", o))
@@ -24,9 +32,12 @@ func NewClient(opts ...options.RequestOption) (r *Client) {
 			Options:  append(defaults, opts...),
 			NewError: NewError,
 		},
+		RateLimit: rl,
 	}
 
 	r.Message = NewMessageService(r.BaseClient.Options...)
+	r.Files = NewFileService(r.BaseClient.Options...)
+	r.Batches = NewBatchService(r.BaseClient.Options...)
 
 	return
 }
@@ -2,6 +2,7 @@ package anthropic
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/y0ug/llmhaven/http/errors"
@@ -27,3 +28,36 @@ func (r *APIError) UnmarshalJSON(data []byte) (err error) {
 	r.ExtraFields = make(map[string]interface{})
 	return json.Unmarshal(data, &r.ExtraFields)
 }
+
+// StreamError is an error event of a stream, the API answered 200 then
+// failed. Its status is the one of the error type over HTTP.
+type StreamError struct {
+	Type    string
+	Message string
+}
+
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Type, e.Message)
+}
+
+func (e *StreamError) GetStatusCode() int {
+	switch e.Type {
+	case "invalid_request_error":
+		return http.StatusBadRequest
+	case "authentication_error":
+		return http.StatusUnauthorized
+	case "permission_error":
+		return http.StatusForbidden
+	case "not_found_error":
+		return http.StatusNotFound
+	case "request_too_large":
+		return http.StatusRequestEntityTooLarge
+	case "rate_limit_error":
+		return http.StatusTooManyRequests
+	case "timeout_error":
+		return http.StatusGatewayTimeout
+	case "overloaded_error":
+		return errors.StatusOverloaded
+	}
+	return http.StatusInternalServerError
+}
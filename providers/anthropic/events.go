@@ -36,11 +36,16 @@ func (h *AnthropicEventHandler) HandleEvent(
 		if err := json.Unmarshal(event.Delta, &delta); err != nil {
 			return evt, nil
 		}
-		if delta.Type == "text_delta" {
+		switch delta.Type {
+		case "text_delta":
 			evt.Type = "text_delta"
 			evt.Delta = delta.Text
+		case "thinking_delta":
+			evt.Type = "thinking_delta"
+			evt.Delta = delta.Thinking
 		}
-	case "message_stop":
+	case "message_start", "message_delta", "message_stop":
+		// The usage so far, the input tokens then the output tokens
 		evt.Message = AnthropicMessageToChatMessage(&h.message)
 	}
 	return evt, nil
@@ -0,0 +1,155 @@
+package anthropic
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/y0ug/llmhaven/http/client"
+	"github.com/y0ug/llmhaven/http/options"
+)
+
+// FileService wraps the v1/files endpoints, the files beta header is added
+// to every request
+type FileService struct {
+	*client.BaseClient
+}
+
+func NewFileService(opts ...options.RequestOption) *FileService {
+	return &FileService{
+		BaseClient: &client.BaseClient{
+			Options:  append(opts[:len(opts):len(opts)], WithBetaFiles()),
+			NewError: NewError,
+		},
+	}
+}
+
+type File struct {
+	ID           string    `json:"id"`
+	Type         string    `json:"type"` // always file
+	Filename     string    `json:"filename"`
+	MimeType     string    `json:"mime_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	CreatedAt    time.Time `json:"created_at"`
+	Downloadable bool      `json:"downloadable,omitempty"`
+}
+
+type FileNewParams struct {
+	Filename  string
+	MediaType string // detected from the filename when empty
+	Content   io.Reader
+}
+
+type FileListParams struct {
+	Limit    int
+	BeforeID string
+	AfterID  string
+}
+
+type FileList struct {
+	Data    []File `json:"data"`
+	FirstID string `json:"first_id,omitempty"`
+	LastID  string `json:"last_id,omitempty"`
+	HasMore bool   `json:"has_more"`
+}
+
+// New uploads a file
+func (svc *FileService) New(
+	ctx context.Context,
+	params FileNewParams,
+	opts ...options.RequestOption,
+) (res *File, err error) {
+	mediaType := params.MediaType
+	if mediaType == "" {
+		mediaType = mime.TypeByExtension(filepath.Ext(params.Filename))
+	}
+	body, contentType, err := client.NewMultipartBody(nil, client.MultipartFile{
+		FieldName:   "file",
+		Filename:    params.Filename,
+		ContentType: mediaType,
+		Content:     params.Content,
+	})
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, options.WithRequestBody(contentType, body))
+	err = svc.Post(ctx, "v1/files", nil, &res, opts...)
+	return
+}
+
+// Upload uploads the file at path
+func (svc *FileService) Upload(
+	ctx context.Context,
+	path string,
+	opts ...options.RequestOption,
+) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return svc.New(ctx, FileNewParams{Filename: filepath.Base(path), Content: f}, opts...)
+}
+
+func (svc *FileService) List(
+	ctx context.Context,
+	params FileListParams,
+	opts ...options.RequestOption,
+) (res *FileList, err error) {
+	if params.Limit > 0 {
+		opts = append(opts, options.WithQuery("limit", strconv.Itoa(params.Limit)))
+	}
+	if params.BeforeID != "" {
+		opts = append(opts, options.WithQuery("before_id", params.BeforeID))
+	}
+	if params.AfterID != "" {
+		opts = append(opts, options.WithQuery("after_id", params.AfterID))
+	}
+	err = svc.Get(ctx, "v1/files", nil, &res, opts...)
+	return
+}
+
+// Metadata returns the file metadata
+func (svc *FileService) Metadata(
+	ctx context.Context,
+	id string,
+	opts ...options.RequestOption,
+) (res *File, err error) {
+	err = svc.Get(ctx, "v1/files/"+url.PathEscape(id), nil, &res, opts...)
+	return
+}
+
+// Download returns the file content, only files created by the code
+// execution tool or skills are downloadable. The caller must close it.
+func (svc *FileService) Download(
+	ctx context.Context,
+	id string,
+	opts ...options.RequestOption,
+) (io.ReadCloser, error) {
+	var raw *http.Response
+	path := "v1/files/" + url.PathEscape(id) + "/content"
+	opts = append(opts, options.WithHeader("Accept", "*/*"))
+	if err := svc.Get(ctx, path, nil, &raw, opts...); err != nil {
+		return nil, fmt.Errorf("error downloading file %s: %w", id, err)
+	}
+	return raw.Body, nil
+}
+
+func (svc *FileService) Delete(
+	ctx context.Context,
+	id string,
+	opts ...options.RequestOption,
+) error {
+	var res struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+	}
+	return svc.BaseClient.Delete(ctx, "v1/files/"+url.PathEscape(id), nil, &res, opts...)
+}
@@ -0,0 +1,105 @@
+package anthropic
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y0ug/llmhaven/http/options"
+)
+
+func newTestFileClient(t *testing.T, handler http.HandlerFunc) *Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewClient(options.WithBaseURL(srv.URL+"/"), options.WithApiKey("x-api-key", "test"))
+}
+
+func TestFileService_New(t *testing.T) {
+	c := newTestFileClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/files", r.URL.Path)
+		assert.Equal(t, BetaFiles, r.Header.Get("anthropic-beta"))
+
+		f, h, err := r.FormFile("file")
+		if assert.NoError(t, err) {
+			data, _ := io.ReadAll(f)
+			assert.Equal(t, "hello", string(data))
+			assert.Equal(t, "notes.txt", h.Filename)
+			assert.Contains(t, h.Header.Get("Content-Type"), "text/plain")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"file_123","type":"file","filename":"notes.txt",
+			"mime_type":"text/plain","size_bytes":5,"created_at":"2025-04-14T10:00:00Z"}`))
+	})
+
+	file, err := c.Files.New(context.Background(), FileNewParams{
+		Filename: "notes.txt",
+		Content:  strings.NewReader("hello"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "file_123", file.ID)
+	assert.Equal(t, int64(5), file.SizeBytes)
+	assert.Equal(t, 2025, file.CreatedAt.Year())
+}
+
+func TestFileService_ListGetDelete(t *testing.T) {
+	c := newTestFileClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, BetaFiles, r.Header.Get("anthropic-beta"))
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/files":
+			assert.Equal(t, "2", r.URL.Query().Get("limit"))
+			assert.Equal(t, "file_1", r.URL.Query().Get("after_id"))
+			w.Write([]byte(`{"data":[{"id":"file_2"}],"first_id":"file_2","last_id":"file_2","has_more":false}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/files/file_2":
+			w.Write([]byte(`{"id":"file_2","filename":"a.pdf"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/files/file_2/content":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"raw":true}`))
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/files/file_2":
+			w.Write([]byte(`{"id":"file_2","type":"file_deleted"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"type":"error","error":{"type":"not_found_error"}}`))
+		}
+	})
+	ctx := context.Background()
+
+	list, err := c.Files.List(ctx, FileListParams{Limit: 2, AfterID: "file_1"})
+	assert.NoError(t, err)
+	assert.Len(t, list.Data, 1)
+	assert.Equal(t, "file_2", list.LastID)
+
+	file, err := c.Files.Metadata(ctx, "file_2")
+	assert.NoError(t, err)
+	assert.Equal(t, "a.pdf", file.Filename)
+
+	// The content is returned as-is even when it's JSON
+	body, err := c.Files.Download(ctx, "file_2")
+	if assert.NoError(t, err) {
+		data, _ := io.ReadAll(body)
+		body.Close()
+		assert.Equal(t, `{"raw":true}`, string(data))
+	}
+
+	assert.NoError(t, c.Files.Delete(ctx, "file_2"))
+
+	_, err = c.Files.Metadata(ctx, "missing")
+	assert.Error(t, err)
+}
+
+func TestWithBeta(t *testing.T) {
+	c := newTestFileClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, BetaFiles+",other-2025-01-01", r.Header.Get("anthropic-beta"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":[]}`))
+	})
+	_, err := c.Files.List(context.Background(), FileListParams{},
+		WithBeta("other-2025-01-01"), WithBetaFiles())
+	assert.NoError(t, err)
+}
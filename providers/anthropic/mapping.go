@@ -1,17 +1,24 @@
 package anthropic
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/http/options"
 )
 
 func BaseChatMessageNewParamsToAnthropic(
 	params chat.ChatParams,
-) MessageNewParams {
+) (MessageNewParams, error) {
 	systemPromt := ""
 	msgs := make([]MessageParam, 0)
 	for _, m := range params.Messages {
 		if m.Role == "system" {
-			systemPromt = m.Content[0].String()
+			// Anthropic has a single system prompt, the messages are joined
+			for _, c := range m.Content {
+				systemPromt = joinSystem(systemPromt, c.String())
+			}
 			continue
 		}
 		role := m.Role
@@ -20,7 +27,7 @@ func BaseChatMessageNewParamsToAnthropic(
 		}
 		msgs = append(msgs, MessageParam{
 			Role:    role,
-			Content: m.Content,
+			Content: ContentToAnthropic(m.Content),
 		})
 	}
 	paramsProvider := MessageNewParams{
@@ -30,8 +37,178 @@ func BaseChatMessageNewParamsToAnthropic(
 		Messages:    msgs,
 		System:      systemPromt,
 		Tools:       params.Tools,
+
+		TopP:          params.TopP,
+		TopK:          params.TopK,
+		StopSequences: params.StopSequences,
+	}
+	if params.User != "" {
+		paramsProvider.Metadata = &Metadata{UserID: params.User}
+	}
+	thinking, err := ThinkingToAnthropic(params.Reasoning, params.MaxTokens)
+	if err != nil {
+		return paramsProvider, err
+	}
+	paramsProvider.Thinking = thinking
+
+	var errs []error
+	// Thinking only accepts the default temperature
+	if thinking != nil && params.Temperature != 0 && params.Temperature != 1 {
+		errs = append(errs, fmt.Errorf("anthropic: temperature must be 1 with thinking, got %v",
+			params.Temperature))
+	}
+	if params.Seed != nil {
+		errs = append(errs, chat.NewUnsupportedParamError("anthropic", "seed"))
+	}
+	if params.PresencePenalty != nil {
+		errs = append(errs, chat.NewUnsupportedParamError("anthropic", "presence_penalty"))
+	}
+	if params.FrequencyPenalty != nil {
+		errs = append(errs, chat.NewUnsupportedParamError("anthropic", "frequency_penalty"))
+	}
+	if len(params.LogitBias) > 0 {
+		errs = append(errs, chat.NewUnsupportedParamError("anthropic", "logit_bias"))
+	}
+	// Thinking only accepts the auto and none tool choices
+	if thinking != nil && len(params.Tools) > 0 && params.ToolChoice != nil &&
+		(params.ToolChoice.Type == chat.ToolChoiceRequired || params.ToolChoice.Type == chat.ToolChoiceTool) {
+		errs = append(errs, fmt.Errorf("anthropic: tool_choice %s can't be used with thinking",
+			params.ToolChoice.Type))
+	}
+	if len(errs) > 0 {
+		return paramsProvider, errors.Join(errs...)
+	}
+
+	if len(params.Tools) > 0 {
+		paramsProvider.ToolChoice = ToolChoiceToAnthropic(params.ToolChoice)
+	}
+
+	// Anthropic has no JSON mode, we force the call of a tool taking the
+	// schema as input. With thinking the call can't be forced, the model is
+	// asked to call it.
+	if format := params.ResponseFormat; format != nil {
+		tool := chat.Tool{
+			Name:        format.Name,
+			InputSchema: format.Schema,
+		}
+		if format.Description != "" {
+			tool.Description = &format.Description
+		}
+		paramsProvider.Tools = append(append([]chat.Tool{}, params.Tools...), tool)
+		if thinking == nil {
+			paramsProvider.ToolChoice = &ToolChoice{Type: "tool", Name: format.Name}
+		} else {
+			paramsProvider.ToolChoice = &ToolChoice{Type: "auto"}
+			paramsProvider.System = joinSystem(paramsProvider.System,
+				fmt.Sprintf("Answer by calling the %s tool.", format.Name))
+		}
+	}
+	return paramsProvider, nil
+}
+
+// joinSystem appends a part to the system prompt
+func joinSystem(system, part string) string {
+	if system == "" {
+		return part
+	}
+	return system + "\n\n" + part
+}
+
+// ContentToAnthropic drops the media type of url and file sources, it's only
+// accepted on base64 sources
+func ContentToAnthropic(contents []*chat.MessageContent) []*chat.MessageContent {
+	out := make([]*chat.MessageContent, len(contents))
+	for i, c := range contents {
+		out[i] = c
+		if c.Source == nil || c.Source.Type == chat.SourceTypeBase64 || c.Source.MediaType == "" {
+			continue
+		}
+		src := *c.Source
+		src.MediaType = ""
+		content := *c
+		content.Source = &src
+		out[i] = &content
+	}
+	return out
+}
+
+// usesFiles reports if a message references an uploaded file, the request
+// needs the files beta header
+func usesFiles(params MessageNewParams) bool {
+	for _, m := range params.Messages {
+		for _, c := range m.Content {
+			if c.Source != nil && c.Source.Type == chat.SourceTypeFile {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// requestOptions returns the options needed by the params
+func requestOptions(params MessageNewParams) []options.RequestOption {
+	if usesFiles(params) {
+		return []options.RequestOption{WithBetaFiles()}
+	}
+	return nil
+}
+
+// Budget used when only an effort is given
+var effortBudget = map[string]int{
+	chat.ReasoningEffortLow:    1024,
+	chat.ReasoningEffortMedium: 8192,
+	chat.ReasoningEffortHigh:   16384,
+}
+
+// Minimum budget_tokens accepted by the API
+const minThinkingBudget = 1024
+
+// ThinkingToAnthropic returns the thinking config, the budget must be below
+// maxTokens. The budget of an effort is lowered to fit, an explicit budget
+// which doesn't is an error.
+func ThinkingToAnthropic(reasoning *chat.Reasoning, maxTokens int) (*ThinkingConfig, error) {
+	if reasoning == nil {
+		return nil, nil
+	}
+	budget := reasoning.BudgetTokens
+	if budget == 0 {
+		budget = effortBudget[reasoning.Effort]
+		if budget == 0 {
+			budget = effortBudget[chat.ReasoningEffortMedium]
+		}
+		if maxTokens > 0 {
+			budget = min(budget, maxTokens-1)
+		}
+	}
+	if budget < minThinkingBudget {
+		return nil, fmt.Errorf("anthropic: thinking budget must be at least %d tokens, got %d",
+			minThinkingBudget, budget)
+	}
+	if maxTokens > 0 && budget >= maxTokens {
+		return nil, fmt.Errorf("anthropic: thinking budget %d must be below max_tokens %d",
+			budget, maxTokens)
+	}
+	return &ThinkingConfig{Type: "enabled", BudgetTokens: budget}, nil
+}
+
+func ToolChoiceToAnthropic(choice *chat.ToolChoice) *ToolChoice {
+	if choice == nil {
+		return nil
+	}
+	tc := &ToolChoice{
+		Type:                   "auto",
+		DisableParallelToolUse: choice.DisableParallel,
+	}
+	switch choice.Type {
+	case chat.ToolChoiceNone:
+		tc.Type = "none"
+	case chat.ToolChoiceRequired:
+		tc.Type = "any"
+	case chat.ToolChoiceTool:
+		tc.Type = "tool"
+		tc.Name = choice.Name
 	}
-	return paramsProvider
+	return tc
 }
 
 func AnthropicMessageToChatMessage(am *Message) *chat.ChatResponse {
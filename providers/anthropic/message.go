@@ -134,6 +134,10 @@ func (a *Message) Accumulate(event MessageStreamEvent) error {
 		switch delta.Type {
 		case "text_delta":
 			a.Content[index].Text += delta.Text
+		case "thinking_delta":
+			a.Content[index].Thinking += delta.Thinking
+		case "signature_delta":
+			a.Content[index].Signature += delta.Signature
 		case "input_json_delta":
 			a.Content[index].InputJson = append(
 				a.Content[index].InputJson,
@@ -213,6 +217,30 @@ type MessageNewParams struct {
 	System        string         `json:"system,omitempty"`
 
 	Temperature float64     `json:"temperature,omitempty"` // Number between 0 and 1 that controls randomness of the output.
+	TopP        *float64    `json:"top_p,omitempty"`
+	TopK        *int        `json:"top_k,omitempty"`
+	Metadata    *Metadata   `json:"metadata,omitempty"`
 	Tools       []chat.Tool `json:"tools,omitempty"`       // ToolParam
-	ToolChoice  interface{} `json:"tool_choice,omitempty"` // Auto but can be used to force to used a tools
+	ToolChoice  *ToolChoice `json:"tool_choice,omitempty"` // Auto but can be used to force to used a tools
+
+	Thinking *ThinkingConfig `json:"thinking,omitempty"`
+}
+
+// ThinkingConfig enables extended thinking, BudgetTokens must be >= 1024
+// and less than MaxTokens
+type ThinkingConfig struct {
+	Type         string `json:"type"` // enabled or disabled
+	BudgetTokens int    `json:"budget_tokens,omitempty"`
+}
+
+type Metadata struct {
+	// An external identifier for the user who is associated with the request.
+	UserID string `json:"user_id,omitempty"`
+}
+
+// ToolChoice type can be auto, any, tool or none
+type ToolChoice struct {
+	Type                   string `json:"type"`
+	Name                   string `json:"name,omitempty"`
+	DisableParallelToolUse bool   `json:"disable_parallel_tool_use,omitempty"`
 }
@@ -1,6 +1,8 @@
 package anthropic
 
 import (
+	"strings"
+
 	"github.com/y0ug/llmhaven/http/config"
 	"github.com/y0ug/llmhaven/http/options"
 )
@@ -14,3 +16,29 @@ func WithApiVersionAnthropic() options.RequestOption {
 		return r.Apply(options.WithHeader("anthropic-version", "2023-06-01"))
 	}
 }
+
+// BetaFiles enables the Files API and the file source in messages
+const BetaFiles = "files-api-2025-04-14"
+
+// WithBeta adds a feature to the anthropic-beta header, it keeps the betas
+// already set
+func WithBeta(beta string) options.RequestOption {
+	return func(r *config.RequestConfig) error {
+		current := r.Request.Header.Get("anthropic-beta")
+		if current == "" {
+			r.Request.Header.Set("anthropic-beta", beta)
+			return nil
+		}
+		for _, b := range strings.Split(current, ",") {
+			if strings.TrimSpace(b) == beta {
+				return nil
+			}
+		}
+		r.Request.Header.Set("anthropic-beta", current+","+beta)
+		return nil
+	}
+}
+
+func WithBetaFiles() options.RequestOption {
+	return WithBeta(BetaFiles)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/http/config"
 	"github.com/y0ug/llmhaven/http/options"
 	"github.com/y0ug/llmhaven/http/streaming"
 )
@@ -22,8 +23,11 @@ func (a *Provider) Send(
 	ctx context.Context,
 	params chat.ChatParams,
 ) (*chat.ChatResponse, error) {
-	paramsProvider := BaseChatMessageNewParamsToAnthropic(params)
-	am, err := a.client.Message.New(ctx, paramsProvider)
+	paramsProvider, err := BaseChatMessageNewParamsToAnthropic(params)
+	if err != nil {
+		return nil, err
+	}
+	am, err := a.client.Message.New(ctx, paramsProvider, requestOptions(paramsProvider)...)
 	if err != nil {
 		return nil, err
 	}
@@ -35,8 +39,11 @@ func (a *Provider) Stream(
 	ctx context.Context,
 	params chat.ChatParams,
 ) (streaming.Streamer[chat.EventStream], error) {
-	paramsProvider := BaseChatMessageNewParamsToAnthropic(params)
-	stream, err := a.client.Message.NewStreaming(ctx, paramsProvider)
+	paramsProvider, err := BaseChatMessageNewParamsToAnthropic(params)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := a.client.Message.NewStreaming(ctx, paramsProvider, requestOptions(paramsProvider)...)
 	if err != nil {
 		return nil, err
 	}
@@ -49,10 +56,18 @@ func (a *Provider) Stream(
 func (a *Provider) CountTokens(ctx context.Context,
 	params chat.ChatParams,
 ) (int64, error) {
-	paramsProvider := BaseChatMessageNewParamsToAnthropic(params)
-	resp, err := a.client.Message.CountTokens(ctx, paramsProvider)
+	paramsProvider, err := BaseChatMessageNewParamsToAnthropic(params)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := a.client.Message.CountTokens(ctx, paramsProvider, requestOptions(paramsProvider)...)
 	if err != nil {
 		return 0, err
 	}
 	return resp.InputTokens, nil
 }
+
+// RateLimits returns the rate limits of the last response
+func (a *Provider) RateLimits() config.RateLimit {
+	return a.client.RateLimit.GetSnapshot()
+}
@@ -0,0 +1,86 @@
+package anthropic
+
+import (
+	"context"
+	stderrors "errors"
+	"sort"
+
+	"github.com/y0ug/llmhaven/batch"
+	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/http/errors"
+)
+
+// Provider implements batch.BatchProvider with the Message Batches API
+
+func (a *Provider) Submit(ctx context.Context, requests []batch.Request) (*batch.Job, error) {
+	ids := make([]string, len(requests))
+	params := make([]chat.ChatParams, len(requests))
+	for i, r := range requests {
+		ids[i], params[i] = r.CustomID, r.Params
+	}
+	b, err := a.client.Batches.NewFromChat(ctx, ids, params)
+	if err != nil {
+		return nil, err
+	}
+	return ToBatchJob(b), nil
+}
+
+func (a *Provider) Status(ctx context.Context, id string) (*batch.Job, error) {
+	b, err := a.client.Batches.Retrieve(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return ToBatchJob(b), nil
+}
+
+func (a *Provider) Results(ctx context.Context, id string) ([]batch.Result, error) {
+	responses, err := a.client.Batches.ChatResults(ctx, id)
+	var batchErr *errors.BatchError
+	if err != nil && !stderrors.As(err, &batchErr) {
+		return nil, err
+	}
+	results := make([]batch.Result, 0, len(responses))
+	for id, resp := range responses {
+		results = append(results, batch.Result{CustomID: id, Response: resp})
+	}
+	if batchErr != nil {
+		for id, err := range batchErr.Items {
+			results = append(results, batch.Result{CustomID: id, Error: err.Error()})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].CustomID < results[j].CustomID
+	})
+	return results, nil
+}
+
+func (a *Provider) Cancel(ctx context.Context, id string) (*batch.Job, error) {
+	b, err := a.client.Batches.Cancel(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return ToBatchJob(b), nil
+}
+
+func ToBatchJob(b *MessageBatch) *batch.Job {
+	c := b.RequestCounts
+	job := &batch.Job{
+		ID:        b.ID,
+		Provider:  "anthropic",
+		Total:     c.Processing + c.Succeeded + c.Errored + c.Canceled + c.Expired,
+		Completed: c.Succeeded,
+		Failed:    c.Errored + c.Canceled + c.Expired,
+	}
+	switch b.ProcessingStatus {
+	case BatchStatusCanceling:
+		job.Status = batch.StatusCanceling
+	case BatchStatusEnded:
+		job.Status = batch.StatusCompleted
+		if b.CancelInitiatedAt != nil {
+			job.Status = batch.StatusCanceled
+		}
+	default:
+		job.Status = batch.StatusInProgress
+	}
+	return job
+}
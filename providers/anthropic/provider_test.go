@@ -2,11 +2,17 @@ package anthropic
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/http/options"
+	"go.uber.org/mock/gomock"
 )
 
 func TestAnthropicProvider_Send(t *testing.T) {
@@ -64,3 +70,380 @@ func TestAnthropicProvider_Send(t *testing.T) {
 		assert.Greater(t, response.Usage.OutputTokens, 0)
 	}
 }
+
+func TestBaseChatMessageNewParamsToAnthropic_ResponseFormat(t *testing.T) {
+	type answer struct {
+		Value string `json:"value"`
+	}
+	params := chat.NewChatParams(
+		chat.WithTools(chat.Tool{Name: "other"}),
+		chat.WithResponseSchema[answer](),
+	)
+
+	got, err := BaseChatMessageNewParamsToAnthropic(*params)
+	assert.NoError(t, err)
+	if assert.Len(t, got.Tools, 2) {
+		assert.Equal(t, "answer", got.Tools[1].Name)
+		assert.Equal(t, params.ResponseFormat.Schema, got.Tools[1].InputSchema)
+	}
+	assert.Len(t, params.Tools, 1)
+	assert.Equal(t, &ToolChoice{Type: "tool", Name: "answer"}, got.ToolChoice)
+}
+
+func TestBaseChatMessageNewParamsToAnthropic_Sources(t *testing.T) {
+	image := chat.NewImageFromURL("https://example.com/cat.png")
+	doc := chat.NewDocumentFromFileID("file_123")
+	doc.Source.MediaType = "application/pdf"
+	inline := &chat.MessageContent{
+		Type:   chat.ContentTypeImage,
+		Source: &chat.AIContentSrc{Type: chat.SourceTypeBase64, MediaType: "image/png", Data: []byte("png")},
+	}
+	params := chat.NewChatParams(chat.WithMessages(chat.NewMessage("user", image, doc, inline)))
+
+	got, err := BaseChatMessageNewParamsToAnthropic(*params)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	b, err := json.Marshal(got.Messages[0].Content)
+	assert.NoError(t, err)
+	var content []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &content))
+	// media_type is only accepted on base64 sources
+	assert.Equal(t, map[string]interface{}{"type": "url", "url": "https://example.com/cat.png"},
+		content[0]["source"])
+	assert.Equal(t, map[string]interface{}{"type": "file", "file_id": "file_123"},
+		content[1]["source"])
+	assert.Equal(t, "image/png", content[2]["source"].(map[string]interface{})["media_type"])
+	// The caller content is left untouched
+	assert.Equal(t, "application/pdf", doc.Source.MediaType)
+	assert.Equal(t, "image/png", image.Source.MediaType)
+}
+
+func TestProvider_FileSourceAddsBeta(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, BetaFiles, r.Header.Get("anthropic-beta"))
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant",
+			"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn",
+			"usage":{"input_tokens":10,"output_tokens":1}}`))
+	}))
+	defer srv.Close()
+	p := &Provider{client: NewClient(options.WithBaseURL(srv.URL+"/"), options.WithApiKey("x-api-key", "test"))}
+
+	_, err := p.Send(context.Background(), chat.ChatParams{
+		Model:     "claude-sonnet-4-0",
+		MaxTokens: 100,
+		Messages: []*chat.ChatMessage{
+			chat.NewMessage("user", chat.NewDocumentFromFileID("file_123"), chat.NewTextContent("Summarize")),
+		},
+	})
+	assert.NoError(t, err)
+
+	msgs := body["messages"].([]interface{})
+	content := msgs[0].(map[string]interface{})["content"].([]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "file", "file_id": "file_123"},
+		content[0].(map[string]interface{})["source"])
+}
+
+func TestBaseChatMessageNewParamsToAnthropic_ToolChoice(t *testing.T) {
+	tests := []struct {
+		name   string
+		choice *chat.ToolChoice
+		want   *ToolChoice
+	}{
+		{name: "unset", choice: nil, want: nil},
+		{
+			name:   "auto",
+			choice: &chat.ToolChoice{Type: chat.ToolChoiceAuto},
+			want:   &ToolChoice{Type: "auto"},
+		},
+		{
+			name:   "none",
+			choice: &chat.ToolChoice{Type: chat.ToolChoiceNone},
+			want:   &ToolChoice{Type: "none"},
+		},
+		{
+			name:   "required",
+			choice: &chat.ToolChoice{Type: chat.ToolChoiceRequired},
+			want:   &ToolChoice{Type: "any"},
+		},
+		{
+			name:   "tool",
+			choice: &chat.ToolChoice{Type: chat.ToolChoiceTool, Name: "get_weather"},
+			want:   &ToolChoice{Type: "tool", Name: "get_weather"},
+		},
+		{
+			name:   "disable parallel",
+			choice: &chat.ToolChoice{DisableParallel: true},
+			want:   &ToolChoice{Type: "auto", DisableParallelToolUse: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := chat.ChatParams{
+				Tools:      []chat.Tool{{Name: "get_weather"}},
+				ToolChoice: tt.choice,
+			}
+			got, err := BaseChatMessageNewParamsToAnthropic(params)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.ToolChoice)
+		})
+	}
+
+	t.Run("no tools", func(t *testing.T) {
+		params := chat.NewChatParams(chat.WithToolChoiceTool("get_weather"))
+		got, err := BaseChatMessageNewParamsToAnthropic(*params)
+		assert.NoError(t, err)
+		assert.Nil(t, got.ToolChoice)
+	})
+}
+
+func TestBaseChatMessageNewParamsToAnthropic_Sampling(t *testing.T) {
+	params := chat.NewChatParams(
+		chat.WithTopP(0.9),
+		chat.WithTopK(40),
+		chat.WithStopSequences("END"),
+		chat.WithUser("user-1"),
+	)
+	got, err := BaseChatMessageNewParamsToAnthropic(*params)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.9, *got.TopP)
+	assert.Equal(t, 40, *got.TopK)
+	assert.Equal(t, []string{"END"}, got.StopSequences)
+	assert.Equal(t, &Metadata{UserID: "user-1"}, got.Metadata)
+
+	params.Update(
+		chat.WithSeed(1),
+		chat.WithLogitBias(map[string]int{"50256": -100}),
+	)
+	_, err = BaseChatMessageNewParamsToAnthropic(*params)
+	assert.ErrorIs(t, err, chat.ErrUnsupportedParam)
+	assert.Contains(t, err.Error(), "seed")
+	assert.Contains(t, err.Error(), "logit_bias")
+}
+
+func TestBaseChatMessageNewParamsToAnthropic_Reasoning(t *testing.T) {
+	params := chat.NewChatParams(chat.WithReasoningBudget(2048))
+	got, err := BaseChatMessageNewParamsToAnthropic(*params)
+	assert.NoError(t, err)
+	assert.Equal(t, &ThinkingConfig{Type: "enabled", BudgetTokens: 2048}, got.Thinking)
+
+	params = chat.NewChatParams(chat.WithReasoningEffort(chat.ReasoningEffortHigh))
+	got, err = BaseChatMessageNewParamsToAnthropic(*params)
+	assert.NoError(t, err)
+	assert.Equal(t, 16384, got.Thinking.BudgetTokens)
+
+	// The budget of an effort is lowered below max_tokens
+	params.Update(chat.WithMaxTokens(4096))
+	got, err = BaseChatMessageNewParamsToAnthropic(*params)
+	assert.NoError(t, err)
+	assert.Equal(t, 4095, got.Thinking.BudgetTokens)
+
+	// Not below the minimum budget
+	params.Update(chat.WithMaxTokens(1024))
+	_, err = BaseChatMessageNewParamsToAnthropic(*params)
+	assert.Error(t, err)
+
+	// An explicit budget isn't changed
+	params = chat.NewChatParams(chat.WithReasoningBudget(8192), chat.WithMaxTokens(8192))
+	_, err = BaseChatMessageNewParamsToAnthropic(*params)
+	assert.Error(t, err)
+	params = chat.NewChatParams(chat.WithReasoningBudget(512), chat.WithMaxTokens(8192))
+	_, err = BaseChatMessageNewParamsToAnthropic(*params)
+	assert.Error(t, err)
+}
+
+func TestBaseChatMessageNewParamsToAnthropic_ReasoningTemperature(t *testing.T) {
+	params := chat.NewChatParams(
+		chat.WithReasoningBudget(2048),
+		chat.WithMaxTokens(4096),
+		chat.WithTemperature(0.5),
+	)
+	_, err := BaseChatMessageNewParamsToAnthropic(*params)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "temperature")
+	}
+
+	params.Update(chat.WithTemperature(1))
+	_, err = BaseChatMessageNewParamsToAnthropic(*params)
+	assert.NoError(t, err)
+
+	// Without thinking any temperature is sent
+	params = chat.NewChatParams(chat.WithMaxTokens(4096), chat.WithTemperature(0.5))
+	_, err = BaseChatMessageNewParamsToAnthropic(*params)
+	assert.NoError(t, err)
+}
+
+func TestBaseChatMessageNewParamsToAnthropic_ReasoningToolChoice(t *testing.T) {
+	thinking := []func(*chat.ChatParams){
+		chat.WithReasoningBudget(2048),
+		chat.WithMaxTokens(4096),
+		chat.WithTools(chat.Tool{Name: "get_weather"}),
+	}
+
+	// Thinking only accepts auto and none
+	for _, choice := range []func(*chat.ChatParams){
+		chat.WithToolChoice(chat.ToolChoice{Type: chat.ToolChoiceRequired}),
+		chat.WithToolChoiceTool("get_weather"),
+	} {
+		params := chat.NewChatParams(append(thinking, choice)...)
+		_, err := BaseChatMessageNewParamsToAnthropic(*params)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "thinking")
+		}
+	}
+	params := chat.NewChatParams(append(thinking, chat.WithToolChoice(chat.ToolChoice{Type: chat.ToolChoiceNone}))...)
+	got, err := BaseChatMessageNewParamsToAnthropic(*params)
+	assert.NoError(t, err)
+	assert.Equal(t, &ToolChoice{Type: "none"}, got.ToolChoice)
+
+	// The schema tool isn't forced, the model is asked to call it
+	type answer struct {
+		Value string `json:"value"`
+	}
+	params = chat.NewChatParams(append(thinking,
+		chat.WithMessages(chat.NewSystemMessage("Be brief.")),
+		chat.WithResponseSchema[answer]())...)
+	got, err = BaseChatMessageNewParamsToAnthropic(*params)
+	assert.NoError(t, err)
+	assert.Equal(t, &ToolChoice{Type: "auto"}, got.ToolChoice)
+	if assert.Len(t, got.Tools, 2) {
+		assert.Equal(t, "answer", got.Tools[1].Name)
+	}
+	assert.Equal(t, "Be brief.\n\nAnswer by calling the answer tool.", got.System)
+}
+
+// messageCounter counts 10 tokens per message
+type messageCounter struct{}
+
+func (messageCounter) CountTokens(ctx context.Context, params chat.ChatParams) (int64, error) {
+	return int64(10 * len(params.Messages)), nil
+}
+
+func TestBaseChatMessageNewParamsToAnthropic_Summary(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	summarizer := chat.NewMockProvider(ctrl)
+	summarizer.EXPECT().Send(gomock.Any(), gomock.Any()).Return(&chat.ChatResponse{
+		Choice: []chat.ChatChoice{{Content: []*chat.MessageContent{chat.NewTextContent("they talked")}}},
+	}, nil)
+
+	m := chat.NewContextManager(messageCounter{}, 30,
+		chat.WithContextStrategy(chat.Summarize(summarizer, "cheap", 1)))
+	params, err := m.Fit(context.Background(), chat.ChatParams{Messages: []*chat.ChatMessage{
+		chat.NewSystemMessage("Be brief."),
+		chat.NewUserMessage("q1"),
+		chat.NewMessage("assistant", chat.NewTextContent("a1")),
+		chat.NewUserMessage("q2"),
+		chat.NewMessage("assistant", chat.NewTextContent("a2")),
+		chat.NewUserMessage("q3"),
+	}})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// The caller's system prompt is kept with the summary
+	got, err := BaseChatMessageNewParamsToAnthropic(params)
+	assert.NoError(t, err)
+	assert.Equal(t, "Be brief.\n\nSummary of the earlier conversation:\nthey talked", got.System)
+	if assert.Len(t, got.Messages, 1) {
+		assert.Equal(t, "q3", got.Messages[0].Content[0].Text)
+	}
+
+	// Several system messages are joined
+	params.Messages = append([]*chat.ChatMessage{chat.NewSystemMessage("First.")}, params.Messages...)
+	got, err = BaseChatMessageNewParamsToAnthropic(params)
+	assert.NoError(t, err)
+	assert.Equal(t, "First.\n\nBe brief.\n\nSummary of the earlier conversation:\nthey talked", got.System)
+}
+
+func TestMessage_AccumulateThinking(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","role":"assistant","usage":{"input_tokens":10}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Let me "}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"think"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"42"}}`,
+		`{"type":"content_block_stop","index":1}`,
+	}
+
+	handler := NewAnthropicEventHandler()
+	var deltas []string
+	for _, data := range events {
+		var event MessageStreamEvent
+		if !assert.NoError(t, json.Unmarshal([]byte(data), &event)) {
+			t.FailNow()
+		}
+		evt, err := handler.HandleEvent(event)
+		assert.NoError(t, err)
+		if evt.Type == "thinking_delta" {
+			deltas = append(deltas, evt.Delta.(string))
+		}
+	}
+	assert.Equal(t, []string{"Let me ", "think"}, deltas)
+
+	content := handler.message.Content
+	if assert.Len(t, content, 2) {
+		assert.Equal(t, chat.ContentTypeThinking, content[0].Type)
+		assert.Equal(t, "Let me think", content[0].Thinking)
+		assert.Equal(t, "sig", content[0].Signature)
+		assert.Equal(t, "42", content[1].Text)
+	}
+
+	// The thinking block must be sent back as is
+	b, err := json.Marshal(handler.message.ToParam())
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), `{"type":"thinking","thinking":"Let me think","signature":"sig"}`)
+}
+
+func TestAnthropicMessageToChatMessage_Usage(t *testing.T) {
+	msg := &Message{Usage: &Usage{
+		InputTokens:              10,
+		OutputTokens:             5,
+		CacheReadInputTokens:     100,
+		CacheCreationInputTokens: 20,
+	}}
+	resp := AnthropicMessageToChatMessage(msg)
+	// input_tokens of the API, without the cache
+	assert.Equal(t, 10, resp.Usage.InputTokens)
+	assert.Equal(t, 100, resp.Usage.InputCachedTokens)
+	assert.Equal(t, 20, resp.Usage.InputCacheCreationTokens)
+}
+
+func TestProvider_RateLimits(t *testing.T) {
+	reset := time.Now().Add(time.Minute).UTC().Format(time.RFC3339)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("anthropic-ratelimit-requests-limit", "50")
+		w.Header().Set("anthropic-ratelimit-requests-remaining", "0")
+		w.Header().Set("anthropic-ratelimit-requests-reset", reset)
+		w.Header().Set("anthropic-ratelimit-tokens-limit", "40000")
+		w.Header().Set("anthropic-ratelimit-tokens-remaining", "39000")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"msg_1","type":"message","role":"assistant",
+			"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn",
+			"usage":{"input_tokens":10,"output_tokens":1}}`)
+	}))
+	defer server.Close()
+
+	p := New(options.WithBaseURL(server.URL+"/"), options.WithApiKey("x-api-key", "test")).(*Provider)
+	assert.True(t, p.RateLimits().UpdatedAt.IsZero())
+
+	_, err := p.Send(context.Background(), *chat.NewChatParams(
+		chat.WithModel("claude-sonnet-4-0"),
+		chat.WithMaxTokens(100),
+		chat.WithMessages(chat.NewUserMessage("?")),
+	))
+	assert.NoError(t, err)
+
+	rl := p.RateLimits()
+	assert.Equal(t, 50, rl.LimitRequests)
+	assert.Equal(t, 0, rl.RemainingRequests)
+	assert.Equal(t, 39000, rl.RemainingTokens)
+	assert.InDelta(t, time.Minute, rl.ResetRequests, float64(2*time.Second))
+	assert.True(t, rl.Exhausted(time.Now()))
+}
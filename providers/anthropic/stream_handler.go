@@ -34,27 +34,21 @@ func (h *AnthropicStreamHandler) HandleEvent(event streaming.Event) (MessageStre
 	case "error":
 		var errorResp struct {
 			Error struct {
-				Type    string      `json:"type"`
-				Message string      `json:"message"`
-				Details interface{} `json:"details"`
+				Type    string `json:"type"`
+				Message string `json:"message"`
 			} `json:"error"`
 		}
 		if err := json.Unmarshal(event.Data, &errorResp); err != nil {
 			return result, fmt.Errorf("failed to parse error response: %w", err)
 		}
-		if err := json.Unmarshal(event.Data, &result); err != nil {
-			return result, fmt.Errorf("failed to parse error response: %w", err)
-		}
-		// fmt.Println("result", string(result.data))
-		err = fmt.Errorf("%s", errorResp.Error.Message)
+		err = &StreamError{Type: errorResp.Error.Type, Message: errorResp.Error.Message}
 	}
 
 	return result, err
 }
 
+// ShouldContinue is true for the error events, HandleEvent returns their
+// StreamError
 func (h *AnthropicStreamHandler) ShouldContinue(event streaming.Event) bool {
-	if event.Type == "ping" {
-		return true
-	}
-	return event.Type != "error"
+	return true
 }
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	llmerrors "github.com/y0ug/llmhaven/http/errors"
 	"github.com/y0ug/llmhaven/http/streaming"
 )
 
@@ -35,4 +36,7 @@ func TestAnthropicStreamHandler_HandleEvent_Error(t *testing.T) {
 	fmt.Println(err)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "Overloaded")
+	assert.True(t, llmerrors.IsRetryable(err))
+	assert.Equal(t, llmerrors.StatusOverloaded, llmerrors.StatusCode(err))
+	assert.True(t, handler.ShouldContinue(event))
 }
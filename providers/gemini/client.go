@@ -3,29 +3,30 @@ package gemini
 import (
 	"os"
 
+	"github.com/y0ug/llmhaven/http/client"
 	"github.com/y0ug/llmhaven/http/options"
-	"github.com/y0ug/llmhaven/providers/openai"
 )
 
 type Client struct {
-	*openai.Client
+	*client.BaseClient
+	Models *ModelService
 }
 
-func WithEnvironmentProduction() options.RequestOption {
-	return options.WithBaseURL("https://generativelanguage.googleapis.com/v1beta/openai/")
-}
-
-func NewClient(opts ...options.RequestOption) *Client {
+func NewClient(opts ...options.RequestOption) (r *Client) {
 	defaults := []options.RequestOption{
 		WithEnvironmentProduction(),
 	}
 	if o, ok := os.LookupEnv("GEMINI_API_KEY"); ok {
-		defaults = append(defaults, options.WithAuthToken(o))
+		defaults = append(defaults, options.WithApiKey("x-goog-api-key", o))
 	}
-	opts = append(defaults, opts...)
-	r := &Client{
-		Client: openai.NewClient(opts...),
+	r = &Client{
+		BaseClient: &client.BaseClient{
+			Options:  append(defaults, opts...),
+			NewError: NewError,
+		},
 	}
 
-	return r
+	r.Models = NewModelService(r.BaseClient.Options...)
+
+	return
 }
@@ -0,0 +1,49 @@
+package gemini
+
+import (
+	"os"
+
+	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/http/options"
+	"github.com/y0ug/llmhaven/providers/openai"
+)
+
+// CompatClient uses the OpenAI compatible endpoint of Gemini, Gemini only
+// features (safety settings, cached content, grounding) are not available
+type CompatClient struct {
+	*openai.Client
+}
+
+func WithEnvironmentCompat() options.RequestOption {
+	return options.WithBaseURL("https://generativelanguage.googleapis.com/v1beta/openai/")
+}
+
+func NewCompatClient(opts ...options.RequestOption) *CompatClient {
+	defaults := []options.RequestOption{
+		WithEnvironmentCompat(),
+	}
+	if o, ok := os.LookupEnv("GEMINI_API_KEY"); ok {
+		defaults = append(defaults, options.WithAuthToken(o))
+	}
+	opts = append(defaults, opts...)
+	r := &CompatClient{
+		Client: openai.NewClient(opts...),
+	}
+
+	return r
+}
+
+type CompatProvider struct {
+	*openai.Provider
+}
+
+// NewCompat creates a provider using the OpenAI compatible endpoint
+func NewCompat(opts ...options.RequestOption) chat.Provider {
+	return &CompatProvider{
+		&openai.Provider{
+			Client: NewCompatClient(opts...).Client,
+			Name:   "gemini-openai",
+			Quirks: openai.NewQuirks(openai.WithNoBatch(), openai.WithUnsupported("logit_bias")),
+		},
+	}
+}
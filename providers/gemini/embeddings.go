@@ -0,0 +1,95 @@
+package gemini
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/y0ug/llmhaven/embedding"
+	"github.com/y0ug/llmhaven/http/config"
+	"github.com/y0ug/llmhaven/http/options"
+)
+
+type EmbedContentRequest struct {
+	Model                string  `json:"model"` // models/{model}
+	Content              Content `json:"content"`
+	TaskType             string  `json:"taskType,omitempty"`
+	Title                string  `json:"title,omitempty"` // only with RETRIEVAL_DOCUMENT
+	OutputDimensionality *int    `json:"outputDimensionality,omitempty"`
+}
+
+type BatchEmbedContentsRequest struct {
+	Requests []EmbedContentRequest `json:"requests"`
+}
+
+type ContentEmbedding struct {
+	Values []float32 `json:"values"`
+}
+
+type BatchEmbedContentsResponse struct {
+	Embeddings []ContentEmbedding `json:"embeddings"`
+}
+
+// BatchEmbedContents embeds up to 100 contents, every request must use model
+func (svc *ModelService) BatchEmbedContents(
+	ctx context.Context,
+	model string,
+	params BatchEmbedContentsRequest,
+	opts ...options.RequestOption,
+) (res BatchEmbedContentsResponse, err error) {
+	opts = append(svc.Options[:], opts...)
+	path := modelPath(model, "batchEmbedContents")
+	err = config.ExecuteNewRequest(ctx, http.MethodPost, path, params, &res, svc.NewError, opts...)
+	return
+}
+
+// Max requests of batchEmbedContents
+const embeddingBatchSize = 100
+
+// Embedder implements embedding.Embedder, Model is used when the params
+// don't set one. The API doesn't return the token usage.
+type Embedder struct {
+	Client *Client
+	Model  string
+}
+
+func NewEmbedder(opts ...options.RequestOption) embedding.Embedder {
+	return &Embedder{
+		Client: NewClient(opts...),
+		Model:  "gemini-embedding-001",
+	}
+}
+
+func (e *Embedder) Embed(
+	ctx context.Context,
+	inputs []string,
+	opts ...func(*embedding.EmbedParams),
+) ([][]float32, embedding.Usage, error) {
+	params := embedding.NewEmbedParams(append([]func(*embedding.EmbedParams){
+		embedding.WithModel(e.Model), embedding.WithBatchSize(embeddingBatchSize),
+	}, opts...)...)
+	model := modelName(params.Model)
+
+	return embedding.InBatches(ctx, inputs, params.BatchSize,
+		func(ctx context.Context, batch []string) ([][]float32, embedding.Usage, error) {
+			req := BatchEmbedContentsRequest{Requests: make([]EmbedContentRequest, len(batch))}
+			for i, input := range batch {
+				req.Requests[i] = EmbedContentRequest{
+					Model:    model,
+					Content:  Content{Parts: []Part{{Text: input}}},
+					TaskType: params.TaskType,
+				}
+				if params.Dimensions > 0 {
+					req.Requests[i].OutputDimensionality = &params.Dimensions
+				}
+			}
+			resp, err := e.Client.Models.BatchEmbedContents(ctx, params.Model, req)
+			if err != nil {
+				return nil, embedding.Usage{}, err
+			}
+			vectors := make([][]float32, len(resp.Embeddings))
+			for i, emb := range resp.Embeddings {
+				vectors[i] = emb.Values
+			}
+			return vectors, embedding.Usage{}, nil
+		})
+}
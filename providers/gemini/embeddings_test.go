@@ -0,0 +1,42 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y0ug/llmhaven/embedding"
+	"github.com/y0ug/llmhaven/http/options"
+)
+
+func TestEmbedder_Embed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1beta/models/gemini-embedding-001:batchEmbedContents", r.URL.Path)
+		var req BatchEmbedContentsRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		resp := BatchEmbedContentsResponse{}
+		for _, r := range req.Requests {
+			assert.Equal(t, "models/gemini-embedding-001", r.Model)
+			assert.Equal(t, embedding.TaskRetrievalDocument, r.TaskType)
+			if assert.NotNil(t, r.OutputDimensionality) {
+				assert.Equal(t, 768, *r.OutputDimensionality)
+			}
+			resp.Embeddings = append(resp.Embeddings, ContentEmbedding{
+				Values: []float32{float32(len(r.Content.Parts[0].Text))},
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	e := NewEmbedder(options.WithBaseURL(server.URL+"/v1beta/"), options.WithApiKey("x-goog-api-key", "key"))
+	vectors, _, err := e.Embed(context.Background(), []string{"a", "bb"},
+		embedding.WithTaskType(embedding.TaskRetrievalDocument),
+		embedding.WithDimensions(768))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float32{{1}, {2}}, vectors)
+}
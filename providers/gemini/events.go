@@ -0,0 +1,84 @@
+package gemini
+
+import (
+	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/http/streaming"
+)
+
+// GeminiEventStream converts the generateContent chunks to chat.EventStream.
+// A chunk can hold text and the finish reason, it produces a text_delta
+// followed by a message_stop.
+type GeminiEventStream struct {
+	stream  streaming.Streamer[GenerateContentResponse]
+	message GenerateContentResponse
+	pending []chat.EventStream
+	current chat.EventStream
+}
+
+func NewGeminiEventStream(
+	stream streaming.Streamer[GenerateContentResponse],
+) *GeminiEventStream {
+	return &GeminiEventStream{stream: stream}
+}
+
+func (s *GeminiEventStream) Next() bool {
+	for len(s.pending) == 0 {
+		if !s.stream.Next() {
+			return false
+		}
+		s.pending = s.HandleEvent(s.stream.Current())
+	}
+	s.current, s.pending = s.pending[0], s.pending[1:]
+	return true
+}
+
+// HandleEvent accumulates the chunk and returns the events it produces
+func (s *GeminiEventStream) HandleEvent(chunk GenerateContentResponse) []chat.EventStream {
+	s.message.Accumulate(chunk)
+	msg := ToChatResponse(&s.message)
+
+	events := make([]chat.EventStream, 0)
+	finished := false
+	for _, candidate := range chunk.Candidates {
+		if candidate.FinishReason != "" {
+			finished = true
+		}
+		// Only the first candidate is streamed as delta
+		if candidate.Index != 0 {
+			continue
+		}
+		for _, part := range candidate.Content.Parts {
+			if part.Text == "" {
+				continue
+			}
+			evt := chat.EventStream{Type: "text_delta", Delta: part.Text, Message: msg}
+			if part.Thought {
+				evt.Type = "thinking_delta"
+			}
+			events = append(events, evt)
+		}
+	}
+
+	if chunk.PromptFeedback != nil && chunk.PromptFeedback.BlockReason != "" {
+		events = append(events, chat.EventStream{
+			Type:    "error",
+			Delta:   "prompt blocked: " + chunk.PromptFeedback.BlockReason,
+			Message: msg,
+		})
+	} else if finished {
+		events = append(events, chat.EventStream{Type: "message_stop", Message: msg})
+	}
+	return events
+}
+
+func (s *GeminiEventStream) Current() chat.EventStream {
+	return s.current
+}
+
+func (s *GeminiEventStream) Err() error {
+	return s.stream.Err()
+}
+
+func (s *GeminiEventStream) Close() error {
+	return s.stream.Close()
+}
@@ -0,0 +1,279 @@
+package gemini
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/y0ug/llmhaven/chat"
+)
+
+// Budget used when only an effort is given
+var effortBudget = map[string]int{
+	chat.ReasoningEffortLow:    1024,
+	chat.ReasoningEffortMedium: 8192,
+	chat.ReasoningEffortHigh:   24576,
+}
+
+func ToGenerateContentRequest(params chat.ChatParams) (GenerateContentRequest, error) {
+	req := GenerateContentRequest{}
+
+	var errs []error
+	if len(params.LogitBias) > 0 {
+		errs = append(errs, chat.NewUnsupportedParamError("gemini", "logit_bias"))
+	}
+	if params.User != "" {
+		errs = append(errs, chat.NewUnsupportedParamError("gemini", "user"))
+	}
+	if len(errs) > 0 {
+		return req, errors.Join(errs...)
+	}
+
+	// functionResponse needs the name of the function, tool_result only
+	// reference the call id
+	names := map[string]string{}
+	for _, m := range params.Messages {
+		for _, c := range m.Content {
+			if c.Type == chat.ContentTypeToolUse {
+				names[c.ID] = c.Name
+			}
+		}
+	}
+
+	for _, m := range params.Messages {
+		if m.Role == "system" {
+			if req.SystemInstruction == nil {
+				req.SystemInstruction = &Content{}
+			}
+			for _, c := range m.Content {
+				req.SystemInstruction.Parts = append(req.SystemInstruction.Parts,
+					Part{Text: c.String()})
+			}
+			continue
+		}
+
+		role := "user"
+		if m.Role == "assistant" {
+			role = "model"
+		}
+		content := Content{Role: role}
+		for _, c := range m.Content {
+			part, err := MessageContentToPart(c, names)
+			if err != nil {
+				return req, err
+			}
+			content.Parts = append(content.Parts, part)
+		}
+		if len(content.Parts) > 0 {
+			req.Contents = append(req.Contents, content)
+		}
+	}
+
+	if len(params.Tools) > 0 {
+		tool := Tool{}
+		for _, t := range params.Tools {
+			decl := FunctionDeclaration{
+				Name:                 t.Name,
+				ParametersJsonSchema: t.InputSchema,
+			}
+			if t.Description != nil {
+				decl.Description = *t.Description
+			}
+			tool.FunctionDeclarations = append(tool.FunctionDeclarations, decl)
+		}
+		req.Tools = append(req.Tools, tool)
+		req.ToolConfig = ToolChoiceToGemini(params.ToolChoice)
+	}
+
+	config := &GenerationConfig{
+		StopSequences:    params.StopSequences,
+		CandidateCount:   params.N,
+		MaxOutputTokens:  params.MaxTokens,
+		Temperature:      params.Temperature,
+		TopP:             params.TopP,
+		TopK:             params.TopK,
+		Seed:             params.Seed,
+		PresencePenalty:  params.PresencePenalty,
+		FrequencyPenalty: params.FrequencyPenalty,
+		ThinkingConfig:   ThinkingToGemini(params.Reasoning),
+	}
+	if format := params.ResponseFormat; format != nil {
+		config.ResponseMimeType = "application/json"
+		config.ResponseJsonSchema = format.Schema
+	}
+	req.GenerationConfig = config
+
+	return req, nil
+}
+
+func MessageContentToPart(c *chat.MessageContent, names map[string]string) (Part, error) {
+	switch c.Type {
+	case chat.ContentTypeText:
+		return Part{Text: c.Text}, nil
+	case chat.ContentTypeThinking:
+		return Part{Text: c.Thinking, Thought: true, ThoughtSignature: c.Signature}, nil
+	case chat.ContentTypeToolUse:
+		args := c.Input
+		if len(args) == 0 && len(c.InputJson) > 0 {
+			args = json.RawMessage(c.InputJson)
+		}
+		return Part{
+			FunctionCall:     &FunctionCall{ID: c.ID, Name: c.Name, Args: args},
+			ThoughtSignature: c.Signature,
+		}, nil
+	case chat.ContentTypeToolResult:
+		key := "output"
+		if c.IsError {
+			key = "error"
+		}
+		var value interface{} = c.Content
+		// Keep structured results as JSON
+		var v interface{}
+		if json.Unmarshal([]byte(c.Content), &v) == nil {
+			value = v
+		}
+		return Part{FunctionResponse: &FunctionResponse{
+			ID:       c.ToolUseID,
+			Name:     names[c.ToolUseID],
+			Response: map[string]interface{}{key: value},
+		}}, nil
+	case chat.ContentTypeImage, chat.ContentTypeDocument, chat.ContentTypeInputAudio:
+		if c.Source == nil {
+			return Part{}, fmt.Errorf("gemini: %s content without source", c.Type)
+		}
+		switch c.Source.Type {
+		case chat.SourceTypeURL:
+			return Part{FileData: &FileData{
+				MimeType: c.Source.MediaType,
+				FileURI:  c.Source.URL,
+			}}, nil
+		case chat.SourceTypeFile:
+			return Part{FileData: &FileData{
+				MimeType: c.Source.MediaType,
+				FileURI:  c.Source.FileID,
+			}}, nil
+		}
+		return Part{InlineData: &Blob{
+			MimeType: c.Source.MediaType,
+			Data:     c.Source.Data,
+		}}, nil
+	}
+	return Part{}, fmt.Errorf("gemini: unsupported content type %s", c.Type)
+}
+
+func ToolChoiceToGemini(choice *chat.ToolChoice) *ToolConfig {
+	if choice == nil {
+		return nil
+	}
+	fc := &FunctionCallingConfig{Mode: "AUTO"}
+	switch choice.Type {
+	case chat.ToolChoiceNone:
+		fc.Mode = "NONE"
+	case chat.ToolChoiceRequired:
+		fc.Mode = "ANY"
+	case chat.ToolChoiceTool:
+		fc.Mode = "ANY"
+		fc.AllowedFunctionNames = []string{choice.Name}
+	}
+	return &ToolConfig{FunctionCallingConfig: fc}
+}
+
+func ThinkingToGemini(reasoning *chat.Reasoning) *ThinkingConfig {
+	if reasoning == nil {
+		return nil
+	}
+	budget := reasoning.BudgetTokens
+	if budget == 0 {
+		budget = effortBudget[reasoning.Effort]
+	}
+	tc := &ThinkingConfig{IncludeThoughts: true}
+	if budget != 0 {
+		tc.ThinkingBudget = &budget
+	}
+	return tc
+}
+
+func ToStopReason(reason string, hasToolCall bool) string {
+	switch reason {
+	case "STOP":
+		if hasToolCall {
+			return "tool_use"
+		}
+		return "end_turn"
+	case "MAX_TOKENS":
+		return "max_tokens"
+	}
+	return strings.ToLower(reason)
+}
+
+// newCallID generates an id for function calls, Gemini doesn't always set one
+func newCallID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "call_" + hex.EncodeToString(b)
+}
+
+func ToChatResponse(resp *GenerateContentResponse) *chat.ChatResponse {
+	cm := &chat.ChatResponse{}
+	cm.ID = resp.ResponseID
+	cm.Model = resp.ModelVersion
+	cm.Usage = &chat.ChatUsage{}
+	if u := resp.UsageMetadata; u != nil {
+		cm.Usage.InputTokens = u.PromptTokenCount + u.ToolUsePromptTokenCount
+		cm.Usage.InputCachedTokens = u.CachedContentTokenCount
+		// Thoughts are billed as output tokens
+		cm.Usage.OutputTokens = u.CandidatesTokenCount + u.ThoughtsTokenCount
+		cm.Usage.OutputReasoningTokens = u.ThoughtsTokenCount
+	}
+
+	for i := range resp.Candidates {
+		candidate := &resp.Candidates[i]
+		c := chat.ChatChoice{Role: "assistant"}
+		hasToolCall := false
+		for j := range candidate.Content.Parts {
+			part := &candidate.Content.Parts[j]
+			switch {
+			case part.FunctionCall != nil:
+				hasToolCall = true
+				// Keep the generated id in the response, a stream call
+				// ToChatResponse on every chunk
+				if part.FunctionCall.ID == "" {
+					part.FunctionCall.ID = newCallID()
+				}
+				content := chat.NewToolUseContent(
+					part.FunctionCall.ID,
+					part.FunctionCall.Name,
+					part.FunctionCall.Args)
+				content.Signature = part.ThoughtSignature
+				c.Content = append(c.Content, content)
+			case part.Thought:
+				c.Content = append(c.Content,
+					chat.NewThinkingContent(part.Text, part.ThoughtSignature))
+			case part.Text != "":
+				content := chat.NewTextContent(part.Text)
+				content.Signature = part.ThoughtSignature
+				c.Content = append(c.Content, content)
+			}
+		}
+		c.StopReason = ToStopReason(candidate.FinishReason, hasToolCall)
+
+		metadata := map[string]interface{}{}
+		if candidate.GroundingMetadata != nil {
+			metadata["grounding_metadata"] = candidate.GroundingMetadata
+		}
+		if len(candidate.SafetyRatings) > 0 {
+			metadata["safety_ratings"] = candidate.SafetyRatings
+		}
+		if len(candidate.CitationMetadata) > 0 {
+			metadata["citation_metadata"] = candidate.CitationMetadata
+		}
+		if len(metadata) > 0 {
+			c.Metadata = metadata
+		}
+		cm.Choice = append(cm.Choice, c)
+	}
+	return cm
+}
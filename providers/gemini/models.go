@@ -0,0 +1,324 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/y0ug/llmhaven/http/config"
+	"github.com/y0ug/llmhaven/http/options"
+	"github.com/y0ug/llmhaven/http/streaming"
+)
+
+// ModelService wraps the models/{model}:generateContent endpoints
+type ModelService struct {
+	Options  []options.RequestOption
+	NewError config.NewAPIError
+}
+
+func NewModelService(opts ...options.RequestOption) *ModelService {
+	return &ModelService{
+		Options:  opts,
+		NewError: NewError,
+	}
+}
+
+// modelName adds the models/ prefix when it's missing
+func modelName(model string) string {
+	if !strings.HasPrefix(model, "models/") && !strings.HasPrefix(model, "tunedModels/") {
+		return "models/" + model
+	}
+	return model
+}
+
+func modelPath(model, method string) string {
+	return modelName(model) + ":" + method
+}
+
+func (svc *ModelService) GenerateContent(
+	ctx context.Context,
+	model string,
+	params GenerateContentRequest,
+	opts ...options.RequestOption,
+) (res GenerateContentResponse, err error) {
+	opts = append(svc.Options[:], opts...)
+	path := modelPath(model, "generateContent")
+	err = config.ExecuteNewRequest(ctx, http.MethodPost, path, params, &res, svc.NewError, opts...)
+	return
+}
+
+func (svc *ModelService) StreamGenerateContent(
+	ctx context.Context,
+	model string,
+	params GenerateContentRequest,
+	opts ...options.RequestOption,
+) (streaming.Streamer[GenerateContentResponse], error) {
+	combinedOpts := append(svc.Options[:], opts...)
+	combinedOpts = append(combinedOpts, options.WithQuery("alt", "sse"))
+	path := modelPath(model, "streamGenerateContent")
+
+	var raw *http.Response
+	err := config.ExecuteNewRequest(
+		ctx,
+		http.MethodPost,
+		path,
+		params,
+		&raw,
+		svc.NewError,
+		combinedOpts...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error executing new request streaming: %w", err)
+	}
+	return streaming.NewStream(
+		streaming.NewDecoderSSE(raw),
+		streaming.NewGenericStreamHandler[GenerateContentResponse](),
+	), nil
+}
+
+type GenerateContentRequest struct {
+	Contents          []Content         `json:"contents"`
+	SystemInstruction *Content          `json:"systemInstruction,omitempty"`
+	Tools             []Tool            `json:"tools,omitempty"`
+	ToolConfig        *ToolConfig       `json:"toolConfig,omitempty"`
+	SafetySettings    []SafetySetting   `json:"safetySettings,omitempty"`
+	GenerationConfig  *GenerationConfig `json:"generationConfig,omitempty"`
+	CachedContent     string            `json:"cachedContent,omitempty"` // cachedContents/{id}
+}
+
+// Content role is user or model
+type Content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []Part `json:"parts"`
+}
+
+// Part holds only one of the data field
+type Part struct {
+	Text             string            `json:"text,omitempty"`
+	InlineData       *Blob             `json:"inlineData,omitempty"`
+	FileData         *FileData         `json:"fileData,omitempty"`
+	FunctionCall     *FunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *FunctionResponse `json:"functionResponse,omitempty"`
+
+	// Thought is set on thinking summary, the signature must be sent back
+	Thought          bool   `json:"thought,omitempty"`
+	ThoughtSignature string `json:"thoughtSignature,omitempty"`
+}
+
+// Blob data is base64 encoded by encoding/json
+type Blob struct {
+	MimeType string `json:"mimeType"`
+	Data     []byte `json:"data"`
+}
+
+// FileData references a file uploaded with the Files API or a gs:// URI
+type FileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+type FunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+// FunctionResponse response must be a JSON object, we use the output and
+// error keys
+type FunctionResponse struct {
+	ID       string                 `json:"id,omitempty"`
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+type Tool struct {
+	FunctionDeclarations []FunctionDeclaration `json:"functionDeclarations,omitempty"`
+	GoogleSearch         *struct{}             `json:"googleSearch,omitempty"`
+	CodeExecution        *struct{}             `json:"codeExecution,omitempty"`
+}
+
+type FunctionDeclaration struct {
+	Name                 string      `json:"name"`
+	Description          string      `json:"description,omitempty"`
+	ParametersJsonSchema interface{} `json:"parametersJsonSchema,omitempty"`
+}
+
+type ToolConfig struct {
+	FunctionCallingConfig *FunctionCallingConfig `json:"functionCallingConfig,omitempty"`
+}
+
+type FunctionCallingConfig struct {
+	Mode                 string   `json:"mode,omitempty"` // AUTO, ANY, NONE
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+// SafetySetting category is HARM_CATEGORY_HARASSMENT, HARM_CATEGORY_HATE_SPEECH,
+// HARM_CATEGORY_SEXUALLY_EXPLICIT, HARM_CATEGORY_DANGEROUS_CONTENT, etc.
+// Threshold is BLOCK_NONE, BLOCK_ONLY_HIGH, BLOCK_MEDIUM_AND_ABOVE,
+// BLOCK_LOW_AND_ABOVE or OFF
+type SafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+type GenerationConfig struct {
+	StopSequences      []string        `json:"stopSequences,omitempty"`
+	ResponseMimeType   string          `json:"responseMimeType,omitempty"`
+	ResponseJsonSchema interface{}     `json:"responseJsonSchema,omitempty"`
+	CandidateCount     *int            `json:"candidateCount,omitempty"`
+	MaxOutputTokens    int             `json:"maxOutputTokens,omitempty"`
+	Temperature        float64         `json:"temperature,omitempty"`
+	TopP               *float64        `json:"topP,omitempty"`
+	TopK               *int            `json:"topK,omitempty"`
+	Seed               *int            `json:"seed,omitempty"`
+	PresencePenalty    *float64        `json:"presencePenalty,omitempty"`
+	FrequencyPenalty   *float64        `json:"frequencyPenalty,omitempty"`
+	ThinkingConfig     *ThinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+type ThinkingConfig struct {
+	IncludeThoughts bool `json:"includeThoughts,omitempty"`
+	ThinkingBudget  *int `json:"thinkingBudget,omitempty"`
+}
+
+type GenerateContentResponse struct {
+	Candidates     []Candidate     `json:"candidates,omitempty"`
+	PromptFeedback *PromptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  *UsageMetadata  `json:"usageMetadata,omitempty"`
+	ModelVersion   string          `json:"modelVersion,omitempty"`
+	ResponseID     string          `json:"responseId,omitempty"`
+}
+
+type Candidate struct {
+	Content           Content            `json:"content"`
+	FinishReason      string             `json:"finishReason,omitempty"` // STOP, MAX_TOKENS, SAFETY, RECITATION, etc
+	Index             int                `json:"index"`
+	SafetyRatings     []SafetyRating     `json:"safetyRatings,omitempty"`
+	CitationMetadata  json.RawMessage    `json:"citationMetadata,omitempty"`
+	GroundingMetadata *GroundingMetadata `json:"groundingMetadata,omitempty"`
+}
+
+type SafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+type PromptFeedback struct {
+	BlockReason   string         `json:"blockReason,omitempty"`
+	SafetyRatings []SafetyRating `json:"safetyRatings,omitempty"`
+}
+
+// GroundingMetadata is returned when the googleSearch tool is used
+type GroundingMetadata struct {
+	WebSearchQueries  []string          `json:"webSearchQueries,omitempty"`
+	GroundingChunks   []GroundingChunk  `json:"groundingChunks,omitempty"`
+	GroundingSupports []json.RawMessage `json:"groundingSupports,omitempty"`
+	SearchEntryPoint  json.RawMessage   `json:"searchEntryPoint,omitempty"`
+}
+
+type GroundingChunk struct {
+	Web *struct {
+		URI   string `json:"uri"`
+		Title string `json:"title"`
+	} `json:"web,omitempty"`
+}
+
+type UsageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	TotalTokenCount         int `json:"totalTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount,omitempty"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount,omitempty"`
+	ToolUsePromptTokenCount int `json:"toolUsePromptTokenCount,omitempty"`
+}
+
+// Accumulate merges a streamed chunk, consecutive text parts are concatenated
+func (a *GenerateContentResponse) Accumulate(chunk GenerateContentResponse) {
+	if chunk.ResponseID != "" {
+		a.ResponseID = chunk.ResponseID
+	}
+	if chunk.ModelVersion != "" {
+		a.ModelVersion = chunk.ModelVersion
+	}
+	if chunk.UsageMetadata != nil {
+		a.UsageMetadata = chunk.UsageMetadata
+	}
+	if chunk.PromptFeedback != nil {
+		a.PromptFeedback = chunk.PromptFeedback
+	}
+
+	for _, delta := range chunk.Candidates {
+		for len(a.Candidates) <= delta.Index {
+			a.Candidates = append(a.Candidates, Candidate{Index: len(a.Candidates)})
+		}
+		c := &a.Candidates[delta.Index]
+		if delta.Content.Role != "" {
+			c.Content.Role = delta.Content.Role
+		}
+		for _, part := range delta.Content.Parts {
+			n := len(c.Content.Parts)
+			if n > 0 && isTextPart(part) && isTextPart(c.Content.Parts[n-1]) &&
+				c.Content.Parts[n-1].Thought == part.Thought {
+				last := &c.Content.Parts[n-1]
+				last.Text += part.Text
+				if part.ThoughtSignature != "" {
+					last.ThoughtSignature = part.ThoughtSignature
+				}
+				continue
+			}
+			c.Content.Parts = append(c.Content.Parts, part)
+		}
+		if delta.FinishReason != "" {
+			c.FinishReason = delta.FinishReason
+		}
+		if delta.SafetyRatings != nil {
+			c.SafetyRatings = delta.SafetyRatings
+		}
+		if delta.CitationMetadata != nil {
+			c.CitationMetadata = delta.CitationMetadata
+		}
+		if delta.GroundingMetadata != nil {
+			c.GroundingMetadata = delta.GroundingMetadata
+		}
+	}
+}
+
+func isTextPart(p Part) bool {
+	return p.InlineData == nil && p.FileData == nil &&
+		p.FunctionCall == nil && p.FunctionResponse == nil
+}
+
+// CountTokensRequest counts a full generateContent request, system
+// instruction and tools included
+type CountTokensRequest struct {
+	GenerateContentRequest CountTokensContent `json:"generateContentRequest"`
+}
+
+type CountTokensContent struct {
+	Model string `json:"model"` // models/{model}
+	GenerateContentRequest
+}
+
+type CountTokensResponse struct {
+	TotalTokens             int `json:"totalTokens"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount,omitempty"`
+}
+
+func (svc *ModelService) CountTokens(
+	ctx context.Context,
+	model string,
+	params CountTokensRequest,
+	opts ...options.RequestOption,
+) (res CountTokensResponse, err error) {
+	// WithSafetySettings and WithCachedContent target generateContent, the
+	// fields are rejected at the top level of countTokens
+	combinedOpts := append(svc.Options[:], options.WithJSONDel("safetySettings"),
+		options.WithJSONDel("cachedContent"))
+	combinedOpts = append(combinedOpts, opts...)
+	path := modelPath(model, "countTokens")
+	err = config.ExecuteNewRequest(ctx, http.MethodPost, path, params, &res, svc.NewError, combinedOpts...)
+	return
+}
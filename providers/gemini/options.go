@@ -0,0 +1,20 @@
+package gemini
+
+import (
+	"github.com/y0ug/llmhaven/http/options"
+)
+
+func WithEnvironmentProduction() options.RequestOption {
+	return options.WithBaseURL("https://generativelanguage.googleapis.com/v1beta/")
+}
+
+// WithSafetySettings sets the safetySettings of generateContent requests
+func WithSafetySettings(settings ...SafetySetting) options.RequestOption {
+	return options.WithJSONSet("safetySettings", settings)
+}
+
+// WithCachedContent uses a cached content created with the cachedContents
+// API, name is in the form cachedContents/{id}
+func WithCachedContent(name string) options.RequestOption {
+	return options.WithJSONSet("cachedContent", name)
+}
@@ -1,19 +1,77 @@
 package gemini
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/y0ug/llmhaven/chat"
 	"github.com/y0ug/llmhaven/http/options"
-	"github.com/y0ug/llmhaven/providers/openai"
+	"github.com/y0ug/llmhaven/http/streaming"
 )
 
 type Provider struct {
-	*openai.Provider
+	client *Client
 }
 
+// New creates a provider using the native generateContent API, see NewCompat
+// for the OpenAI compatible endpoint
 func New(opts ...options.RequestOption) chat.Provider {
 	return &Provider{
-		&openai.Provider{
-			Client: NewClient(opts...).Client,
+		client: NewClient(opts...),
+	}
+}
+
+func (p *Provider) Send(
+	ctx context.Context,
+	params chat.ChatParams,
+) (*chat.ChatResponse, error) {
+	req, err := ToGenerateContentRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Models.GenerateContent(ctx, params.Model, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Candidates) == 0 && resp.PromptFeedback != nil &&
+		resp.PromptFeedback.BlockReason != "" {
+		return nil, fmt.Errorf("gemini: prompt blocked: %s", resp.PromptFeedback.BlockReason)
+	}
+	return ToChatResponse(&resp), nil
+}
+
+func (p *Provider) Stream(
+	ctx context.Context,
+	params chat.ChatParams,
+) (streaming.Streamer[chat.EventStream], error) {
+	req, err := ToGenerateContentRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := p.client.Models.StreamGenerateContent(ctx, params.Model, req)
+	if err != nil {
+		return nil, err
+	}
+	return NewGeminiEventStream(stream), nil
+}
+
+// CountTokens uses the countTokens endpoint
+func (p *Provider) CountTokens(
+	ctx context.Context,
+	params chat.ChatParams,
+) (int64, error) {
+	req, err := ToGenerateContentRequest(params)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := p.client.Models.CountTokens(ctx, params.Model, CountTokensRequest{
+		GenerateContentRequest: CountTokensContent{
+			Model:                  modelName(params.Model),
+			GenerateContentRequest: req,
 		},
+	})
+	if err != nil {
+		return 0, err
 	}
+	return int64(resp.TotalTokens), nil
 }
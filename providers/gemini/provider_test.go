@@ -2,11 +2,15 @@ package gemini
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/http/options"
 )
 
 func TestSend(t *testing.T) {
@@ -44,3 +48,177 @@ func TestSend(t *testing.T) {
 	fmt.Println(response.Choice[0].Content[0].String())
 	fmt.Printf("Usage: %d %d\n", response.Usage.InputTokens, response.Usage.OutputTokens)
 }
+
+func TestToGenerateContentRequest(t *testing.T) {
+	desc := "Get the weather"
+	params := chat.NewChatParams(
+		chat.WithModel("gemini-2.0-flash"),
+		chat.WithMaxTokens(100),
+		chat.WithMessages(
+			chat.NewMessage("system", chat.NewTextContent("Be brief")),
+			chat.NewUserMessage("Weather in Paris?"),
+			chat.NewMessage("assistant",
+				chat.NewToolUseContent("call_1", "get_weather", json.RawMessage(`{"location":"Paris"}`))),
+			chat.NewMessage("user", chat.NewToolResultContent("call_1", `{"temp":20}`)),
+		),
+		chat.WithTools(chat.Tool{Name: "get_weather", Description: &desc}),
+		chat.WithToolChoiceTool("get_weather"),
+	)
+	req, err := ToGenerateContentRequest(*params)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "Be brief", req.SystemInstruction.Parts[0].Text)
+	if assert.Len(t, req.Contents, 3) {
+		assert.Equal(t, "user", req.Contents[0].Role)
+		assert.Equal(t, "model", req.Contents[1].Role)
+		assert.Equal(t, "get_weather", req.Contents[1].Parts[0].FunctionCall.Name)
+		fr := req.Contents[2].Parts[0].FunctionResponse
+		assert.Equal(t, "get_weather", fr.Name)
+		assert.Equal(t, map[string]interface{}{"temp": float64(20)}, fr.Response["output"])
+	}
+	assert.Equal(t, "Get the weather", req.Tools[0].FunctionDeclarations[0].Description)
+	assert.Equal(t, &FunctionCallingConfig{
+		Mode:                 "ANY",
+		AllowedFunctionNames: []string{"get_weather"},
+	}, req.ToolConfig.FunctionCallingConfig)
+	assert.Equal(t, 100, req.GenerationConfig.MaxOutputTokens)
+
+	params.Update(chat.WithLogitBias(map[string]int{"1": 1}))
+	_, err = ToGenerateContentRequest(*params)
+	assert.ErrorIs(t, err, chat.ErrUnsupportedParam)
+}
+
+func TestProvider_SendNative(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1beta/models/gemini-2.0-flash:generateContent", r.URL.Path)
+		assert.Equal(t, "key", r.Header.Get("x-goog-api-key"))
+		var req map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Contains(t, req, "safetySettings")
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"candidates": [{
+				"content": {"role": "model", "parts": [
+					{"text": "thinking...", "thought": true},
+					{"functionCall": {"name": "get_weather", "args": {"location": "Paris"}}, "thoughtSignature": "sig"}
+				]},
+				"finishReason": "STOP",
+				"groundingMetadata": {"webSearchQueries": ["weather paris"]}
+			}],
+			"usageMetadata": {"promptTokenCount": 10, "candidatesTokenCount": 5, "thoughtsTokenCount": 3, "cachedContentTokenCount": 4},
+			"modelVersion": "gemini-2.0-flash",
+			"responseId": "resp_1"
+		}`)
+	}))
+	defer server.Close()
+
+	provider := New(
+		options.WithBaseURL(server.URL+"/v1beta/"),
+		options.WithApiKey("x-goog-api-key", "key"),
+		WithSafetySettings(SafetySetting{
+			Category:  "HARM_CATEGORY_HARASSMENT",
+			Threshold: "BLOCK_ONLY_HIGH",
+		}),
+	)
+	resp, err := provider.Send(context.Background(), *chat.NewChatParams(
+		chat.WithModel("gemini-2.0-flash"),
+		chat.WithMessages(chat.NewUserMessage("Weather in Paris?")),
+	))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "resp_1", resp.ID)
+	assert.Equal(t, 10, resp.Usage.InputTokens)
+	assert.Equal(t, 4, resp.Usage.InputCachedTokens)
+	assert.Equal(t, 8, resp.Usage.OutputTokens)
+	assert.Equal(t, 3, resp.Usage.OutputReasoningTokens)
+
+	choice := resp.Choice[0]
+	assert.Equal(t, "tool_use", choice.StopReason)
+	assert.Contains(t, choice.Metadata, "grounding_metadata")
+	if assert.Len(t, choice.Content, 2) {
+		assert.Equal(t, chat.ContentTypeThinking, choice.Content[0].Type)
+		call := choice.Content[1]
+		assert.Equal(t, chat.ContentTypeToolUse, call.Type)
+		assert.NotEmpty(t, call.ID)
+		assert.Equal(t, "sig", call.Signature)
+	}
+}
+
+func TestProvider_StreamNative(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/models/gemini-2.0-flash:streamGenerateContent", r.URL.Path)
+		assert.Equal(t, "sse", r.URL.Query().Get("alt"))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"role\":\"model\",\"parts\":[{\"text\":\"Hello\"}]}}]}\r\n\r\n")
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\" world\"}]},\"finishReason\":\"STOP\"}],"+
+			"\"usageMetadata\":{\"promptTokenCount\":3,\"candidatesTokenCount\":2}}\r\n\r\n")
+	}))
+	defer server.Close()
+
+	provider := New(options.WithBaseURL(server.URL + "/"))
+	stream, err := provider.Stream(context.Background(), *chat.NewChatParams(
+		chat.WithModel("gemini-2.0-flash"),
+		chat.WithMessages(chat.NewUserMessage("Hi")),
+	))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer stream.Close()
+
+	var types []string
+	var last chat.EventStream
+	for stream.Next() {
+		last = stream.Current()
+		types = append(types, last.Type)
+	}
+	assert.NoError(t, stream.Err())
+	assert.Equal(t, []string{"text_delta", "text_delta", "message_stop"}, types)
+	assert.Equal(t, "Hello world", last.Message.Choice[0].Content[0].Text)
+	assert.Equal(t, "end_turn", last.Message.Choice[0].StopReason)
+	assert.Equal(t, 2, last.Message.Usage.OutputTokens)
+}
+
+func TestProvider_CountTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/models/gemini-2.0-flash:countTokens", r.URL.Path)
+		var req map[string]json.RawMessage
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.NotContains(t, req, "safetySettings")
+
+		var body CountTokensRequest
+		assert.NoError(t, json.Unmarshal(req["generateContentRequest"], &body.GenerateContentRequest))
+		content := body.GenerateContentRequest
+		assert.Equal(t, "models/gemini-2.0-flash", content.Model)
+		assert.NotNil(t, content.SystemInstruction)
+		assert.Len(t, content.Contents, 1)
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"totalTokens": 42}`)
+	}))
+	defer server.Close()
+
+	provider := New(
+		options.WithBaseURL(server.URL+"/"),
+		WithSafetySettings(SafetySetting{
+			Category:  "HARM_CATEGORY_HARASSMENT",
+			Threshold: "BLOCK_ONLY_HIGH",
+		}),
+	)
+	counter, ok := provider.(chat.TokenCounter)
+	if !assert.True(t, ok) {
+		t.FailNow()
+	}
+	n, err := counter.CountTokens(context.Background(), *chat.NewChatParams(
+		chat.WithModel("gemini-2.0-flash"),
+		chat.WithMessages(
+			chat.NewSystemMessage("Be brief"),
+			chat.NewUserMessage("Hi"),
+		),
+	))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), n)
+}
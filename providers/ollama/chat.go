@@ -0,0 +1,112 @@
+package ollama
+
+import (
+	"encoding/json"
+
+	"github.com/y0ug/llmhaven/http/options"
+	"github.com/y0ug/llmhaven/internal"
+)
+
+// ChatService targets api/chat, the stream is NDJSON
+type ChatService struct {
+	*internal.GenericChatService[ChatRequest, ChatResponse, ChatResponse]
+}
+
+func NewChatService(opts ...options.RequestOption) *ChatService {
+	return &ChatService{
+		GenericChatService: &internal.GenericChatService[ChatRequest, ChatResponse, ChatResponse]{
+			Options:  opts,
+			NewError: NewError,
+			Endpoint: "api/chat",
+		},
+	}
+}
+
+type ChatRequest struct {
+	Model    string        `json:"model"`
+	Messages []Message     `json:"messages"`
+	Tools    []Tool        `json:"tools,omitempty"`
+	Format   interface{}   `json:"format,omitempty"` // "json" or a JSON schema
+	Options  *ModelOptions `json:"options,omitempty"`
+	// Ollama stream by default
+	Stream    bool   `json:"stream"`
+	Think     *bool  `json:"think,omitempty"`
+	KeepAlive string `json:"keep_alive,omitempty"`
+}
+
+type Message struct {
+	Role      string     `json:"role"` // system, user, assistant or tool
+	Content   string     `json:"content"`
+	Thinking  string     `json:"thinking,omitempty"`
+	Images    [][]byte   `json:"images,omitempty"` // base64 encoded by encoding/json
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	ToolName  string     `json:"tool_name,omitempty"` // name of the tool for role tool
+}
+
+// ToolCall has no id in Ollama, we generate one to match the tool results
+type ToolCall struct {
+	ID       string       `json:"id,omitempty"`
+	Function FunctionCall `json:"function"`
+}
+
+type FunctionCall struct {
+	Index     int             `json:"index,omitempty"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type Tool struct {
+	Type     string       `json:"type"` // function
+	Function ToolFunction `json:"function"`
+}
+
+type ToolFunction struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Parameters  interface{} `json:"parameters,omitempty"`
+}
+
+// ModelOptions are the runtime parameters of the model
+type ModelOptions struct {
+	NumPredict       int      `json:"num_predict,omitempty"`
+	NumCtx           int      `json:"num_ctx,omitempty"`
+	Temperature      float64  `json:"temperature,omitempty"`
+	TopP             *float64 `json:"top_p,omitempty"`
+	TopK             *int     `json:"top_k,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	Stop             []string `json:"stop,omitempty"`
+	PresencePenalty  *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+}
+
+type ChatResponse struct {
+	Model      string  `json:"model"`
+	CreatedAt  string  `json:"created_at"`
+	Message    Message `json:"message"`
+	Done       bool    `json:"done"`
+	DoneReason string  `json:"done_reason,omitempty"` // stop, length, load
+
+	TotalDuration      int64 `json:"total_duration,omitempty"`
+	LoadDuration       int64 `json:"load_duration,omitempty"`
+	PromptEvalCount    int   `json:"prompt_eval_count,omitempty"`
+	PromptEvalDuration int64 `json:"prompt_eval_duration,omitempty"`
+	EvalCount          int   `json:"eval_count,omitempty"`
+	EvalDuration       int64 `json:"eval_duration,omitempty"`
+
+	// Set when the error happens while streaming
+	Error string `json:"error,omitempty"`
+}
+
+func (a *ChatResponse) Accumulate(chunk ChatResponse) {
+	message := a.Message
+	message.Content += chunk.Message.Content
+	message.Thinking += chunk.Message.Thinking
+	message.ToolCalls = append(message.ToolCalls, chunk.Message.ToolCalls...)
+	message.Images = append(message.Images, chunk.Message.Images...)
+	if chunk.Message.Role != "" {
+		message.Role = chunk.Message.Role
+	}
+
+	*a = chunk
+	a.Message = message
+}
@@ -0,0 +1,53 @@
+package ollama
+
+import (
+	"os"
+	"strings"
+
+	"github.com/y0ug/llmhaven/http/client"
+	"github.com/y0ug/llmhaven/http/options"
+)
+
+type Client struct {
+	*client.BaseClient
+	Chat   *ChatService
+	Models *ModelService
+	Embed  *EmbedService
+}
+
+func NewClient(opts ...options.RequestOption) (r *Client) {
+	defaults := []options.RequestOption{
+		WithEnvironmentLocal(),
+	}
+	if o, ok := os.LookupEnv("OLLAMA_HOST"); ok && o != "" {
+		defaults = append(defaults, WithHost(o))
+	}
+	r = &Client{
+		BaseClient: &client.BaseClient{
+			Options:  append(defaults, opts...),
+			NewError: NewError,
+		},
+	}
+
+	r.Chat = NewChatService(r.BaseClient.Options...)
+	r.Models = NewModelService(r.BaseClient)
+	r.Embed = NewEmbedService(r.BaseClient)
+
+	return
+}
+
+func WithEnvironmentLocal() options.RequestOption {
+	return options.WithBaseURL("http://localhost:11434/")
+}
+
+// WithHost sets the base URL from a value like OLLAMA_HOST, the scheme and
+// port are optional
+func WithHost(host string) options.RequestOption {
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	if !strings.HasSuffix(host, "/") {
+		host += "/"
+	}
+	return options.WithBaseURL(host)
+}
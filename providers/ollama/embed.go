@@ -0,0 +1,82 @@
+package ollama
+
+import (
+	"context"
+
+	"github.com/y0ug/llmhaven/embedding"
+	"github.com/y0ug/llmhaven/http/client"
+	"github.com/y0ug/llmhaven/http/options"
+)
+
+// EmbedService wraps api/embed
+type EmbedService struct {
+	client *client.BaseClient
+}
+
+func NewEmbedService(c *client.BaseClient) *EmbedService {
+	return &EmbedService{client: c}
+}
+
+type EmbedRequest struct {
+	Model      string        `json:"model"`
+	Input      []string      `json:"input"`
+	Truncate   *bool         `json:"truncate,omitempty"` // true by default
+	Dimensions int           `json:"dimensions,omitempty"`
+	Options    *ModelOptions `json:"options,omitempty"`
+	KeepAlive  string        `json:"keep_alive,omitempty"`
+}
+
+type EmbedResponse struct {
+	Model           string      `json:"model"`
+	Embeddings      [][]float32 `json:"embeddings"`
+	PromptEvalCount int         `json:"prompt_eval_count"`
+}
+
+func (svc *EmbedService) New(
+	ctx context.Context,
+	params EmbedRequest,
+	opts ...options.RequestOption,
+) (res *EmbedResponse, err error) {
+	err = svc.client.Post(ctx, "api/embed", params, &res, opts...)
+	return
+}
+
+// The API has no limit, large batches only use more memory
+const embeddingBatchSize = 256
+
+// Embedder implements embedding.Embedder, Model is used when the params
+// don't set one
+type Embedder struct {
+	Client *Client
+	Model  string
+}
+
+func NewEmbedder(opts ...options.RequestOption) embedding.Embedder {
+	return &Embedder{
+		Client: NewClient(opts...),
+		Model:  "nomic-embed-text",
+	}
+}
+
+func (e *Embedder) Embed(
+	ctx context.Context,
+	inputs []string,
+	opts ...func(*embedding.EmbedParams),
+) ([][]float32, embedding.Usage, error) {
+	params := embedding.NewEmbedParams(append([]func(*embedding.EmbedParams){
+		embedding.WithModel(e.Model), embedding.WithBatchSize(embeddingBatchSize),
+	}, opts...)...)
+
+	return embedding.InBatches(ctx, inputs, params.BatchSize,
+		func(ctx context.Context, batch []string) ([][]float32, embedding.Usage, error) {
+			resp, err := e.Client.Embed.New(ctx, EmbedRequest{
+				Model:      params.Model,
+				Input:      batch,
+				Dimensions: params.Dimensions,
+			})
+			if err != nil {
+				return nil, embedding.Usage{}, err
+			}
+			return resp.Embeddings, embedding.Usage{InputTokens: resp.PromptEvalCount}, nil
+		})
+}
@@ -0,0 +1,35 @@
+package ollama
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/y0ug/llmhaven/http/errors"
+)
+
+func NewError(resp *http.Response, req *http.Request) errors.APIError {
+	return &APIError{
+		APIErrorBase: errors.APIErrorBase{
+			StatusCode: resp.StatusCode,
+			Request:    req,
+			Response:   resp,
+		},
+	}
+}
+
+// APIError body is {"error": "message"}
+type APIError struct {
+	errors.APIErrorBase
+	Message string `json:"error"`
+}
+
+func (r *APIError) UnmarshalJSON(data []byte) (err error) {
+	r.JSON = string(data)
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &body); err == nil {
+		r.Message = body.Error
+	}
+	return nil
+}
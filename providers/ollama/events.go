@@ -0,0 +1,42 @@
+package ollama
+
+import (
+	"fmt"
+
+	"github.com/y0ug/llmhaven/chat"
+)
+
+// OllamaEventHandler processes the api/chat chunks
+type OllamaEventHandler struct {
+	response ChatResponse
+}
+
+func NewOllamaEventHandler() *OllamaEventHandler {
+	return &OllamaEventHandler{}
+}
+
+func (h *OllamaEventHandler) ShouldContinue(chunk ChatResponse) bool {
+	return true
+}
+
+func (h *OllamaEventHandler) HandleEvent(chunk ChatResponse) (chat.EventStream, error) {
+	if chunk.Error != "" {
+		return chat.EventStream{Type: "error", Delta: chunk.Error},
+			fmt.Errorf("ollama: %s", chunk.Error)
+	}
+
+	h.response.Accumulate(chunk)
+	evt := chat.EventStream{Message: ToChatResponse(&h.response)}
+
+	switch {
+	case chunk.Done:
+		evt.Type = "message_stop"
+	case chunk.Message.Thinking != "":
+		evt.Type = "thinking_delta"
+		evt.Delta = chunk.Message.Thinking
+	default:
+		evt.Type = "text_delta"
+		evt.Delta = chunk.Message.Content
+	}
+	return evt, nil
+}
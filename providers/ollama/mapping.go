@@ -0,0 +1,189 @@
+package ollama
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/y0ug/llmhaven/chat"
+)
+
+func ToChatRequest(params chat.ChatParams) (ChatRequest, error) {
+	req := ChatRequest{
+		Model: params.Model,
+		Options: &ModelOptions{
+			NumPredict:       params.MaxTokens,
+			Temperature:      params.Temperature,
+			TopP:             params.TopP,
+			TopK:             params.TopK,
+			Seed:             params.Seed,
+			Stop:             params.StopSequences,
+			PresencePenalty:  params.PresencePenalty,
+			FrequencyPenalty: params.FrequencyPenalty,
+		},
+	}
+
+	var errs []error
+	if params.N != nil && *params.N > 1 {
+		errs = append(errs, chat.NewUnsupportedParamError("ollama", "n"))
+	}
+	if len(params.LogitBias) > 0 {
+		errs = append(errs, chat.NewUnsupportedParamError("ollama", "logit_bias"))
+	}
+	// No tool_choice, auto is the default and none is emulated
+	if tc := params.ToolChoice; tc != nil &&
+		tc.Type != chat.ToolChoiceAuto && tc.Type != chat.ToolChoiceNone {
+		errs = append(errs, chat.NewUnsupportedParamError("ollama", "tool_choice"))
+	}
+	if len(errs) > 0 {
+		return req, errors.Join(errs...)
+	}
+
+	messages, err := MessageToOllama(params.Messages...)
+	if err != nil {
+		return req, err
+	}
+	req.Messages = messages
+
+	// none is emulated by not sending the tools
+	if params.ToolChoice == nil || params.ToolChoice.Type != chat.ToolChoiceNone {
+		req.Tools = ToolsToOllama(params.Tools...)
+	}
+	if params.ResponseFormat != nil {
+		req.Format = params.ResponseFormat.Schema
+	}
+	if params.Reasoning != nil {
+		think := true
+		req.Think = &think
+	}
+	return req, nil
+}
+
+func MessageToOllama(messages ...*chat.ChatMessage) ([]Message, error) {
+	// The tool message needs the tool name, tool_result only reference the
+	// call id
+	names := map[string]string{}
+	for _, m := range messages {
+		for _, c := range m.Content {
+			if c.Type == chat.ContentTypeToolUse {
+				names[c.ID] = c.Name
+			}
+		}
+	}
+
+	result := make([]Message, 0, len(messages))
+	for _, m := range messages {
+		msg := Message{Role: m.Role}
+		var text strings.Builder
+		for _, c := range m.Content {
+			switch c.Type {
+			case chat.ContentTypeText:
+				text.WriteString(c.Text)
+			case chat.ContentTypeThinking:
+				msg.Thinking += c.Thinking
+			case chat.ContentTypeRedactedThinking:
+			case chat.ContentTypeImage:
+				if c.Source == nil || c.Source.Type != chat.SourceTypeBase64 {
+					return nil, fmt.Errorf("ollama: image must be sent as base64")
+				}
+				msg.Images = append(msg.Images, c.Source.Data)
+			case chat.ContentTypeToolUse:
+				args := c.Input
+				if len(args) == 0 {
+					args = json.RawMessage(c.InputJson)
+				}
+				if len(args) == 0 {
+					args = json.RawMessage("{}")
+				}
+				msg.ToolCalls = append(msg.ToolCalls, ToolCall{
+					ID:       c.ID,
+					Function: FunctionCall{Name: c.Name, Arguments: args},
+				})
+			case chat.ContentTypeToolResult:
+				result = append(result, Message{
+					Role:     "tool",
+					Content:  c.Content,
+					ToolName: names[c.ToolUseID],
+				})
+			default:
+				return nil, fmt.Errorf("ollama: unsupported content type %s", c.Type)
+			}
+		}
+		msg.Content = text.String()
+		if msg.Content == "" && msg.Thinking == "" && len(msg.Images) == 0 &&
+			len(msg.ToolCalls) == 0 {
+			continue
+		}
+		result = append(result, msg)
+	}
+	return result, nil
+}
+
+func ToolsToOllama(tools ...chat.Tool) []Tool {
+	result := make([]Tool, 0, len(tools))
+	for _, tool := range tools {
+		t := Tool{
+			Type: "function",
+			Function: ToolFunction{
+				Name:       tool.Name,
+				Parameters: tool.InputSchema,
+			},
+		}
+		if tool.Description != nil {
+			t.Function.Description = *tool.Description
+		}
+		result = append(result, t)
+	}
+	return result
+}
+
+func ToStopReason(reason string, hasToolCall bool) string {
+	switch {
+	case hasToolCall:
+		return "tool_use"
+	case reason == "length":
+		return "max_tokens"
+	case reason == "stop", reason == "":
+		return "end_turn"
+	}
+	return reason
+}
+
+// newCallID generates an id for tool calls, Ollama doesn't set one
+func newCallID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "call_" + hex.EncodeToString(b)
+}
+
+func ToChatResponse(resp *ChatResponse) *chat.ChatResponse {
+	cm := &chat.ChatResponse{}
+	cm.Model = resp.Model
+	cm.Usage = &chat.ChatUsage{
+		InputTokens:  resp.PromptEvalCount,
+		OutputTokens: resp.EvalCount,
+	}
+
+	c := chat.ChatChoice{Role: "assistant"}
+	if resp.Message.Thinking != "" {
+		c.Content = append(c.Content, chat.NewThinkingContent(resp.Message.Thinking, ""))
+	}
+	if resp.Message.Content != "" {
+		c.Content = append(c.Content, chat.NewTextContent(resp.Message.Content))
+	}
+	for i := range resp.Message.ToolCalls {
+		call := &resp.Message.ToolCalls[i]
+		// Keep the generated id, a stream call ToChatResponse on every chunk
+		if call.ID == "" {
+			call.ID = newCallID()
+		}
+		c.Content = append(c.Content,
+			chat.NewToolUseContent(call.ID, call.Function.Name, call.Function.Arguments))
+	}
+	c.StopReason = ToStopReason(resp.DoneReason, len(resp.Message.ToolCalls) > 0)
+	cm.Choice = append(cm.Choice, c)
+	return cm
+}
@@ -0,0 +1,44 @@
+package ollama
+
+import (
+	"context"
+	"time"
+
+	"github.com/y0ug/llmhaven/http/client"
+	"github.com/y0ug/llmhaven/http/options"
+)
+
+// ModelService lists the models available locally
+type ModelService struct {
+	client *client.BaseClient
+}
+
+func NewModelService(c *client.BaseClient) *ModelService {
+	return &ModelService{client: c}
+}
+
+type Model struct {
+	Name       string       `json:"name"`
+	Model      string       `json:"model"`
+	ModifiedAt time.Time    `json:"modified_at"`
+	Size       int64        `json:"size"`
+	Digest     string       `json:"digest"`
+	Details    ModelDetails `json:"details"`
+}
+
+type ModelDetails struct {
+	Format            string   `json:"format,omitempty"`
+	Family            string   `json:"family,omitempty"`
+	Families          []string `json:"families,omitempty"`
+	ParameterSize     string   `json:"parameter_size,omitempty"`
+	QuantizationLevel string   `json:"quantization_level,omitempty"`
+}
+
+// List calls api/tags
+func (svc *ModelService) List(ctx context.Context, opts ...options.RequestOption) ([]Model, error) {
+	var res struct {
+		Models []Model `json:"models"`
+	}
+	err := svc.client.Get(ctx, "api/tags", nil, &res, opts...)
+	return res.Models, err
+}
@@ -0,0 +1,71 @@
+package ollama
+
+import (
+	"context"
+
+	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/http/options"
+	"github.com/y0ug/llmhaven/http/streaming"
+	"github.com/y0ug/llmhaven/tokenizer"
+)
+
+type Provider struct {
+	client *Client
+}
+
+func New(opts ...options.RequestOption) chat.Provider {
+	return &Provider{
+		client: NewClient(opts...),
+	}
+}
+
+func (p *Provider) Send(
+	ctx context.Context,
+	params chat.ChatParams,
+) (*chat.ChatResponse, error) {
+	req, err := ToChatRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Chat.New(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return ToChatResponse(&resp), nil
+}
+
+func (p *Provider) Stream(
+	ctx context.Context,
+	params chat.ChatParams,
+) (streaming.Streamer[chat.EventStream], error) {
+	req, err := ToChatRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := p.client.Chat.NewStreaming(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return chat.NewProviderEventStream(
+		stream,
+		NewOllamaEventHandler(),
+	), nil
+}
+
+// Models returns the models pulled on the server
+func (p *Provider) Models(ctx context.Context) ([]Model, error) {
+	return p.client.Models.List(ctx)
+}
+
+// CountTokens approximates the count with the o200k tokenizer, Ollama has no
+// endpoint for it and every model uses its own vocabulary
+func (p *Provider) CountTokens(
+	ctx context.Context,
+	params chat.ChatParams,
+) (int64, error) {
+	enc, err := tokenizer.Get(tokenizer.O200kBase)
+	if err != nil {
+		return 0, err
+	}
+	return int64(tokenizer.CountChat(enc, params)), nil
+}
@@ -0,0 +1,170 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y0ug/llmhaven/chat"
+)
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, chat.Provider) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server, New(WithHost(server.URL))
+}
+
+func TestProvider_Send(t *testing.T) {
+	_, provider := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/chat", r.URL.Path)
+		var req ChatRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.False(t, req.Stream)
+		assert.Equal(t, "llama3.2", req.Model)
+		assert.Equal(t, 100, req.Options.NumPredict)
+		if assert.Len(t, req.Messages, 4) {
+			assert.Equal(t, "system", req.Messages[0].Role)
+			assert.Equal(t, []byte("png"), req.Messages[1].Images[0])
+			assert.Equal(t, "get_weather", req.Messages[2].ToolCalls[0].Function.Name)
+			assert.Equal(t, "tool", req.Messages[3].Role)
+			assert.Equal(t, "get_weather", req.Messages[3].ToolName)
+		}
+		assert.Len(t, req.Tools, 1)
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, `{
+			"model": "llama3.2",
+			"message": {"role": "assistant", "content": "", "tool_calls": [
+				{"function": {"name": "get_weather", "arguments": {"location": "Rome"}}}
+			]},
+			"done": true,
+			"done_reason": "stop",
+			"prompt_eval_count": 26,
+			"eval_count": 12
+		}`)
+	})
+
+	params := chat.NewChatParams(
+		chat.WithModel("llama3.2"),
+		chat.WithMaxTokens(100),
+		chat.WithMessages(
+			chat.NewMessage("system", chat.NewTextContent("Be brief")),
+			chat.NewMessage("user",
+				chat.NewTextContent("What is this?"),
+				chat.NewSourceContent("image", "image/png", []byte("png"))),
+			chat.NewMessage("assistant",
+				chat.NewToolUseContent("call_1", "get_weather", json.RawMessage(`{"location":"Paris"}`))),
+			chat.NewMessage("user", chat.NewToolResultContent("call_1", "Sunny")),
+		),
+		chat.WithTools(chat.Tool{Name: "get_weather"}),
+	)
+	resp, err := provider.Send(context.Background(), *params)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, 26, resp.Usage.InputTokens)
+	assert.Equal(t, 12, resp.Usage.OutputTokens)
+	assert.Equal(t, "tool_use", resp.Choice[0].StopReason)
+	call := resp.Choice[0].Content[0]
+	assert.Equal(t, chat.ContentTypeToolUse, call.Type)
+	assert.NotEmpty(t, call.ID)
+	assert.JSONEq(t, `{"location":"Rome"}`, string(call.Input))
+}
+
+func TestProvider_Stream(t *testing.T) {
+	_, provider := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, true, req["stream"])
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprintln(w, `{"model":"llama3.2","message":{"role":"assistant","content":"Hello"},"done":false}`)
+		fmt.Fprintln(w, `{"model":"llama3.2","message":{"role":"assistant","content":" world"},"done":false}`)
+		fmt.Fprintln(w, `{"model":"llama3.2","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":5,"eval_count":2}`)
+	})
+
+	stream, err := provider.Stream(context.Background(), *chat.NewChatParams(
+		chat.WithModel("llama3.2"),
+		chat.WithMessages(chat.NewUserMessage("Hi")),
+	))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer stream.Close()
+
+	var deltas []interface{}
+	var last chat.EventStream
+	for stream.Next() {
+		last = stream.Current()
+		if last.Type == "text_delta" {
+			deltas = append(deltas, last.Delta)
+		}
+	}
+	assert.NoError(t, stream.Err())
+	assert.Equal(t, []interface{}{"Hello", " world"}, deltas)
+	assert.Equal(t, "message_stop", last.Type)
+	assert.Equal(t, "Hello world", last.Message.Choice[0].Content[0].Text)
+	assert.Equal(t, 2, last.Message.Usage.OutputTokens)
+}
+
+func TestProvider_StreamError(t *testing.T) {
+	_, provider := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson; charset=utf-8")
+		fmt.Fprintln(w, `{"error":"model requires more system memory"}`)
+	})
+
+	stream, err := provider.Stream(context.Background(), chat.ChatParams{Model: "llama3.2"})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer stream.Close()
+	for stream.Next() {
+	}
+	assert.ErrorContains(t, stream.Err(), "more system memory")
+}
+
+func TestProvider_Models(t *testing.T) {
+	_, provider := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/api/tags", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, `{"models":[{"name":"llama3.2:latest","model":"llama3.2:latest","size":2019393189,
+			"details":{"family":"llama","parameter_size":"3.2B"}}]}`)
+	})
+
+	models, err := provider.(*Provider).Models(context.Background())
+	if assert.NoError(t, err) && assert.Len(t, models, 1) {
+		assert.Equal(t, "llama3.2:latest", models[0].Name)
+		assert.Equal(t, "3.2B", models[0].Details.ParameterSize)
+	}
+}
+
+func TestToChatRequest_ToolChoice(t *testing.T) {
+	tools := chat.WithTools(chat.Tool{Name: "get_weather"})
+
+	params := chat.NewChatParams(tools, chat.WithToolChoice(chat.ToolChoice{Type: chat.ToolChoiceAuto}))
+	req, err := ToChatRequest(*params)
+	assert.NoError(t, err)
+	assert.Len(t, req.Tools, 1)
+
+	params = chat.NewChatParams(tools, chat.WithToolChoice(chat.ToolChoice{Type: chat.ToolChoiceNone}))
+	req, err = ToChatRequest(*params)
+	assert.NoError(t, err)
+	assert.Empty(t, req.Tools)
+
+	for _, tc := range []chat.ToolChoice{
+		{Type: chat.ToolChoiceRequired},
+		{Type: chat.ToolChoiceTool, Name: "get_weather"},
+	} {
+		params = chat.NewChatParams(tools, chat.WithToolChoice(tc))
+		_, err = ToChatRequest(*params)
+		var unsupported *chat.UnsupportedParamError
+		if assert.ErrorAs(t, err, &unsupported, string(tc.Type)) {
+			assert.Equal(t, "tool_choice", unsupported.Param)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/http/client"
+	"github.com/y0ug/llmhaven/http/errors"
+	"github.com/y0ug/llmhaven/http/options"
+	"github.com/y0ug/llmhaven/http/streaming"
+)
+
+// Batch status
+const (
+	BatchStatusValidating = "validating"
+	BatchStatusFailed     = "failed"
+	BatchStatusInProgress = "in_progress"
+	BatchStatusFinalizing = "finalizing"
+	BatchStatusCompleted  = "completed"
+	BatchStatusExpired    = "expired"
+	BatchStatusCancelling = "cancelling"
+	BatchStatusCancelled  = "cancelled"
+)
+
+// BatchService wraps the batches endpoints, the input is a JSONL file
+// uploaded with the Files service
+type BatchService struct {
+	*client.BaseClient
+	Files *FileService
+}
+
+func NewBatchService(files *FileService, opts ...options.RequestOption) *BatchService {
+	return &BatchService{
+		BaseClient: client.NewBaseClient(NewAPIError, opts...),
+		Files:      files,
+	}
+}
+
+type Batch struct {
+	ID               string            `json:"id"`
+	Object           string            `json:"object"` // always batch
+	Endpoint         string            `json:"endpoint"`
+	Errors           *BatchErrors      `json:"errors,omitempty"`
+	InputFileID      string            `json:"input_file_id"`
+	CompletionWindow string            `json:"completion_window"`
+	Status           string            `json:"status"`
+	OutputFileID     string            `json:"output_file_id,omitempty"`
+	ErrorFileID      string            `json:"error_file_id,omitempty"`
+	CreatedAt        int64             `json:"created_at"`
+	CompletedAt      int64             `json:"completed_at,omitempty"`
+	ExpiresAt        int64             `json:"expires_at,omitempty"`
+	RequestCounts    BatchCounts       `json:"request_counts"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+// Done reports if the batch reached a final status
+func (b *Batch) Done() bool {
+	switch b.Status {
+	case BatchStatusCompleted, BatchStatusFailed, BatchStatusExpired, BatchStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// BatchErrors are the validation errors of the input file
+type BatchErrors struct {
+	Data []struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Line    *int   `json:"line,omitempty"`
+	} `json:"data"`
+}
+
+func (e *BatchErrors) String() string {
+	if e == nil || len(e.Data) == 0 {
+		return "no error details"
+	}
+	var b bytes.Buffer
+	for i, d := range e.Data {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		if d.Line != nil {
+			fmt.Fprintf(&b, "line %d: ", *d.Line)
+		}
+		fmt.Fprintf(&b, "%s: %s", d.Code, d.Message)
+	}
+	return b.String()
+}
+
+type BatchCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+// BatchRequest is a line of the input file
+type BatchRequest struct {
+	CustomID string                  `json:"custom_id"`
+	Method   string                  `json:"method"`
+	URL      string                  `json:"url"`
+	Body     ChatCompletionNewParams `json:"body"`
+}
+
+type BatchNewParams struct {
+	InputFileID      string            `json:"input_file_id"`
+	Endpoint         string            `json:"endpoint"`          // /v1/chat/completions
+	CompletionWindow string            `json:"completion_window"` // only 24h
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+type BatchListParams struct {
+	Limit int
+	After string
+}
+
+type BatchList struct {
+	Data    []Batch `json:"data"`
+	FirstID string  `json:"first_id,omitempty"`
+	LastID  string  `json:"last_id,omitempty"`
+	HasMore bool    `json:"has_more"`
+}
+
+// BatchResult is a line of the output and error files
+type BatchResult struct {
+	ID       string `json:"id"`
+	CustomID string `json:"custom_id"`
+	Response *struct {
+		StatusCode int             `json:"status_code"`
+		RequestID  string          `json:"request_id"`
+		Body       json.RawMessage `json:"body"`
+	} `json:"response,omitempty"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// ToChatResponse decodes the completion of a succeeded request, an error
+// otherwise
+func (r *BatchResult) ToChatResponse() (*chat.ChatResponse, error) {
+	if r.Error != nil {
+		return nil, fmt.Errorf("batch request failed: %s: %s", r.Error.Code, r.Error.Message)
+	}
+	if r.Response == nil {
+		return nil, fmt.Errorf("batch result %s without response", r.CustomID)
+	}
+	if r.Response.StatusCode >= 400 {
+		return nil, fmt.Errorf("batch request failed: %d %s",
+			r.Response.StatusCode, r.Response.Body)
+	}
+	var cc ChatCompletion
+	if err := json.Unmarshal(r.Response.Body, &cc); err != nil {
+		return nil, fmt.Errorf("error decoding batch result %s: %w", r.CustomID, err)
+	}
+	return ToChatResponse(&cc), nil
+}
+
+// NewBatchInput encodes the requests as JSONL
+func NewBatchInput(requests []BatchRequest) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range requests {
+		if err := enc.Encode(r); err != nil {
+			return nil, fmt.Errorf("error encoding request %s: %w", r.CustomID, err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (svc *BatchService) New(
+	ctx context.Context,
+	params BatchNewParams,
+	opts ...options.RequestOption,
+) (res *Batch, err error) {
+	if params.Endpoint == "" {
+		params.Endpoint = "/v1/chat/completions"
+	}
+	if params.CompletionWindow == "" {
+		params.CompletionWindow = "24h"
+	}
+	err = svc.Post(ctx, "batches", params, &res, opts...)
+	return
+}
+
+// NewFromChatCompletions uploads the input file and creates the batch,
+// customIDs[i] is the id of params[i]
+func (svc *BatchService) NewFromChatCompletions(
+	ctx context.Context,
+	customIDs []string,
+	params []ChatCompletionNewParams,
+	opts ...options.RequestOption,
+) (*Batch, error) {
+	if len(customIDs) != len(params) {
+		return nil, fmt.Errorf("got %d custom ids for %d params", len(customIDs), len(params))
+	}
+	requests := make([]BatchRequest, len(params))
+	for i, p := range params {
+		// Batch requests can't be streamed
+		p.Stream, p.StreamOptions = false, nil
+		requests[i] = BatchRequest{
+			CustomID: customIDs[i],
+			Method:   "POST",
+			URL:      "/v1/chat/completions",
+			Body:     p,
+		}
+	}
+	input, err := NewBatchInput(requests)
+	if err != nil {
+		return nil, err
+	}
+	file, err := svc.Files.New(ctx, FileNewParams{
+		Filename:  "batch.jsonl",
+		Purpose:   FilePurposeBatch,
+		MediaType: "application/jsonl",
+		Content:   bytes.NewReader(input),
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error uploading batch input: %w", err)
+	}
+	return svc.New(ctx, BatchNewParams{InputFileID: file.ID}, opts...)
+}
+
+func (svc *BatchService) Retrieve(
+	ctx context.Context,
+	id string,
+	opts ...options.RequestOption,
+) (res *Batch, err error) {
+	err = svc.Get(ctx, "batches/"+url.PathEscape(id), nil, &res, opts...)
+	return
+}
+
+func (svc *BatchService) List(
+	ctx context.Context,
+	params BatchListParams,
+	opts ...options.RequestOption,
+) (res *BatchList, err error) {
+	if params.Limit > 0 {
+		opts = append(opts, options.WithQuery("limit", strconv.Itoa(params.Limit)))
+	}
+	if params.After != "" {
+		opts = append(opts, options.WithQuery("after", params.After))
+	}
+	err = svc.Get(ctx, "batches", nil, &res, opts...)
+	return
+}
+
+func (svc *BatchService) Cancel(
+	ctx context.Context,
+	id string,
+	opts ...options.RequestOption,
+) (res *Batch, err error) {
+	err = svc.Post(ctx, "batches/"+url.PathEscape(id)+"/cancel", nil, &res, opts...)
+	return
+}
+
+// Results streams a JSONL output or error file
+func (svc *BatchService) Results(
+	ctx context.Context,
+	fileID string,
+	opts ...options.RequestOption,
+) (streaming.Streamer[BatchResult], error) {
+	body, err := svc.Files.Content(ctx, fileID, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return streaming.NewStream(
+		streaming.NewDecoderNDJSON(body),
+		streaming.NewGenericStreamHandler[BatchResult](),
+	), nil
+}
+
+// ChatResults reads the output and error files keyed by custom id. When
+// requests failed the responses of the others are returned with an
+// *errors.BatchError.
+func (svc *BatchService) ChatResults(
+	ctx context.Context,
+	batch *Batch,
+	opts ...options.RequestOption,
+) (map[string]*chat.ChatResponse, error) {
+	results := make(map[string]*chat.ChatResponse)
+	failed := make(map[string]error)
+	for _, fileID := range []string{batch.OutputFileID, batch.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+		stream, err := svc.Results(ctx, fileID, opts...)
+		if err != nil {
+			return results, err
+		}
+		for stream.Next() {
+			r := stream.Current()
+			resp, err := r.ToChatResponse()
+			if err != nil {
+				failed[r.CustomID] = err
+				continue
+			}
+			results[r.CustomID] = resp
+		}
+		err = stream.Err()
+		stream.Close()
+		if err != nil {
+			return results, err
+		}
+	}
+	if len(failed) > 0 {
+		return results, &errors.BatchError{Items: failed}
+	}
+	return results, nil
+}
+
+// BatchWaitParams controls the polling of WaitForBatch, the interval
+// doubles after each poll up to MaxInterval
+type BatchWaitParams struct {
+	Interval    time.Duration // 10s by default
+	MaxInterval time.Duration // 5m by default
+}
+
+// WaitForBatch polls the batch until it's done and returns its results.
+// Expired and cancelled batches return the results of the requests
+// completed in time.
+func (svc *BatchService) WaitForBatch(
+	ctx context.Context,
+	id string,
+	params BatchWaitParams,
+	opts ...options.RequestOption,
+) (map[string]*chat.ChatResponse, error) {
+	interval := params.Interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	maxInterval := params.MaxInterval
+	if maxInterval <= 0 {
+		maxInterval = 5 * time.Minute
+	}
+
+	for {
+		batch, err := svc.Retrieve(ctx, id, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if batch.Done() {
+			if batch.Status == BatchStatusFailed {
+				return nil, fmt.Errorf("batch %s failed: %s", id, batch.Errors)
+			}
+			return svc.ChatResults(ctx, batch, opts...)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+		interval = min(interval*2, maxInterval)
+	}
+}
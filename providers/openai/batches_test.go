@@ -0,0 +1,169 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y0ug/llmhaven/batch"
+	llmerrors "github.com/y0ug/llmhaven/http/errors"
+	"github.com/y0ug/llmhaven/http/options"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewCompatibleClient(options.WithBaseURL(srv.URL+"/"), options.WithAuthToken("test"))
+}
+
+func TestFileService(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/files":
+			assert.Equal(t, FilePurposeUserData, r.FormValue("purpose"))
+			f, h, err := r.FormFile("file")
+			if assert.NoError(t, err) {
+				data, _ := io.ReadAll(f)
+				assert.Equal(t, "data", string(data))
+				assert.Equal(t, "a.txt", h.Filename)
+			}
+			w.Write([]byte(`{"id":"file-1","object":"file","bytes":4,"filename":"a.txt","purpose":"user_data"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/files":
+			assert.Equal(t, "batch", r.URL.Query().Get("purpose"))
+			w.Write([]byte(`{"data":[{"id":"file-1"}],"has_more":false}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/files/file-1/content":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte("data"))
+		case r.Method == http.MethodDelete && r.URL.Path == "/files/file-1":
+			w.Write([]byte(`{"id":"file-1","object":"file","deleted":true}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+	ctx := context.Background()
+
+	file, err := c.Files.New(ctx, FileNewParams{
+		Filename: "a.txt",
+		Purpose:  FilePurposeUserData,
+		Content:  strings.NewReader("data"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "file-1", file.ID)
+
+	list, err := c.Files.List(ctx, FileListParams{Purpose: FilePurposeBatch})
+	assert.NoError(t, err)
+	assert.Len(t, list.Data, 1)
+
+	body, err := c.Files.Content(ctx, "file-1")
+	if assert.NoError(t, err) {
+		data, _ := io.ReadAll(body)
+		body.Close()
+		assert.Equal(t, "data", string(data))
+	}
+
+	deleted, err := c.Files.Delete(ctx, "file-1")
+	assert.NoError(t, err)
+	assert.True(t, deleted.Deleted)
+}
+
+func TestBatchService_WaitForBatch(t *testing.T) {
+	var polls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/files":
+			assert.Equal(t, FilePurposeBatch, r.FormValue("purpose"))
+			f, _, err := r.FormFile("file")
+			if assert.NoError(t, err) {
+				data, _ := io.ReadAll(f)
+				lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+				if assert.Len(t, lines, 2) {
+					assert.Contains(t, lines[0], `"custom_id":"a"`)
+					assert.Contains(t, lines[0], `"url":"/v1/chat/completions"`)
+					assert.NotContains(t, lines[0], `"stream"`)
+				}
+			}
+			w.Write([]byte(`{"id":"file-in","purpose":"batch"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/batches":
+			w.Write([]byte(`{"id":"batch_1","status":"validating","input_file_id":"file-in"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/batches/batch_1":
+			if atomic.AddInt32(&polls, 1) < 3 {
+				w.Write([]byte(`{"id":"batch_1","status":"in_progress"}`))
+				return
+			}
+			w.Write([]byte(`{"id":"batch_1","status":"completed",
+				"output_file_id":"file-out","error_file_id":"file-err"}`))
+		case r.URL.Path == "/files/file-out/content":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte(`{"id":"r1","custom_id":"a","response":{"status_code":200,"body":{"id":"chatcmpl-1","model":"gpt-4o-mini","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"A"}}],"usage":{"prompt_tokens":2,"completion_tokens":1}}},"error":null}
+`))
+		case r.URL.Path == "/files/file-err/content":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte(`{"id":"r2","custom_id":"b","response":{"status_code":400,"body":{"error":{"message":"bad"}}},"error":null}
+`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+	ctx := context.Background()
+
+	params := []ChatCompletionNewParams{
+		{Model: "gpt-4o-mini", Stream: true},
+		{Model: "gpt-4o-mini"},
+	}
+	batch, err := c.Batches.NewFromChatCompletions(ctx, []string{"a", "b"}, params)
+	assert.NoError(t, err)
+	assert.Equal(t, "batch_1", batch.ID)
+
+	results, err := c.Batches.WaitForBatch(ctx, batch.ID, BatchWaitParams{
+		Interval: time.Millisecond,
+	})
+	assert.Equal(t, int32(3), polls)
+	var batchErr *llmerrors.BatchError
+	if assert.True(t, errors.As(err, &batchErr)) {
+		assert.Contains(t, batchErr.Items["b"].Error(), "400")
+	}
+	if assert.Contains(t, results, "a") {
+		assert.Equal(t, "A", results["a"].Choice[0].Content[0].Text)
+		assert.Equal(t, 2, results["a"].Usage.InputTokens)
+	}
+}
+
+func TestBatchService_WaitForBatchFailed(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"batch_1","status":"failed",
+			"errors":{"data":[{"code":"invalid_json","message":"bad line","line":2}]}}`))
+	})
+	_, err := c.Batches.WaitForBatch(context.Background(), "batch_1", BatchWaitParams{})
+	assert.ErrorContains(t, err, "line 2: invalid_json: bad line")
+}
+
+func TestProvider_BatchProvider(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		assert.Equal(t, "/batches/batch_1", r.URL.Path)
+		w.Write([]byte(`{"id":"batch_1","status":"finalizing",
+			"request_counts":{"total":3,"completed":2,"failed":1}}`))
+	})
+	p := &Provider{Client: c, Name: "groq"}
+	assert.True(t, p.SupportsBatch())
+
+	job, err := p.Status(context.Background(), "batch_1")
+	assert.NoError(t, err)
+	assert.Equal(t, batch.StatusInProgress, job.Status)
+	assert.Equal(t, "groq", job.Provider)
+	assert.Equal(t, 3, job.Total)
+	assert.Equal(t, 1, job.Failed)
+
+	p.Quirks = NewQuirks(WithNoBatch())
+	assert.False(t, p.SupportsBatch())
+}
@@ -47,7 +47,15 @@ type ChatCompletionChunkChoicesDelta struct {
 	ToolCalls  []ToolCall  `json:"tool_calls,omitempty"`
 	Content    string      `json:"content,omitempty"`
 	ToolCallId string      `json:"tool_call_id,omitempty"`
-	JSON       string      `json:"-"`
+	// Reasoning text returned by DeepSeek and other compatible APIs
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+	JSON             string `json:"-"`
+}
+
+func (r *ChatCompletionChunkChoicesDelta) UnmarshalJSON(data []byte) (err error) {
+	r.JSON = string(data)
+	type Alias ChatCompletionChunkChoicesDelta
+	return json.Unmarshal(data, (*Alias)(r))
 }
 
 func (r *ChatCompletionChoice) UnmarshalJSON(data []byte) (err error) {
@@ -64,7 +72,9 @@ type ChatCompletionMessage struct {
 	ToolCalls  []ToolCall  `json:"tool_calls,omitempty"`
 	Content    string      `json:"content,omitempty"`
 	ToolCallId string      `json:"tool_call_id,omitempty"`
-	JSON       string      `json:"-"`
+	// Reasoning text returned by DeepSeek and other compatible APIs
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+	JSON             string `json:"-"`
 }
 
 func (r *ChatCompletionMessage) UnmarshalJSON(data []byte) (err error) {
@@ -137,6 +147,10 @@ func (cc *ChatCompletion) Accumulate(chunk ChatCompletionChunk) bool {
 	cc.Usage.CompletionTokensDetails.RejectedPredictionTokens += chunk.Usage.CompletionTokensDetails.RejectedPredictionTokens
 	cc.Usage.PromptTokensDetails.AudioTokens += chunk.Usage.PromptTokensDetails.AudioTokens
 	cc.Usage.PromptTokensDetails.CachedTokens += chunk.Usage.PromptTokensDetails.CachedTokens
+	cc.Usage.Cost += chunk.Usage.Cost
+	if len(chunk.Usage.JSON) > 0 {
+		cc.Usage.JSON = chunk.Usage.JSON
+	}
 
 	for _, deltaChoice := range chunk.Choices {
 		cc.Choices = expandToFit(cc.Choices, int(deltaChoice.Index))
@@ -157,6 +171,9 @@ func (cc *ChatCompletion) Accumulate(chunk ChatCompletionChunk) bool {
 		if deltaChoice.Delta.Refusal != "" {
 			choice.Message.Refusal += deltaChoice.Delta.Refusal
 		}
+		if deltaChoice.Delta.ReasoningContent != "" {
+			choice.Message.ReasoningContent += deltaChoice.Delta.ReasoningContent
+		}
 		for _, deltaTool := range deltaChoice.Delta.ToolCalls {
 			choice.Message.ToolCalls = expandToFit(
 				choice.Message.ToolCalls,
@@ -219,12 +236,24 @@ type CompletionUsage struct {
 		AudioTokens              int `json:"audio_tokens"`
 		ReasoningTokens          int `json:"reasoning_tokens"`
 		RejectedPredictionTokens int `json:"rejected_prediction_tokens"`
-	}
+	} `json:"completion_tokens_details"`
 	PromptTokensDetails struct {
 		CachedTokens int `json:"cached_tokens"`
 		AudioTokens  int `json:"audio_tokens"`
 	} `json:"prompt_tokens_details"`
+	// Cost in USD of the request, returned by OpenRouter
 	Cost float64 `json:"cost,omitempty"`
+	// Raw usage object, used to read provider specific fields
+	JSON json.RawMessage `json:"-"`
+}
+
+func (r *CompletionUsage) UnmarshalJSON(data []byte) (err error) {
+	if string(data) == "null" {
+		return nil
+	}
+	r.JSON = append(json.RawMessage(nil), data...)
+	type Alias CompletionUsage
+	return json.Unmarshal(data, (*Alias)(r))
 }
 
 // Creates a model response for the given chat conversation. Learn more in the
@@ -256,22 +285,30 @@ type CompletionUsage struct {
 type ChatCompletionNewParams struct {
 	Model               string `json:"model"`
 	MaxCompletionTokens *int   `json:"max_completion_tokens,omitempty"`
+	MaxTokens           *int   `json:"max_tokens,omitempty"`       // Deprecated by OpenAI, still used by compatible APIs
 	ReasoningEffort     string `json:"reasoning_effort,omitempty"` // low, medium, high
 	// Number between -2.0 and 2.0. Positive values penalize new tokens based on their existing frequency in the text so far, decreasing the model's likelihood to repeat the same line verbatim.
-	FrequencyPenalty *float64     `json:"frequency_penalty,omitempty"`
-	N                *int         `json:"n,omitempty"` // Number of completions to generate for each prompt.
-	ResponseFormat   *interface{} `json:"response_format,omitempty"`
-	Stop             *string      `json:"stop,omitempty"`   // Up to 4 sequences where the API will stop generating further tokens.
-	Stream           bool         `json:"stream,omitempty"` // If true, the API will return a response as soon as it becomes available, even if the completion is not finished.
+	FrequencyPenalty *float64        `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64        `json:"presence_penalty,omitempty"` // Number between -2.0 and 2.0, positive values penalize tokens already present.
+	Seed             *int            `json:"seed,omitempty"`
+	RandomSeed       *int            `json:"random_seed,omitempty"` // Mistral name of seed
+	TopK             *int            `json:"top_k,omitempty"`       // Not accepted by OpenAI
+	LogitBias        map[string]int  `json:"logit_bias,omitempty"`  // Token id to bias from -100 to 100
+	User             string          `json:"user,omitempty"`        // Unique identifier of the end-user
+	N                *int            `json:"n,omitempty"`           // Number of completions to generate for each prompt.
+	ResponseFormat   *ResponseFormat `json:"response_format,omitempty"`
+	Stop             []string        `json:"stop,omitempty"`   // Up to 4 sequences where the API will stop generating further tokens.
+	Stream           bool            `json:"stream,omitempty"` // If true, the API will return a response as soon as it becomes available, even if the completion is not finished.
 	StreamOptions    *struct {
 		IncludeUsage bool `json:"include_usage,omitempty"`
 	} `json:"stream_options,omitempty"`
 	Temperature float64     `json:"temperature,omitempty"` // Number between 0 and 1 that controls randomness of the output.
-	TopP        float64     `json:"top_p,omitempty"`       // Number between 0 and 1 that controls the cumulative probability of the output.
+	TopP        *float64    `json:"top_p,omitempty"`       // Number between 0 and 1 that controls the cumulative probability of the output.
 	Tools       []Tool      `json:"tools,omitempty"`
-	ToolChoice  interface{} `json:"tool_choice,omitempty"` // Auto but can be used to force to used a tools
-	// ParallelToolCalls bool      `json:"parallel_tool_calls"`
-	Messages []ChatCompletionMessageParam `json:"messages"`
+	ToolChoice  interface{} `json:"tool_choice,omitempty"` // auto, none, required or ToolChoiceFunction
+	// Set to false to get at most one tool call per response
+	ParallelToolCalls *bool                        `json:"parallel_tool_calls,omitempty"`
+	Messages          []ChatCompletionMessageParam `json:"messages"`
 }
 
 type ChatCompletionMessageParam struct {
@@ -290,6 +327,54 @@ type ChatCompletionMessageParam struct {
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
 }
 
+// ContentPart of a message, Type is text, image_url, input_audio or file
+type ContentPart struct {
+	Type       string      `json:"type"`
+	Text       string      `json:"text,omitempty"`
+	ImageURL   *ImageURL   `json:"image_url,omitempty"`
+	InputAudio *InputAudio `json:"input_audio,omitempty"`
+	File       *File       `json:"file,omitempty"`
+}
+
+// ImageURL is a remote URL or a data URL
+type ImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"` // auto, low, high
+}
+
+type InputAudio struct {
+	Data   string `json:"data"`   // base64
+	Format string `json:"format"` // wav or mp3
+}
+
+// File is a data URL in FileData with its Filename or a FileID
+type File struct {
+	FileData string `json:"file_data,omitempty"`
+	FileID   string `json:"file_id,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// ResponseFormat type can be text, json_object or json_schema
+type ResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *JSONSchemaFormat `json:"json_schema,omitempty"`
+}
+
+type JSONSchemaFormat struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Schema      interface{} `json:"schema,omitempty"`
+	Strict      bool        `json:"strict,omitempty"`
+}
+
+// ToolChoiceFunction forces the call of a specific function
+type ToolChoiceFunction struct {
+	Type     string `json:"type"` // Always "function"
+	Function struct {
+		Name string `json:"name"`
+	} `json:"function"`
+}
+
 type ToolFunction struct {
 	Name        string      `json:"name"`
 	Description *string     `json:"description,omitempty"`
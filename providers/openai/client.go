@@ -4,17 +4,26 @@ import (
 	"os"
 
 	"github.com/y0ug/llmhaven/http/client"
+	"github.com/y0ug/llmhaven/http/config"
 	"github.com/y0ug/llmhaven/http/options"
 )
 
 type Client struct {
 	*client.BaseClient
-	Chat *ChatCompletionService
+	Chat       *ChatCompletionService
+	Files      *FileService
+	Batches    *BatchService
+	Embeddings *EmbeddingService
+
+	// RateLimit is updated from the headers of every response
+	RateLimit *config.RateLimit
 }
 
 func NewClient(opts ...options.RequestOption) (r *Client) {
+	rl := &config.RateLimit{}
 	defaults := []options.RequestOption{
 		WithEnvironmentProduction(),
+		options.WithRateLimit(rl, config.ParseOpenAIRateLimit),
 	}
 	if o, ok := os.LookupEnv("OPENAI_API_KEY"); ok {
 		defaults = append(defaults, options.WithAuthToken(o))
@@ -27,9 +36,33 @@ func NewClient(opts ...options.RequestOption) (r *Client) {
 	}
 	r = &Client{
 		BaseClient: client.NewBaseClient(NewAPIError, append(defaults, opts...)...),
+		RateLimit:  rl,
+	}
+
+	r.Chat = NewChatCompletionService(r.Options...)
+	r.Files = NewFileService(r.Options...)
+	r.Batches = NewBatchService(r.Files, r.Options...)
+	r.Embeddings = NewEmbeddingService(r.Options...)
+
+	return
+}
+
+// NewCompatibleClient creates a client for an OpenAI compatible API, the
+// OPENAI_* environment variables are not used
+func NewCompatibleClient(opts ...options.RequestOption) (r *Client) {
+	rl := &config.RateLimit{}
+	opts = append([]options.RequestOption{
+		options.WithRateLimit(rl, config.ParseOpenAIRateLimit),
+	}, opts...)
+	r = &Client{
+		BaseClient: client.NewBaseClient(NewAPIError, opts...),
+		RateLimit:  rl,
 	}
 
 	r.Chat = NewChatCompletionService(r.Options...)
+	r.Files = NewFileService(r.Options...)
+	r.Batches = NewBatchService(r.Files, r.Options...)
+	r.Embeddings = NewEmbeddingService(r.Options...)
 
 	return
 }
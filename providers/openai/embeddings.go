@@ -0,0 +1,102 @@
+package openai
+
+import (
+	"context"
+	"sort"
+
+	"github.com/y0ug/llmhaven/embedding"
+	"github.com/y0ug/llmhaven/http/client"
+	"github.com/y0ug/llmhaven/http/options"
+)
+
+// EmbeddingService wraps the embeddings endpoint
+type EmbeddingService struct {
+	*client.BaseClient
+}
+
+func NewEmbeddingService(opts ...options.RequestOption) *EmbeddingService {
+	return &EmbeddingService{
+		BaseClient: client.NewBaseClient(NewAPIError, opts...),
+	}
+}
+
+type EmbeddingNewParams struct {
+	Input          []string `json:"input"`
+	Model          string   `json:"model"`
+	Dimensions     int      `json:"dimensions,omitempty"`      // text-embedding-3 and later
+	EncodingFormat string   `json:"encoding_format,omitempty"` // float or base64
+	User           string   `json:"user,omitempty"`
+}
+
+type Embedding struct {
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+	Object    string    `json:"object"` // always embedding
+}
+
+type EmbeddingResponse struct {
+	Data  []Embedding `json:"data"`
+	Model string      `json:"model"`
+	Usage struct {
+		PromptTokens int `json:"prompt_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+func (svc *EmbeddingService) New(
+	ctx context.Context,
+	params EmbeddingNewParams,
+	opts ...options.RequestOption,
+) (res *EmbeddingResponse, err error) {
+	err = svc.Post(ctx, "embeddings", params, &res, opts...)
+	return
+}
+
+// Max inputs of an embeddings request
+const embeddingBatchSize = 2048
+
+// Embedder implements embedding.Embedder, Model is used when the params
+// don't set one
+type Embedder struct {
+	Client *Client
+	Model  string
+}
+
+func NewEmbedder(opts ...options.RequestOption) embedding.Embedder {
+	return &Embedder{
+		Client: NewClient(opts...),
+		Model:  "text-embedding-3-small",
+	}
+}
+
+func (e *Embedder) Embed(
+	ctx context.Context,
+	inputs []string,
+	opts ...func(*embedding.EmbedParams),
+) ([][]float32, embedding.Usage, error) {
+	params := embedding.NewEmbedParams(append([]func(*embedding.EmbedParams){
+		embedding.WithModel(e.Model), embedding.WithBatchSize(embeddingBatchSize),
+	}, opts...)...)
+
+	return embedding.InBatches(ctx, inputs, params.BatchSize,
+		func(ctx context.Context, batch []string) ([][]float32, embedding.Usage, error) {
+			resp, err := e.Client.Embeddings.New(ctx, EmbeddingNewParams{
+				Input:          batch,
+				Model:          params.Model,
+				Dimensions:     params.Dimensions,
+				EncodingFormat: "float",
+			})
+			if err != nil {
+				return nil, embedding.Usage{}, err
+			}
+			// The data is documented in the input order, sort to be sure
+			sort.Slice(resp.Data, func(i, j int) bool {
+				return resp.Data[i].Index < resp.Data[j].Index
+			})
+			vectors := make([][]float32, len(resp.Data))
+			for i, d := range resp.Data {
+				vectors[i] = d.Embedding
+			}
+			return vectors, embedding.Usage{InputTokens: resp.Usage.PromptTokens}, nil
+		})
+}
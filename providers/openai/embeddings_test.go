@@ -0,0 +1,45 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y0ug/llmhaven/embedding"
+)
+
+func TestEmbedder_Embed(t *testing.T) {
+	var calls int
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/embeddings", r.URL.Path)
+		var req EmbeddingNewParams
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "text-embedding-3-large", req.Model)
+		assert.Equal(t, 256, req.Dimensions)
+		calls++
+
+		// Returned in reverse order, the index gives the position
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":[`)
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			fmt.Fprintf(w, `{"index":%d,"embedding":[%d]}`, i, len(req.Input[i]))
+			if i > 0 {
+				fmt.Fprint(w, ",")
+			}
+		}
+		fmt.Fprintf(w, `],"usage":{"prompt_tokens":%d}}`, len(req.Input))
+	})
+
+	e := &Embedder{Client: c, Model: "text-embedding-3-small"}
+	vectors, usage, err := e.Embed(context.Background(), []string{"a", "bb", "ccc"},
+		embedding.WithModel("text-embedding-3-large"),
+		embedding.WithDimensions(256),
+		embedding.WithBatchSize(2))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 3, usage.InputTokens)
+	assert.Equal(t, [][]float32{{1}, {2}, {3}}, vectors)
+}
@@ -5,6 +5,7 @@ import "github.com/y0ug/llmhaven/chat"
 // OpenAIEventHandler processes OpenAI-specific events
 type OpenAIEventHandler struct {
 	completion ChatCompletion
+	Quirks     Quirks
 }
 
 func NewOpenAIEventHandler() *OpenAIEventHandler {
@@ -19,15 +20,26 @@ func (h *OpenAIEventHandler) ShouldContinue(chunk ChatCompletionChunk) bool {
 func (h *OpenAIEventHandler) HandleEvent(
 	chunk ChatCompletionChunk,
 ) (chat.EventStream, error) {
+	h.Quirks.applyChunk(&chunk)
 	h.completion.Accumulate(chunk)
 	evt := chat.EventStream{Message: ToChatResponse(&h.completion)}
+	if err := h.Quirks.applyUsage(&h.completion, evt.Message); err != nil {
+		return evt, err
+	}
 
 	if chunk.Usage.CompletionTokens != 0 || len(chunk.Choices) == 0 {
 		evt.Type = "message_stop"
 		return evt, nil
 	}
 
+	delta := chunk.Choices[0].Delta
+	if delta.ReasoningContent != "" && delta.Content == "" {
+		evt.Type = "thinking_delta"
+		evt.Delta = delta.ReasoningContent
+		return evt, nil
+	}
+
 	evt.Type = "text_delta"
-	evt.Delta = chunk.Choices[0].Delta.Content
+	evt.Delta = delta.Content
 	return evt, nil
 }
@@ -0,0 +1,172 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/y0ug/llmhaven/http/client"
+	"github.com/y0ug/llmhaven/http/options"
+)
+
+// File purpose
+const (
+	FilePurposeBatch      = "batch"
+	FilePurposeUserData   = "user_data"
+	FilePurposeVision     = "vision"
+	FilePurposeAssistants = "assistants"
+	FilePurposeFineTune   = "fine-tune"
+)
+
+// FileService wraps the files endpoints
+type FileService struct {
+	*client.BaseClient
+}
+
+func NewFileService(opts ...options.RequestOption) *FileService {
+	return &FileService{
+		BaseClient: client.NewBaseClient(NewAPIError, opts...),
+	}
+}
+
+type FileObject struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"` // always file
+	Bytes     int64  `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	ExpiresAt int64  `json:"expires_at,omitempty"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+}
+
+type FileNewParams struct {
+	Filename  string
+	Purpose   string
+	MediaType string // detected from the filename when empty
+	Content   io.Reader
+}
+
+type FileListParams struct {
+	Purpose string
+	Limit   int
+	After   string
+	Order   string // asc or desc
+}
+
+type FileList struct {
+	Data    []FileObject `json:"data"`
+	FirstID string       `json:"first_id,omitempty"`
+	LastID  string       `json:"last_id,omitempty"`
+	HasMore bool         `json:"has_more"`
+}
+
+type FileDeleted struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Deleted bool   `json:"deleted"`
+}
+
+// New uploads a file
+func (svc *FileService) New(
+	ctx context.Context,
+	params FileNewParams,
+	opts ...options.RequestOption,
+) (res *FileObject, err error) {
+	mediaType := params.MediaType
+	if mediaType == "" {
+		mediaType = mime.TypeByExtension(filepath.Ext(params.Filename))
+	}
+	body, contentType, err := client.NewMultipartBody(
+		map[string]string{"purpose": params.Purpose},
+		client.MultipartFile{
+			FieldName:   "file",
+			Filename:    params.Filename,
+			ContentType: mediaType,
+			Content:     params.Content,
+		})
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, options.WithRequestBody(contentType, body))
+	err = svc.Post(ctx, "files", nil, &res, opts...)
+	return
+}
+
+// Upload uploads the file at path
+func (svc *FileService) Upload(
+	ctx context.Context,
+	path string,
+	purpose string,
+	opts ...options.RequestOption,
+) (*FileObject, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return svc.New(ctx, FileNewParams{
+		Filename: filepath.Base(path),
+		Purpose:  purpose,
+		Content:  f,
+	}, opts...)
+}
+
+func (svc *FileService) List(
+	ctx context.Context,
+	params FileListParams,
+	opts ...options.RequestOption,
+) (res *FileList, err error) {
+	if params.Purpose != "" {
+		opts = append(opts, options.WithQuery("purpose", params.Purpose))
+	}
+	if params.Limit > 0 {
+		opts = append(opts, options.WithQuery("limit", strconv.Itoa(params.Limit)))
+	}
+	if params.After != "" {
+		opts = append(opts, options.WithQuery("after", params.After))
+	}
+	if params.Order != "" {
+		opts = append(opts, options.WithQuery("order", params.Order))
+	}
+	err = svc.Get(ctx, "files", nil, &res, opts...)
+	return
+}
+
+func (svc *FileService) Retrieve(
+	ctx context.Context,
+	id string,
+	opts ...options.RequestOption,
+) (res *FileObject, err error) {
+	err = svc.Get(ctx, "files/"+url.PathEscape(id), nil, &res, opts...)
+	return
+}
+
+// Content returns the file content, the caller must close it
+func (svc *FileService) Content(
+	ctx context.Context,
+	id string,
+	opts ...options.RequestOption,
+) (io.ReadCloser, error) {
+	var raw *http.Response
+	opts = append(opts, options.WithHeader("Accept", "*/*"))
+	err := svc.Get(ctx, "files/"+url.PathEscape(id)+"/content", nil, &raw, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading file %s: %w", id, err)
+	}
+	return raw.Body, nil
+}
+
+func (svc *FileService) Delete(
+	ctx context.Context,
+	id string,
+	opts ...options.RequestOption,
+) (res *FileDeleted, err error) {
+	err = svc.BaseClient.Delete(ctx, "files/"+url.PathEscape(id), nil, &res, opts...)
+	return
+}
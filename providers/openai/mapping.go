@@ -1,48 +1,156 @@
 package openai
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"mime"
 
 	"github.com/y0ug/llmhaven/chat"
 )
 
+// MessageToOpenAI converts the messages, every content of a message is
+// mapped: tool_use to tool_calls, tool_result to tool messages and the
+// others to content parts. A single text is sent as a string.
 func MessageToOpenAI(
 	m ...*chat.ChatMessage,
-) []ChatCompletionMessageParam {
+) ([]ChatCompletionMessageParam, error) {
 	userMessages := make([]ChatCompletionMessageParam, 0)
-	ignored := map[int]bool{}
 
-	for i, msg := range m {
-		// for _, content := range msg.Content {
-		content := msg.Content[0]
-		if ignored[i] {
-			continue
-		}
-
-		switch content.Type {
-		case chat.ContentTypeToolUse:
-			// For toolCalls we need to process all of them in one time
-			userMessages = append(userMessages, ChatCompletionMessageParam{
-				Role:      "assistant",
-				ToolCalls: MessageContentToToolCall(msg.Content...),
-			})
-
-		case chat.ContentTypeToolResult:
-			for _, c := range msg.Content {
+	for _, msg := range m {
+		parts := make([]ContentPart, 0)
+		calls := make([]*chat.MessageContent, 0)
+		for _, content := range msg.Content {
+			switch content.Type {
+			case chat.ContentTypeThinking, chat.ContentTypeRedactedThinking:
+				// OpenAI doesn't accept them back
+			case chat.ContentTypeToolUse:
+				calls = append(calls, content)
+			case chat.ContentTypeToolResult:
 				userMessages = append(userMessages, ChatCompletionMessageParam{
 					Role:       "tool",
-					Content:    c.Content,
-					ToolCallID: c.ToolUseID,
+					Content:    content.Content,
+					ToolCallID: content.ToolUseID,
 				})
+			default:
+				part, err := MessageContentToPart(content)
+				if err != nil {
+					return nil, err
+				}
+				parts = append(parts, part)
 			}
-		default:
-			userMessages = append(userMessages, ChatCompletionMessageParam{
-				Role:    msg.Role,
-				Content: content.String(),
-			})
 		}
+
+		if len(parts) == 0 && len(calls) == 0 {
+			continue
+		}
+		param := ChatCompletionMessageParam{Role: msg.Role}
+		if len(calls) > 0 {
+			// For toolCalls we need to process all of them in one time
+			param.Role = "assistant"
+			param.ToolCalls = MessageContentToToolCall(calls...)
+		} else if msg.Role == "tool" {
+			// Content sent with the tool results
+			param.Role = "user"
+		}
+		if len(parts) == 1 && parts[0].Type == "text" {
+			param.Content = parts[0].Text
+		} else if len(parts) > 0 {
+			param.Content = parts
+		}
+		userMessages = append(userMessages, param)
+	}
+	return userMessages, nil
+}
+
+// MessageContentToPart converts a content to an OpenAI content part
+func MessageContentToPart(content *chat.MessageContent) (ContentPart, error) {
+	switch content.Type {
+	case chat.ContentTypeText:
+		return ContentPart{Type: "text", Text: content.Text}, nil
+	case chat.ContentTypeImage:
+		if content.Source == nil {
+			return ContentPart{}, fmt.Errorf("openai: image content without source")
+		}
+		switch content.Source.Type {
+		case chat.SourceTypeURL:
+			return ContentPart{
+				Type:     "image_url",
+				ImageURL: &ImageURL{URL: content.Source.URL},
+			}, nil
+		case chat.SourceTypeFile:
+			// file parts are only accepted for documents
+			return ContentPart{}, fmt.Errorf(
+				"openai: image from file id is not supported, send it as url or base64")
+		}
+		return ContentPart{
+			Type:     "image_url",
+			ImageURL: &ImageURL{URL: dataURL(content.Source)},
+		}, nil
+	case chat.ContentTypeDocument:
+		if content.Source == nil {
+			return ContentPart{}, fmt.Errorf("openai: document content without source")
+		}
+		switch content.Source.Type {
+		case chat.SourceTypeURL:
+			return ContentPart{}, fmt.Errorf("openai: document from url is not supported, upload it")
+		case chat.SourceTypeFile:
+			return ContentPart{
+				Type: "file",
+				File: &File{FileID: content.Source.FileID},
+			}, nil
+		}
+		return ContentPart{
+			Type: "file",
+			File: &File{
+				FileData: dataURL(content.Source),
+				Filename: filename(content.Source.MediaType),
+			},
+		}, nil
+	case chat.ContentTypeInputAudio:
+		if content.Source == nil {
+			return ContentPart{}, fmt.Errorf("openai: audio content without source")
+		}
+		if content.Source.Type != chat.SourceTypeBase64 {
+			return ContentPart{}, fmt.Errorf("openai: audio must be sent as base64")
+		}
+		format, ok := audioFormats[content.Source.MediaType]
+		if !ok {
+			return ContentPart{}, fmt.Errorf(
+				"openai: unsupported audio format %s", content.Source.MediaType)
+		}
+		return ContentPart{
+			Type: "input_audio",
+			InputAudio: &InputAudio{
+				Data:   base64.StdEncoding.EncodeToString(content.Source.Data),
+				Format: format,
+			},
+		}, nil
 	}
-	return userMessages
+	return ContentPart{}, fmt.Errorf("openai: unsupported content type %s", content.Type)
+}
+
+var audioFormats = map[string]string{
+	"audio/wav":   "wav",
+	"audio/x-wav": "wav",
+	"audio/wave":  "wav",
+	"audio/mpeg":  "mp3",
+	"audio/mp3":   "mp3",
+}
+
+func dataURL(src *chat.AIContentSrc) string {
+	return "data:" + src.MediaType + ";base64," + base64.StdEncoding.EncodeToString(src.Data)
+}
+
+// filename is required with file_data, only the extension matters
+func filename(mediaType string) string {
+	if mediaType == "application/pdf" {
+		return "document.pdf"
+	}
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil && len(exts) > 0 {
+		return "document" + exts[0]
+	}
+	return "document"
 }
 
 func ToolCallToMessageContent(t ToolCall) *chat.MessageContent {
@@ -121,9 +229,16 @@ func ToChatResponse(cc *ChatCompletion) *chat.ChatResponse {
 	cm.Usage.InputCachedTokens = cc.Usage.PromptTokensDetails.CachedTokens
 	cm.Usage.InputAudioTokens = cc.Usage.PromptTokensDetails.AudioTokens
 	cm.Usage.OutputAudioTokens = cc.Usage.CompletionTokensDetails.AudioTokens
+	if cc.Usage.Cost > 0 {
+		cm.Cost = &chat.Cost{Total: cc.Usage.Cost}
+	}
 
 	for _, choice := range cc.Choices {
 		c := chat.ChatChoice{}
+		if choice.Message.ReasoningContent != "" {
+			c.Content = append(c.Content,
+				chat.NewThinkingContent(choice.Message.ReasoningContent, ""))
+		}
 		for _, call := range choice.Message.ToolCalls {
 			c.Content = append(
 				c.Content,
@@ -150,15 +265,107 @@ func ToChatResponse(cc *ChatCompletion) *chat.ChatResponse {
 	return cm
 }
 
+func ResponseFormatToOpenAI(format *chat.ResponseFormat) *ResponseFormat {
+	if format == nil {
+		return nil
+	}
+	return &ResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &JSONSchemaFormat{
+			Name:        format.Name,
+			Description: format.Description,
+			Schema:      format.Schema,
+			Strict:      format.Strict,
+		},
+	}
+}
+
+// ToolChoiceToOpenAI returns the tool_choice and parallel_tool_calls values
+func ToolChoiceToOpenAI(choice *chat.ToolChoice) (interface{}, *bool) {
+	if choice == nil {
+		return nil, nil
+	}
+	var parallel *bool
+	if choice.DisableParallel {
+		parallel = new(bool)
+	}
+	switch choice.Type {
+	case chat.ToolChoiceNone:
+		return "none", parallel
+	case chat.ToolChoiceRequired:
+		return "required", parallel
+	case chat.ToolChoiceTool:
+		tc := ToolChoiceFunction{Type: "function"}
+		tc.Function.Name = choice.Name
+		return tc, parallel
+	case chat.ToolChoiceAuto:
+		return "auto", parallel
+	default:
+		return nil, parallel
+	}
+}
+
+// ReasoningEffortToOpenAI returns the reasoning_effort, a budget without
+// effort is converted to the closest level
+func ReasoningEffortToOpenAI(reasoning *chat.Reasoning) string {
+	switch {
+	case reasoning == nil:
+		return ""
+	case reasoning.Effort != "":
+		return reasoning.Effort
+	case reasoning.BudgetTokens <= 0:
+		return ""
+	case reasoning.BudgetTokens <= 2048:
+		return chat.ReasoningEffortLow
+	case reasoning.BudgetTokens <= 8192:
+		return chat.ReasoningEffortMedium
+	default:
+		return chat.ReasoningEffortHigh
+	}
+}
+
+// ToChatCompletionNewParams maps params for the OpenAI API, the compatible
+// APIs use Provider with their Quirks
 func ToChatCompletionNewParams(
 	params chat.ChatParams,
-) ChatCompletionNewParams {
-	return ChatCompletionNewParams{
+) (ChatCompletionNewParams, error) {
+	p, err := toChatCompletionNewParams(params)
+	if err != nil {
+		return p, err
+	}
+	return p, Quirks{}.checkParams("openai", params)
+}
+
+func toChatCompletionNewParams(
+	params chat.ChatParams,
+) (ChatCompletionNewParams, error) {
+	messages, err := MessageToOpenAI(params.Messages...)
+	if err != nil {
+		return ChatCompletionNewParams{}, err
+	}
+	p := ChatCompletionNewParams{
 		Model:               params.Model,
 		MaxCompletionTokens: &params.MaxTokens,
 		Temperature:         params.Temperature,
 		N:                   params.N,
-		Messages:            MessageToOpenAI(params.Messages...),
+		Messages:            messages,
 		Tools:               ToolsToOpenAI(params.Tools...),
+		ResponseFormat:      ResponseFormatToOpenAI(params.ResponseFormat),
+
+		TopP:             params.TopP,
+		Stop:             params.StopSequences,
+		Seed:             params.Seed,
+		PresencePenalty:  params.PresencePenalty,
+		FrequencyPenalty: params.FrequencyPenalty,
+		LogitBias:        params.LogitBias,
+		User:             params.User,
+		ReasoningEffort:  ReasoningEffortToOpenAI(params.Reasoning),
+		TopK:             params.TopK,
+	}
+
+	// tool_choice is rejected when no tools are provided
+	if len(p.Tools) > 0 {
+		p.ToolChoice, p.ParallelToolCalls = ToolChoiceToOpenAI(params.ToolChoice)
 	}
+	return p, nil
 }
@@ -4,46 +4,115 @@ import (
 	"context"
 
 	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/http/config"
 	"github.com/y0ug/llmhaven/http/options"
 	"github.com/y0ug/llmhaven/http/streaming"
+	"github.com/y0ug/llmhaven/tokenizer"
 )
 
 type Provider struct {
 	Client *Client
+	Name   string
+	Quirks Quirks
 }
 
 func New(opts ...options.RequestOption) chat.Provider {
 	return &Provider{
 		Client: NewClient(opts...),
+		Name:   "openai",
 	}
 }
 
+// NewCompatible creates a provider for an OpenAI compatible API, opts must
+// set the base URL and the authentication
+func NewCompatible(name string, quirks []Quirk, opts ...options.RequestOption) *Provider {
+	return &Provider{
+		Client: NewCompatibleClient(opts...),
+		Name:   name,
+		Quirks: NewQuirks(quirks...),
+	}
+}
+
+// chatParams maps params with the quirks of the API, a param it doesn't
+// accept is an error
+func (a *Provider) chatParams(params chat.ChatParams) (ChatCompletionNewParams, error) {
+	p, err := toChatCompletionNewParams(params)
+	if err != nil {
+		return p, err
+	}
+	name := a.Name
+	if name == "" {
+		name = "openai"
+	}
+	if err := a.Quirks.checkParams(name, params); err != nil {
+		return p, err
+	}
+	a.Quirks.applyParams(&p)
+	return p, nil
+}
+
 func (a *Provider) Send(
 	ctx context.Context,
 	params chat.ChatParams,
 ) (*chat.ChatResponse, error) {
-	paramsProvider := ToChatCompletionNewParams(params)
+	paramsProvider, err := a.chatParams(params)
+	if err != nil {
+		return nil, err
+	}
 
 	resp, err := a.Client.Chat.New(ctx, paramsProvider)
 	if err != nil {
 		return nil, err
 	}
+	a.Quirks.applyCompletion(&resp)
 
-	return ToChatResponse(&resp), nil
+	ret := ToChatResponse(&resp)
+	if err := a.Quirks.applyUsage(&resp, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
 }
 
 func (a *Provider) Stream(
 	ctx context.Context,
 	params chat.ChatParams,
 ) (streaming.Streamer[chat.EventStream], error) {
-	paramsProvider := ToChatCompletionNewParams(params)
+	paramsProvider, err := a.chatParams(params)
+	if err != nil {
+		return nil, err
+	}
 
-	stream, err := a.Client.Chat.NewStreaming(ctx, paramsProvider)
+	stream, err := a.Client.Chat.NewStreaming(ctx, paramsProvider, a.Quirks.streamOptions()...)
 	if err != nil {
 		return nil, err
 	}
+	handler := NewOpenAIEventHandler()
+	handler.Quirks = a.Quirks
 	return chat.NewProviderEventStream(
 		stream,
-		NewOpenAIEventHandler(),
+		handler,
 	), nil
 }
+
+// CountTokens counts offline with the tokenizer of the model and its
+// embedded encoding. Compatible APIs use another vocabulary, the count is
+// then an approximation.
+func (a *Provider) CountTokens(
+	ctx context.Context,
+	params chat.ChatParams,
+) (int64, error) {
+	enc, err := tokenizer.Get(tokenizer.ForModel(params.Model))
+	if err != nil {
+		return 0, err
+	}
+	return int64(tokenizer.CountChat(enc, params)), nil
+}
+
+// RateLimits returns the rate limits of the last response, they are empty
+// when the client was not created by NewClient or NewCompatibleClient
+func (a *Provider) RateLimits() config.RateLimit {
+	if a.Client == nil || a.Client.RateLimit == nil {
+		return config.RateLimit{}
+	}
+	return a.Client.RateLimit.GetSnapshot()
+}
@@ -0,0 +1,101 @@
+package openai
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"sort"
+
+	"github.com/y0ug/llmhaven/batch"
+	"github.com/y0ug/llmhaven/http/errors"
+)
+
+// Provider implements batch.BatchProvider with the Batch API, compatible
+// APIs without it have the NoBatch quirk
+
+func (a *Provider) SupportsBatch() bool {
+	return !a.Quirks.NoBatch
+}
+
+func (a *Provider) Submit(ctx context.Context, requests []batch.Request) (*batch.Job, error) {
+	ids := make([]string, len(requests))
+	params := make([]ChatCompletionNewParams, len(requests))
+	for i, r := range requests {
+		p, err := a.chatParams(r.Params)
+		if err != nil {
+			return nil, fmt.Errorf("request %s: %w", r.CustomID, err)
+		}
+		ids[i], params[i] = r.CustomID, p
+	}
+	b, err := a.Client.Batches.NewFromChatCompletions(ctx, ids, params)
+	if err != nil {
+		return nil, err
+	}
+	return a.toBatchJob(b), nil
+}
+
+func (a *Provider) Status(ctx context.Context, id string) (*batch.Job, error) {
+	b, err := a.Client.Batches.Retrieve(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return a.toBatchJob(b), nil
+}
+
+func (a *Provider) Results(ctx context.Context, id string) ([]batch.Result, error) {
+	b, err := a.Client.Batches.Retrieve(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := a.Client.Batches.ChatResults(ctx, b)
+	var batchErr *errors.BatchError
+	if err != nil && !stderrors.As(err, &batchErr) {
+		return nil, err
+	}
+	results := make([]batch.Result, 0, len(responses))
+	for id, resp := range responses {
+		results = append(results, batch.Result{CustomID: id, Response: resp})
+	}
+	if batchErr != nil {
+		for id, err := range batchErr.Items {
+			results = append(results, batch.Result{CustomID: id, Error: err.Error()})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].CustomID < results[j].CustomID
+	})
+	return results, nil
+}
+
+func (a *Provider) Cancel(ctx context.Context, id string) (*batch.Job, error) {
+	b, err := a.Client.Batches.Cancel(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return a.toBatchJob(b), nil
+}
+
+func (a *Provider) toBatchJob(b *Batch) *batch.Job {
+	job := &batch.Job{
+		ID:        b.ID,
+		Provider:  a.Name,
+		Total:     b.RequestCounts.Total,
+		Completed: b.RequestCounts.Completed,
+		Failed:    b.RequestCounts.Failed,
+	}
+	switch b.Status {
+	case BatchStatusCompleted:
+		job.Status = batch.StatusCompleted
+	case BatchStatusFailed:
+		job.Status = batch.StatusFailed
+	case BatchStatusExpired:
+		job.Status = batch.StatusExpired
+	case BatchStatusCancelling:
+		job.Status = batch.StatusCanceling
+	case BatchStatusCancelled:
+		job.Status = batch.StatusCanceled
+	default:
+		job.Status = batch.StatusInProgress
+	}
+	return job
+}
@@ -2,11 +2,22 @@ package openai
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/http/config"
+	"github.com/y0ug/llmhaven/http/options"
+	"github.com/y0ug/llmhaven/tokenizer"
 )
 
 func TestFromLLMMessageToOpenAi(t *testing.T) {
@@ -58,12 +69,77 @@ func TestFromLLMMessageToOpenAi(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := MessageToOpenAI(tt.messages...)
+			got, err := MessageToOpenAI(tt.messages...)
+			assert.NoError(t, err)
 			assert.Equal(t, tt.want, got)
 		})
 	}
 }
 
+func TestMessageToOpenAI_Multimodal(t *testing.T) {
+	msgs, err := MessageToOpenAI(
+		chat.NewMessage("user",
+			chat.NewTextContent("Describe"),
+			chat.NewTextContent("both files"),
+			chat.NewSourceContent("image", "image/png", []byte("png")),
+			chat.NewSourceContent("document", "application/pdf", []byte("pdf")),
+			&chat.MessageContent{
+				Type:   chat.ContentTypeInputAudio,
+				Source: &chat.AIContentSrc{Type: "base64", MediaType: "audio/wav", Data: []byte("wav")},
+			},
+		),
+		chat.NewMessage("assistant",
+			chat.NewTextContent("Let me check"),
+			chat.NewToolUseContent("call_1", "lookup", json.RawMessage(`{}`)),
+		),
+		chat.NewMessage("user",
+			chat.NewToolResultContent("call_1", "found"),
+			chat.NewTextContent("Go on"),
+		),
+	)
+	if !assert.NoError(t, err) || !assert.Len(t, msgs, 4) {
+		t.FailNow()
+	}
+
+	assert.Equal(t, []ContentPart{
+		{Type: "text", Text: "Describe"},
+		{Type: "text", Text: "both files"},
+		{Type: "image_url", ImageURL: &ImageURL{URL: "data:image/png;base64,cG5n"}},
+		{Type: "file", File: &File{FileData: "data:application/pdf;base64,cGRm", Filename: "document.pdf"}},
+		{Type: "input_audio", InputAudio: &InputAudio{Data: "d2F2", Format: "wav"}},
+	}, msgs[0].Content)
+
+	assert.Equal(t, "assistant", msgs[1].Role)
+	assert.Equal(t, "Let me check", msgs[1].Content)
+	assert.Len(t, msgs[1].ToolCalls, 1)
+
+	assert.Equal(t, "tool", msgs[2].Role)
+	assert.Equal(t, "call_1", msgs[2].ToolCallID)
+	assert.Equal(t, "user", msgs[3].Role)
+	assert.Equal(t, "Go on", msgs[3].Content)
+
+	msgs, err = MessageToOpenAI(chat.NewMessage("user",
+		chat.NewImageFromURL("https://example.com/cat.png"),
+		chat.NewDocumentFromFileID("file-abc"),
+	))
+	if assert.NoError(t, err) {
+		assert.Equal(t, []ContentPart{
+			{Type: "image_url", ImageURL: &ImageURL{URL: "https://example.com/cat.png"}},
+			{Type: "file", File: &File{FileID: "file-abc"}},
+		}, msgs[0].Content)
+	}
+
+	_, err = MessageToOpenAI(chat.NewMessage("user",
+		chat.NewDocumentFromURL("https://example.com/doc.pdf")))
+	assert.Error(t, err)
+
+	_, err = MessageToOpenAI(chat.NewMessage("user", chat.NewImageFromFileID("file-abc")))
+	assert.ErrorContains(t, err, "url or base64")
+
+	_, err = MessageToOpenAI(chat.NewMessage("user", &chat.MessageContent{Type: "video"}))
+	assert.ErrorContains(t, err, "unsupported content type video")
+}
+
 func TestOpenAIProvider_Send(t *testing.T) {
 	// Create a new adapter with a mock client
 	adapter := New()
@@ -98,3 +174,337 @@ func TestOpenAIProvider_Send(t *testing.T) {
 	assert.Greater(t, response.Usage.InputTokens, 0)
 	assert.Greater(t, response.Usage.OutputTokens, 0)
 }
+
+func TestToChatCompletionNewParams_ResponseFormat(t *testing.T) {
+	type answer struct {
+		Value string `json:"value"`
+	}
+	params := chat.NewChatParams(chat.WithResponseSchema[answer]())
+
+	got, err := ToChatCompletionNewParams(*params)
+	assert.NoError(t, err)
+	if assert.NotNil(t, got.ResponseFormat) {
+		assert.Equal(t, "json_schema", got.ResponseFormat.Type)
+		assert.Equal(t, "answer", got.ResponseFormat.JSONSchema.Name)
+		assert.Equal(t, params.ResponseFormat.Schema, got.ResponseFormat.JSONSchema.Schema)
+	}
+}
+
+func TestToChatCompletionNewParams_ToolChoice(t *testing.T) {
+	forced := ToolChoiceFunction{Type: "function"}
+	forced.Function.Name = "get_weather"
+	disabled := false
+
+	tests := []struct {
+		name         string
+		choice       *chat.ToolChoice
+		want         interface{}
+		wantParallel *bool
+	}{
+		{name: "unset", choice: nil, want: nil},
+		{name: "auto", choice: &chat.ToolChoice{Type: chat.ToolChoiceAuto}, want: "auto"},
+		{name: "none", choice: &chat.ToolChoice{Type: chat.ToolChoiceNone}, want: "none"},
+		{
+			name:   "required",
+			choice: &chat.ToolChoice{Type: chat.ToolChoiceRequired},
+			want:   "required",
+		},
+		{
+			name:   "tool",
+			choice: &chat.ToolChoice{Type: chat.ToolChoiceTool, Name: "get_weather"},
+			want:   forced,
+		},
+		{
+			name:         "disable parallel",
+			choice:       &chat.ToolChoice{Type: chat.ToolChoiceAuto, DisableParallel: true},
+			want:         "auto",
+			wantParallel: &disabled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := chat.ChatParams{
+				Tools:      []chat.Tool{{Name: "get_weather"}},
+				ToolChoice: tt.choice,
+			}
+			got, err := ToChatCompletionNewParams(params)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.ToolChoice)
+			assert.Equal(t, tt.wantParallel, got.ParallelToolCalls)
+		})
+	}
+
+	t.Run("no tools", func(t *testing.T) {
+		params := chat.NewChatParams(chat.WithToolChoiceTool("get_weather"))
+		got, err := ToChatCompletionNewParams(*params)
+		assert.NoError(t, err)
+		assert.Nil(t, got.ToolChoice)
+	})
+}
+
+func TestToChatCompletionNewParams_Sampling(t *testing.T) {
+	params := chat.NewChatParams(
+		chat.WithTopP(0.9),
+		chat.WithStopSequences("END", "STOP"),
+		chat.WithSeed(42),
+		chat.WithPresencePenalty(0.5),
+		chat.WithFrequencyPenalty(-0.5),
+		chat.WithLogitBias(map[string]int{"50256": -100}),
+		chat.WithUser("user-1"),
+	)
+	got, err := ToChatCompletionNewParams(*params)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.9, *got.TopP)
+	assert.Equal(t, []string{"END", "STOP"}, got.Stop)
+	assert.Equal(t, 42, *got.Seed)
+	assert.Equal(t, 0.5, *got.PresencePenalty)
+	assert.Equal(t, -0.5, *got.FrequencyPenalty)
+	assert.Equal(t, map[string]int{"50256": -100}, got.LogitBias)
+	assert.Equal(t, "user-1", got.User)
+
+	params.Update(chat.WithTopK(40))
+	_, err = ToChatCompletionNewParams(*params)
+	assert.ErrorIs(t, err, chat.ErrUnsupportedParam)
+	assert.Contains(t, err.Error(), "top_k")
+}
+
+func TestToChatCompletionNewParams_Reasoning(t *testing.T) {
+	tests := []struct {
+		reasoning *chat.Reasoning
+		want      string
+	}{
+		{nil, ""},
+		{&chat.Reasoning{Effort: "high"}, "high"},
+		{&chat.Reasoning{BudgetTokens: 1024}, "low"},
+		{&chat.Reasoning{BudgetTokens: 4096}, "medium"},
+		{&chat.Reasoning{BudgetTokens: 32000}, "high"},
+	}
+	for _, tt := range tests {
+		got, err := ToChatCompletionNewParams(chat.ChatParams{Reasoning: tt.reasoning})
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, got.ReasoningEffort)
+	}
+}
+
+func TestToChatResponse_ReasoningContent(t *testing.T) {
+	cc := &ChatCompletion{
+		Choices: []ChatCompletionChoice{
+			{Message: ChatCompletionMessage{
+				Role:             "assistant",
+				Content:          "42",
+				ReasoningContent: "Let me think",
+			}},
+		},
+	}
+	resp := ToChatResponse(cc)
+	content := resp.Choice[0].Content
+	if assert.Len(t, content, 2) {
+		assert.Equal(t, chat.ContentTypeThinking, content[0].Type)
+		assert.Equal(t, "Let me think", content[0].Thinking)
+		assert.Equal(t, "42", content[1].Text)
+	}
+
+	// Thinking is dropped when the message is sent back
+	msgs, err := MessageToOpenAI(resp.ToMessageParams())
+	assert.NoError(t, err)
+	if assert.Len(t, msgs, 1) {
+		assert.Equal(t, "42", msgs[0].Content)
+	}
+}
+
+func TestProvider_Quirks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+		var req map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, float64(100), req["max_tokens"])
+		assert.NotContains(t, req, "max_completion_tokens")
+
+		if req["stream"] == true {
+			assert.NotContains(t, req, "stream_options")
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"id\":\"1\",\"choices\":[{\"delta\":{\"role\":\"assistant\",\"reasoning\":\"Hmm\"}}]}\n\n")
+			fmt.Fprint(w, "data: {\"id\":\"1\",\"choices\":[{\"delta\":{\"content\":\"42\"}}]}\n\n")
+			fmt.Fprint(w, "data: {\"id\":\"1\",\"choices\":[],\"usage\":{\"prompt_tokens\":10,\"completion_tokens\":2,\"cache_hit\":4}}\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"1","model":"m","choices":[{"message":{"role":"assistant","content":"42","reasoning":"Hmm"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":10,"completion_tokens":2,"cache_hit":4}}`)
+	}))
+	defer server.Close()
+
+	parseUsage := func(raw json.RawMessage, usage *chat.ChatUsage) error {
+		var u struct {
+			CacheHit int `json:"cache_hit"`
+		}
+		if err := json.Unmarshal(raw, &u); err != nil {
+			return err
+		}
+		usage.InputCachedTokens = u.CacheHit
+		return nil
+	}
+	provider := NewCompatible("test",
+		[]Quirk{
+			WithMaxTokensField(),
+			WithNoStreamOptions(),
+			WithReasoningField("reasoning"),
+			WithUsageParser(parseUsage),
+		},
+		options.WithBaseURL(server.URL+"/"),
+		options.WithAuthToken("key"),
+	)
+	params := chat.NewChatParams(
+		chat.WithModel("m"),
+		chat.WithMaxTokens(100),
+		chat.WithMessages(chat.NewUserMessage("?")),
+	)
+
+	resp, err := provider.Send(context.Background(), *params)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "Hmm", resp.Choice[0].Content[0].Thinking)
+	assert.Equal(t, 4, resp.Usage.InputCachedTokens)
+
+	stream, err := provider.Stream(context.Background(), *params)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer stream.Close()
+	var types []string
+	var last chat.EventStream
+	for stream.Next() {
+		last = stream.Current()
+		types = append(types, last.Type)
+	}
+	assert.NoError(t, stream.Err())
+	assert.Equal(t, []string{"thinking_delta", "text_delta", "message_stop"}, types)
+	assert.Equal(t, 4, last.Message.Usage.InputCachedTokens)
+	assert.Equal(t, "Hmm", last.Message.Choice[0].Content[0].Thinking)
+}
+
+func TestProvider_QuirksParams(t *testing.T) {
+	var req map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = nil
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"1","model":"m","choices":[{"message":{"role":"assistant","content":"42"},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+	newProvider := func(name string, quirks ...Quirk) *Provider {
+		return NewCompatible(name, quirks, options.WithBaseURL(server.URL+"/"))
+	}
+	params := func(opts ...func(*chat.ChatParams)) chat.ChatParams {
+		opts = append(opts, chat.WithModel("m"), chat.WithMessages(chat.NewUserMessage("?")))
+		return *chat.NewChatParams(opts...)
+	}
+
+	// top_k is only sent to the APIs accepting it
+	_, err := newProvider("test").Send(context.Background(), params(chat.WithTopK(40)))
+	var unsupported *chat.UnsupportedParamError
+	if assert.ErrorAs(t, err, &unsupported) {
+		assert.Equal(t, "test", unsupported.Provider)
+		assert.Equal(t, "top_k", unsupported.Param)
+	}
+	_, err = newProvider("together", WithTopK()).Send(context.Background(), params(chat.WithTopK(40)))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(40), req["top_k"])
+
+	// seed is renamed, logit_bias is rejected
+	mistral := newProvider("mistral", WithRandomSeedField(), WithUnsupported("logit_bias"))
+	_, err = mistral.Send(context.Background(), params(chat.WithSeed(42)))
+	assert.NoError(t, err)
+	assert.Equal(t, float64(42), req["random_seed"])
+	assert.NotContains(t, req, "seed")
+	_, err = mistral.Stream(context.Background(), params(chat.WithLogitBias(map[string]int{"50256": -100})))
+	assert.ErrorIs(t, err, chat.ErrUnsupportedParam)
+	assert.EqualError(t, err, "mistral: logit_bias is unsupported by provider")
+}
+
+func TestProvider_CountTokens(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("LLMHAVEN_TOKENIZER_DIR", dir)
+
+	provider := New(options.WithApiKey("Authorization", "Bearer key"))
+	counter, ok := provider.(chat.TokenCounter)
+	if !assert.True(t, ok) {
+		t.FailNow()
+	}
+	params := *chat.NewChatParams(
+		chat.WithModel("gpt-4o"),
+		chat.WithMessages(chat.NewUserMessage("hi")),
+	)
+	// Embedded ranks of gpt-4, the prompt_tokens of the API
+	gpt4 := params
+	gpt4.Model = "gpt-4"
+	n, err := counter.CountTokens(context.Background(), gpt4)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(8), n)
+
+	// Only the single bytes in Dir, overriding the embedded ranks
+	var ranks strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&ranks, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	path := filepath.Join(dir, tokenizer.O200kBase+".tiktoken")
+	assert.NoError(t, os.WriteFile(path, []byte(ranks.String()), 0o644))
+
+	n, err = counter.CountTokens(context.Background(), params)
+	assert.NoError(t, err)
+	// reply + message + "user" + "hi"
+	assert.Equal(t, int64(3+3+4+2), n)
+}
+
+func TestToChatResponse_Cost(t *testing.T) {
+	cc := &ChatCompletion{}
+	assert.Nil(t, ToChatResponse(cc).Cost)
+
+	// OpenRouter returns the cost in the usage
+	assert.NoError(t, json.Unmarshal([]byte(`{"id":"1","usage":{"prompt_tokens":10,"completion_tokens":2,"cost":0.0012}}`), cc))
+	resp := ToChatResponse(cc)
+	if assert.NotNil(t, resp.Cost) {
+		assert.Equal(t, 0.0012, resp.Cost.Total)
+	}
+}
+
+func TestProvider_RateLimits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ratelimit-Limit-Requests", "500")
+		w.Header().Set("X-Ratelimit-Remaining-Requests", "499")
+		w.Header().Set("X-Ratelimit-Reset-Requests", "120ms")
+		w.Header().Set("X-Ratelimit-Limit-Tokens", "30000")
+		w.Header().Set("X-Ratelimit-Remaining-Tokens", "29000")
+		w.Header().Set("X-Ratelimit-Reset-Tokens", "6m0s")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"1","model":"m","choices":[{"message":{"role":"assistant","content":"42"},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	var updates []*config.RateLimit
+	onUpdate := func(rl *config.RateLimit) { updates = append(updates, rl) }
+	var shared config.RateLimit
+	provider := NewCompatible("test", nil,
+		options.WithBaseURL(server.URL+"/"),
+		options.WithAuthToken("key"),
+		options.WithRateLimit(&shared, config.ParseOpenAIRateLimit, onUpdate),
+	)
+	_, err := provider.Send(context.Background(), *chat.NewChatParams(
+		chat.WithModel("m"),
+		chat.WithMessages(chat.NewUserMessage("?")),
+	))
+	assert.NoError(t, err)
+
+	rl := provider.RateLimits()
+	assert.Equal(t, 499, rl.RemainingRequests)
+	assert.Equal(t, 29000, rl.RemainingTokens)
+	assert.Equal(t, 120*time.Millisecond, rl.ResetRequests)
+	assert.Equal(t, 6*time.Minute, rl.ResetTokens)
+	if assert.Len(t, updates, 1) {
+		assert.Equal(t, 29000, updates[0].RemainingTokens)
+	}
+	assert.Equal(t, 499, shared.GetSnapshot().RemainingRequests)
+}
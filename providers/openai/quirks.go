@@ -0,0 +1,174 @@
+package openai
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/http/options"
+)
+
+// Quirks describes how an OpenAI compatible API differs from OpenAI
+type Quirks struct {
+	// stream_options is rejected, usage may not be sent while streaming
+	NoStreamOptions bool
+	// max_tokens is sent instead of max_completion_tokens
+	MaxTokensField bool
+	// ReasoningField is the message field holding the reasoning text when
+	// it's not reasoning_content
+	ReasoningField string
+	// The batches and files endpoints are missing, batch jobs run locally
+	NoBatch bool
+	// UsageParser reads the raw usage object, called after the default mapping
+	UsageParser func(raw json.RawMessage, usage *chat.ChatUsage) error
+	// top_k is accepted, OpenAI rejects it
+	TopK bool
+	// Unsupported are the params rejected by the API like seed or
+	// logit_bias, an error is returned instead of sending them
+	Unsupported []string
+	// random_seed is sent instead of seed
+	RandomSeedField bool
+}
+
+type Quirk func(*Quirks)
+
+func WithNoStreamOptions() Quirk {
+	return func(q *Quirks) {
+		q.NoStreamOptions = true
+	}
+}
+
+func WithMaxTokensField() Quirk {
+	return func(q *Quirks) {
+		q.MaxTokensField = true
+	}
+}
+
+func WithReasoningField(name string) Quirk {
+	return func(q *Quirks) {
+		q.ReasoningField = name
+	}
+}
+
+func WithNoBatch() Quirk {
+	return func(q *Quirks) {
+		q.NoBatch = true
+	}
+}
+
+func WithUsageParser(parser func(raw json.RawMessage, usage *chat.ChatUsage) error) Quirk {
+	return func(q *Quirks) {
+		q.UsageParser = parser
+	}
+}
+
+func WithTopK() Quirk {
+	return func(q *Quirks) {
+		q.TopK = true
+	}
+}
+
+// WithUnsupported sets the params rejected by the API: seed, logit_bias,
+// presence_penalty or frequency_penalty
+func WithUnsupported(params ...string) Quirk {
+	return func(q *Quirks) {
+		q.Unsupported = append(q.Unsupported, params...)
+	}
+}
+
+func WithRandomSeedField() Quirk {
+	return func(q *Quirks) {
+		q.RandomSeedField = true
+	}
+}
+
+func NewQuirks(quirks ...Quirk) Quirks {
+	q := Quirks{}
+	for _, quirk := range quirks {
+		quirk(&q)
+	}
+	return q
+}
+
+func (q Quirks) applyParams(p *ChatCompletionNewParams) {
+	if q.MaxTokensField && p.MaxCompletionTokens != nil {
+		p.MaxTokens, p.MaxCompletionTokens = p.MaxCompletionTokens, nil
+	}
+	if q.RandomSeedField && p.Seed != nil {
+		p.RandomSeed, p.Seed = p.Seed, nil
+	}
+}
+
+// checkParams returns an UnsupportedParamError of provider for each param
+// set but not accepted by the API
+func (q Quirks) checkParams(provider string, params chat.ChatParams) error {
+	set := map[string]bool{
+		"top_k":             params.TopK != nil,
+		"seed":              params.Seed != nil,
+		"logit_bias":        len(params.LogitBias) > 0,
+		"presence_penalty":  params.PresencePenalty != nil,
+		"frequency_penalty": params.FrequencyPenalty != nil,
+	}
+	var errs []error
+	if set["top_k"] && !q.TopK {
+		errs = append(errs, chat.NewUnsupportedParamError(provider, "top_k"))
+	}
+	for _, param := range q.Unsupported {
+		if set[param] {
+			errs = append(errs, chat.NewUnsupportedParamError(provider, param))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (q Quirks) streamOptions() []options.RequestOption {
+	if q.NoStreamOptions {
+		return []options.RequestOption{options.WithJSONDel("stream_options")}
+	}
+	return nil
+}
+
+// reasoning returns the value of the ReasoningField of the raw JSON message
+func (q Quirks) reasoning(raw string) string {
+	if q.ReasoningField == "" || raw == "" {
+		return ""
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+		return ""
+	}
+	var s string
+	_ = json.Unmarshal(fields[q.ReasoningField], &s)
+	return s
+}
+
+func (q Quirks) applyCompletion(cc *ChatCompletion) {
+	if q.ReasoningField == "" {
+		return
+	}
+	for i := range cc.Choices {
+		msg := &cc.Choices[i].Message
+		if s := q.reasoning(msg.JSON); s != "" {
+			msg.ReasoningContent = s
+		}
+	}
+}
+
+func (q Quirks) applyChunk(chunk *ChatCompletionChunk) {
+	if q.ReasoningField == "" {
+		return
+	}
+	for i := range chunk.Choices {
+		delta := &chunk.Choices[i].Delta
+		if s := q.reasoning(delta.JSON); s != "" {
+			delta.ReasoningContent = s
+		}
+	}
+}
+
+func (q Quirks) applyUsage(cc *ChatCompletion, resp *chat.ChatResponse) error {
+	if q.UsageParser == nil || len(cc.Usage.JSON) == 0 {
+		return nil
+	}
+	return q.UsageParser(cc.Usage.JSON, resp.Usage)
+}
@@ -0,0 +1,16 @@
+package openrouter
+
+import (
+	"github.com/y0ug/llmhaven/embedding"
+	"github.com/y0ug/llmhaven/http/options"
+	"github.com/y0ug/llmhaven/providers/openai"
+)
+
+// NewEmbedder uses the OpenAI compatible embeddings endpoint, models are
+// prefixed by their provider
+func NewEmbedder(opts ...options.RequestOption) embedding.Embedder {
+	return &openai.Embedder{
+		Client: NewClient(opts...).Client,
+		Model:  "openai/text-embedding-3-small",
+	}
+}
@@ -14,6 +14,8 @@ func New(opts ...options.RequestOption) chat.Provider {
 	return &Provider{
 		&openai.Provider{
 			Client: NewClient(opts...).Client,
+			Name:   "openrouter",
+			Quirks: openai.NewQuirks(openai.WithNoBatch(), openai.WithTopK()),
 		},
 	}
 }
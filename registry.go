@@ -0,0 +1,204 @@
+package llmhaven
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/y0ug/llmhaven/batch"
+	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/http/config"
+	"github.com/y0ug/llmhaven/http/options"
+	"github.com/y0ug/llmhaven/http/streaming"
+	"github.com/y0ug/llmhaven/modelinfo"
+	"github.com/y0ug/llmhaven/providers/anthropic"
+	"github.com/y0ug/llmhaven/providers/deepseek"
+	"github.com/y0ug/llmhaven/providers/gemini"
+	"github.com/y0ug/llmhaven/providers/ollama"
+	"github.com/y0ug/llmhaven/providers/openai"
+	"github.com/y0ug/llmhaven/providers/openrouter"
+)
+
+var (
+	registryMu sync.RWMutex
+	factories  = map[string]ProviderFactory{}
+	aliases    = map[string]string{}
+)
+
+func init() {
+	Register("anthropic", anthropic.New)
+	Register("openai", openai.New)
+	Register("openrouter", openrouter.New)
+	Register("gemini", gemini.New)
+	Register("gemini-openai", gemini.NewCompat)
+	Register("deepseek", deepseek.New)
+	Register("ollama", ollama.New)
+	Register("groq", NewGroq)
+	Register("together", NewTogether)
+	Register("mistral", NewMistral)
+	Register("vllm", NewVLLM)
+	Register("lmstudio", NewLMStudio)
+
+	RegisterAlias("claude", "anthropic")
+	RegisterAlias("google", "gemini")
+}
+
+// Register makes a provider available in New, registering an existing name
+// replace it
+func Register(name string, factory ProviderFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	factories[strings.ToLower(name)] = factory
+}
+
+// RegisterAlias makes alias resolve to the provider name
+func RegisterAlias(alias, name string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	aliases[strings.ToLower(alias)] = strings.ToLower(name)
+}
+
+// Providers returns the sorted names of the registered providers, aliases
+// are not included
+func Providers() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookup(name string) (ProviderFactory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	name = strings.ToLower(name)
+	if target, ok := aliases[name]; ok {
+		name = target
+	}
+	factory, ok := factories[name]
+	return factory, ok
+}
+
+// NewFromModel creates the provider of a "provider/model" string, the
+// provider is inferred from the name when there is no prefix. The model is
+// used for every request without ChatParams.Model.
+func NewFromModel(model string, requestOpts ...options.RequestOption,
+) (chat.Provider, error) {
+	m, err := modelinfo.Get(model, nil)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := New(m.Provider, requestOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &modelProvider{Provider: provider, model: m.Name}, nil
+}
+
+// modelProvider binds a provider to a model
+type modelProvider struct {
+	chat.Provider
+	model string
+}
+
+func (p *modelProvider) Send(ctx context.Context, params chat.ChatParams) (*chat.ChatResponse, error) {
+	if params.Model == "" {
+		params.Model = p.model
+	}
+	return p.Provider.Send(ctx, params)
+}
+
+func (p *modelProvider) Stream(
+	ctx context.Context,
+	params chat.ChatParams,
+) (streaming.Streamer[chat.EventStream], error) {
+	if params.Model == "" {
+		params.Model = p.model
+	}
+	return p.Provider.Stream(ctx, params)
+}
+
+// CountTokens is forwarded when the provider is a chat.TokenCounter
+func (p *modelProvider) CountTokens(ctx context.Context, params chat.ChatParams) (int64, error) {
+	counter, ok := p.Provider.(chat.TokenCounter)
+	if !ok {
+		return 0, chat.ErrCountTokensUnsupported
+	}
+	if params.Model == "" {
+		params.Model = p.model
+	}
+	return counter.CountTokens(ctx, params)
+}
+
+// RateLimits is forwarded when the provider is a RateLimitReporter
+func (p *modelProvider) RateLimits() config.RateLimit {
+	if reporter, ok := p.Provider.(RateLimitReporter); ok {
+		return reporter.RateLimits()
+	}
+	return config.RateLimit{}
+}
+
+// SupportsBatch is false when the provider has no batch API, batch.New then
+// uses a local executor
+func (p *modelProvider) SupportsBatch() bool {
+	if _, ok := p.Provider.(batch.BatchProvider); !ok {
+		return false
+	}
+	if s, ok := p.Provider.(batch.Supporter); ok {
+		return s.SupportsBatch()
+	}
+	return true
+}
+
+func (p *modelProvider) batchProvider() (batch.BatchProvider, error) {
+	bp, ok := p.Provider.(batch.BatchProvider)
+	if !ok {
+		return nil, fmt.Errorf("provider of %s has no batch API", p.model)
+	}
+	return bp, nil
+}
+
+// Submit is forwarded to the batch API, the model is set on the requests
+// without one
+func (p *modelProvider) Submit(ctx context.Context, requests []batch.Request) (*batch.Job, error) {
+	bp, err := p.batchProvider()
+	if err != nil {
+		return nil, err
+	}
+	requests = append([]batch.Request(nil), requests...)
+	for i := range requests {
+		if requests[i].Params.Model == "" {
+			requests[i].Params.Model = p.model
+		}
+	}
+	return bp.Submit(ctx, requests)
+}
+
+func (p *modelProvider) Status(ctx context.Context, id string) (*batch.Job, error) {
+	bp, err := p.batchProvider()
+	if err != nil {
+		return nil, err
+	}
+	return bp.Status(ctx, id)
+}
+
+func (p *modelProvider) Results(ctx context.Context, id string) ([]batch.Result, error) {
+	bp, err := p.batchProvider()
+	if err != nil {
+		return nil, err
+	}
+	return bp.Results(ctx, id)
+}
+
+func (p *modelProvider) Cancel(ctx context.Context, id string) (*batch.Job, error) {
+	bp, err := p.batchProvider()
+	if err != nil {
+		return nil, err
+	}
+	return bp.Cancel(ctx, id)
+}
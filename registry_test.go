@@ -0,0 +1,160 @@
+package llmhaven
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y0ug/llmhaven/batch"
+	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/embedding"
+	"github.com/y0ug/llmhaven/http/options"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRegistry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mock := chat.NewMockProvider(ctrl)
+
+	Register("custom", func(opts ...options.RequestOption) chat.Provider { return mock })
+	RegisterAlias("my-alias", "custom")
+
+	assert.Contains(t, Providers(), "custom")
+	assert.Contains(t, Providers(), "anthropic")
+	assert.NotContains(t, Providers(), "my-alias")
+
+	provider, err := New("my-alias")
+	assert.NoError(t, err)
+	assert.Equal(t, mock, provider)
+
+	_, err = New("claude")
+	assert.NoError(t, err)
+
+	_, err = New("unknown")
+	assert.Error(t, err)
+}
+
+func TestNewFromModel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mock := chat.NewMockProvider(ctrl)
+	Register("custom", func(opts ...options.RequestOption) chat.Provider { return mock })
+
+	gomock.InOrder(
+		mock.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, params chat.ChatParams) (*chat.ChatResponse, error) {
+				assert.Equal(t, "org/model-1", params.Model)
+				return &chat.ChatResponse{}, nil
+			}),
+		mock.EXPECT().Send(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, params chat.ChatParams) (*chat.ChatResponse, error) {
+				assert.Equal(t, "other", params.Model)
+				return &chat.ChatResponse{}, nil
+			}),
+	)
+
+	provider, err := NewFromModel("custom/org/model-1")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = provider.Send(context.Background(), chat.ChatParams{})
+	assert.NoError(t, err)
+	_, err = provider.Send(context.Background(), chat.ChatParams{Model: "other"})
+	assert.NoError(t, err)
+
+	counter, ok := provider.(chat.TokenCounter)
+	if assert.True(t, ok) {
+		_, err = counter.CountTokens(context.Background(), chat.ChatParams{})
+		assert.ErrorIs(t, err, chat.ErrCountTokensUnsupported)
+	}
+
+	_, err = NewFromModel("claude-3-5-sonnet-20241022")
+	assert.NoError(t, err)
+}
+
+// batchProvider has a batch API recording the submitted requests
+type batchProvider struct {
+	*chat.MockProvider
+	requests []batch.Request
+}
+
+func (p *batchProvider) Submit(ctx context.Context, requests []batch.Request) (*batch.Job, error) {
+	p.requests = requests
+	return &batch.Job{ID: "native"}, nil
+}
+
+func (p *batchProvider) Status(ctx context.Context, id string) (*batch.Job, error) {
+	return &batch.Job{ID: id}, nil
+}
+
+func (p *batchProvider) Results(ctx context.Context, id string) ([]batch.Result, error) {
+	return nil, nil
+}
+
+func (p *batchProvider) Cancel(ctx context.Context, id string) (*batch.Job, error) {
+	return &batch.Job{ID: id}, nil
+}
+
+func TestNewFromModel_Batch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	native := &batchProvider{MockProvider: chat.NewMockProvider(ctrl)}
+	Register("custom-batch", func(opts ...options.RequestOption) chat.Provider { return native })
+	Register("custom", func(opts ...options.RequestOption) chat.Provider {
+		return chat.NewMockProvider(ctrl)
+	})
+
+	provider, err := NewFromModel("custom-batch/model-1")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	bp := batch.New(provider)
+	_, isLocal := bp.(*batch.Local)
+	assert.False(t, isLocal)
+	job, err := bp.Submit(context.Background(), batch.NewRequests(chat.ChatParams{}))
+	assert.NoError(t, err)
+	assert.Equal(t, "native", job.ID)
+	assert.Equal(t, "model-1", native.requests[0].Params.Model)
+
+	// Without batch API the local executor is used
+	provider, err = NewFromModel("custom/model-1")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, isLocal = batch.New(provider).(*batch.Local)
+	assert.True(t, isLocal)
+}
+
+type fakeEmbedder struct {
+	params *embedding.EmbedParams
+}
+
+func (e *fakeEmbedder) Embed(
+	ctx context.Context,
+	inputs []string,
+	opts ...func(*embedding.EmbedParams),
+) ([][]float32, embedding.Usage, error) {
+	e.params = embedding.NewEmbedParams(opts...)
+	return nil, embedding.Usage{}, nil
+}
+
+func TestNewEmbedder(t *testing.T) {
+	fake := &fakeEmbedder{}
+	RegisterEmbedder("custom", func(opts ...options.RequestOption) embedding.Embedder { return fake })
+	RegisterAlias("my-embedder", "custom")
+	assert.Contains(t, Embedders(), "openai")
+
+	e, err := NewEmbedder("my-embedder/model-1")
+	assert.NoError(t, err)
+	_, _, err = e.Embed(context.Background(), []string{"a"})
+	assert.NoError(t, err)
+	assert.Equal(t, "model-1", fake.params.Model)
+
+	// The call option wins
+	_, _, err = e.Embed(context.Background(), []string{"a"}, embedding.WithModel("model-2"))
+	assert.NoError(t, err)
+	assert.Equal(t, "model-2", fake.params.Model)
+
+	_, err = NewEmbedder("google")
+	assert.NoError(t, err)
+	_, err = NewEmbedder("anthropic")
+	assert.Error(t, err)
+}
@@ -0,0 +1,195 @@
+package retrieval
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Chunk is a part of a text, Heading is the Markdown section path
+type Chunk struct {
+	Text    string
+	Index   int
+	Heading string
+}
+
+type Chunker interface {
+	Chunk(text string) []Chunk
+}
+
+// TextChunker splits a text in chunks of Size characters, cutting at
+// paragraphs, then lines, sentences and words. Overlap characters of the
+// previous chunk are repeated at the start of the next one, a chunk can be
+// up to Size+Overlap long.
+type TextChunker struct {
+	Size    int
+	Overlap int
+}
+
+func NewTextChunker(size, overlap int) *TextChunker {
+	return &TextChunker{Size: size, Overlap: overlap}
+}
+
+func (c *TextChunker) Chunk(text string) []Chunk {
+	var chunks []Chunk
+	for i, s := range c.split(text) {
+		chunks = append(chunks, Chunk{Text: s, Index: i})
+	}
+	return chunks
+}
+
+// Separators tried in order, the text is cut on the first one giving parts
+// smaller than the size
+var separators = []string{"\n\n", "\n", ". ", " "}
+
+func (c *TextChunker) split(text string) []string {
+	size := c.Size
+	if size <= 0 {
+		size = 1000
+	}
+	overlap := min(max(c.Overlap, 0), size/2)
+
+	pieces := splitPieces(strings.TrimSpace(text), size, separators)
+
+	// Merge the pieces up to the size
+	var chunks []string
+	var current strings.Builder
+	prefix := 0 // length of the overlap at the start of current
+	for _, p := range pieces {
+		if current.Len() > prefix && runeLen(current.String())+runeLen(p) > size {
+			chunk := strings.TrimSpace(current.String())
+			chunks = append(chunks, chunk)
+			current.Reset()
+			t := tail(chunk, overlap)
+			current.WriteString(t)
+			prefix = len(t)
+		}
+		current.WriteString(p)
+	}
+	if current.Len() > prefix {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			chunks = append(chunks, s)
+		}
+	}
+	return chunks
+}
+
+// splitPieces cuts text after the separators so every piece fits in size,
+// the separators are kept at the end of the pieces
+func splitPieces(text string, size int, seps []string) []string {
+	if runeLen(text) <= size {
+		return []string{text}
+	}
+	if len(seps) == 0 {
+		// No separator left, hard cut
+		var pieces []string
+		runes := []rune(text)
+		for len(runes) > size {
+			pieces = append(pieces, string(runes[:size]))
+			runes = runes[size:]
+		}
+		return append(pieces, string(runes))
+	}
+	var pieces []string
+	for _, part := range strings.SplitAfter(text, seps[0]) {
+		if part == "" {
+			continue
+		}
+		pieces = append(pieces, splitPieces(part, size, seps[1:])...)
+	}
+	return pieces
+}
+
+// tail returns the last n characters of s, starting on a word
+func tail(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s + " "
+	}
+	t := runes[len(runes)-n:]
+	for i, r := range t {
+		if unicode.IsSpace(r) {
+			return strings.TrimLeftFunc(string(t[i:]), unicode.IsSpace) + " "
+		}
+	}
+	return string(t) + " "
+}
+
+func runeLen(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+// MarkdownChunker splits on the headings then chunks the sections with a
+// TextChunker, code blocks are never split on a heading
+type MarkdownChunker struct {
+	TextChunker
+}
+
+func NewMarkdownChunker(size, overlap int) *MarkdownChunker {
+	return &MarkdownChunker{TextChunker{Size: size, Overlap: overlap}}
+}
+
+type section struct {
+	heading string
+	text    strings.Builder
+}
+
+func (c *MarkdownChunker) Chunk(text string) []Chunk {
+	var sections []*section
+	var headings []string // heading of each level
+	current := &section{}
+	inFence := false
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		if level, title := parseHeading(trimmed); !inFence && level > 0 {
+			sections = append(sections, current)
+			if len(headings) >= level {
+				headings = headings[:level-1]
+			}
+			for len(headings) < level-1 {
+				headings = append(headings, "")
+			}
+			headings = append(headings, title)
+			current = &section{heading: joinHeadings(headings)}
+		}
+		current.text.WriteString(line)
+	}
+	sections = append(sections, current)
+
+	var chunks []Chunk
+	for _, s := range sections {
+		for _, t := range c.split(s.text.String()) {
+			chunks = append(chunks, Chunk{Text: t, Index: len(chunks), Heading: s.heading})
+		}
+	}
+	return chunks
+}
+
+// parseHeading returns the level and title of an ATX heading
+func parseHeading(line string) (int, string) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(line) && line[level] != ' ') {
+		return 0, ""
+	}
+	return level, strings.TrimSpace(strings.TrimRight(line[level:], "#"))
+}
+
+func joinHeadings(headings []string) string {
+	parts := make([]string, 0, len(headings))
+	for _, h := range headings {
+		if h != "" {
+			parts = append(parts, h)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
@@ -0,0 +1,73 @@
+package retrieval
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextChunker(t *testing.T) {
+	text := "First paragraph is here.\n\nSecond paragraph. It has two sentences.\n\nThird."
+	chunks := NewTextChunker(40, 0).Chunk(text)
+	if assert.Len(t, chunks, 3) {
+		assert.Equal(t, "First paragraph is here.", chunks[0].Text)
+		assert.Equal(t, "Second paragraph. It has two sentences.", chunks[1].Text)
+		assert.Equal(t, "Third.", chunks[2].Text)
+		assert.Equal(t, 2, chunks[2].Index)
+	}
+
+	// A single paragraph is cut on sentences then words
+	long := strings.Repeat("word ", 30)
+	for _, c := range NewTextChunker(32, 0).Chunk(long) {
+		assert.LessOrEqual(t, len(c.Text), 32)
+		assert.False(t, strings.HasSuffix(c.Text, "wo"))
+	}
+
+	// No separator, hard cut
+	chunks = NewTextChunker(10, 0).Chunk(strings.Repeat("é", 25))
+	if assert.Len(t, chunks, 3) {
+		assert.Equal(t, 10, runeLen(chunks[0].Text))
+	}
+
+	assert.Empty(t, NewTextChunker(10, 0).Chunk("  "))
+}
+
+func TestTextChunker_Overlap(t *testing.T) {
+	chunks := NewTextChunker(20, 8).Chunk("one two three four five six seven eight")
+	if assert.Greater(t, len(chunks), 1) {
+		// The end of a chunk starts the next one
+		last := chunks[0].Text[strings.LastIndex(chunks[0].Text, " ")+1:]
+		assert.True(t, strings.HasPrefix(chunks[1].Text, last), chunks)
+	}
+}
+
+func TestMarkdownChunker(t *testing.T) {
+	text := `Intro text.
+
+# Guide
+
+## Install
+
+Run go get.
+
+` + "```sh\n# not a heading\ngo get\n```" + `
+
+## Usage
+
+Call New.
+
+# API
+`
+	chunks := NewMarkdownChunker(1000, 0).Chunk(text)
+	if assert.Len(t, chunks, 5) {
+		assert.Equal(t, "Intro text.", chunks[0].Text)
+		assert.Equal(t, "", chunks[0].Heading)
+		assert.Equal(t, "Guide", chunks[1].Heading)
+		assert.Equal(t, "Guide > Install", chunks[2].Heading)
+		assert.Contains(t, chunks[2].Text, "# not a heading")
+		assert.Equal(t, "Guide > Usage", chunks[3].Heading)
+		assert.Equal(t, "API", chunks[4].Heading)
+		assert.Equal(t, 4, chunks[4].Index)
+	}
+}
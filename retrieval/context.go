@@ -0,0 +1,73 @@
+package retrieval
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/y0ug/llmhaven/chat"
+)
+
+// ContextInstruction is the first context block, it asks the model to cite
+// the sources
+var ContextInstruction = "Answer using the sources below when they are relevant. " +
+	"Cite the sources you use with their number, like [1]."
+
+// Citation is the source of a chunk numbered [Number] in the context
+type Citation struct {
+	Number  int
+	ID      string
+	Source  string
+	Heading string
+	Score   float32
+}
+
+func (c Citation) String() string {
+	s := fmt.Sprintf("[%d] %s", c.Number, c.Source)
+	if c.Heading != "" {
+		s += " > " + c.Heading
+	}
+	return s
+}
+
+// Citations numbers the matches from 1, in the order of the context blocks
+func Citations(matches []Match) []Citation {
+	citations := make([]Citation, len(matches))
+	for i, m := range matches {
+		source := m.Metadata[MetadataSource]
+		if source == "" {
+			source = m.ID
+		}
+		citations[i] = Citation{
+			Number:  i + 1,
+			ID:      m.ID,
+			Source:  source,
+			Heading: m.Metadata[MetadataHeading],
+			Score:   m.Score,
+		}
+	}
+	return citations
+}
+
+// NewContextContent formats the matches as text blocks, each one starts with
+// its citation
+func NewContextContent(matches []Match) []*chat.MessageContent {
+	if len(matches) == 0 {
+		return nil
+	}
+	contents := []*chat.MessageContent{chat.NewTextContent(ContextInstruction)}
+	for i, c := range Citations(matches) {
+		var b strings.Builder
+		b.WriteString(c.String())
+		b.WriteString("\n")
+		b.WriteString(matches[i].Text)
+		contents = append(contents, chat.NewTextContent(b.String()))
+	}
+	return contents
+}
+
+// WithContext returns a copy of msg with the context blocks before its
+// content, msg is not modified
+func WithContext(msg *chat.ChatMessage, matches []Match) *chat.ChatMessage {
+	content := append(NewContextContent(matches), msg.Content...)
+	return chat.NewMessage(msg.Role, content...)
+}
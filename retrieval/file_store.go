@@ -0,0 +1,86 @@
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FileStore is a MemoryStore saved as JSON to a file after every change
+type FileStore struct {
+	*MemoryStore
+	path string
+}
+
+// NewFileStore loads the documents of path when it exists
+func NewFileStore(path string, opts ...StoreOption) (*FileStore, error) {
+	s := &FileStore{MemoryStore: NewMemoryStore(opts...), path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var docs []Document
+	if err := json.Unmarshal(data, &docs); err != nil {
+		return nil, fmt.Errorf("error loading %s: %w", path, err)
+	}
+	if err := s.MemoryStore.add(docs...); err != nil {
+		return nil, fmt.Errorf("error loading %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func (s *FileStore) Add(ctx context.Context, docs ...Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.MemoryStore.add(docs...); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+func (s *FileStore) Delete(ctx context.Context, ids ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.docs, id)
+	}
+	return s.save()
+}
+
+func (s *FileStore) DeleteWhere(ctx context.Context, filter Filter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleteWhere(filter)
+	return s.save()
+}
+
+// save writes the documents sorted by id, s.mu must be held
+func (s *FileStore) save() error {
+	docs := make([]Document, 0, len(s.docs))
+	for _, d := range s.docs {
+		docs = append(docs, d)
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].ID < docs[j].ID })
+	data, err := json.Marshal(docs)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	// Written then renamed to not leave a truncated file
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,95 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/y0ug/llmhaven/embedding"
+)
+
+// Metadata keys set by Index.AddText
+const (
+	MetadataSource  = "source"
+	MetadataHeading = "heading"
+)
+
+// Index chunks, embeds and stores texts
+type Index struct {
+	Embedder embedding.Embedder
+	Store    Store
+	Chunker  Chunker
+	// EmbedOptions are passed to every Embed call, the model for example
+	EmbedOptions []func(*embedding.EmbedParams)
+}
+
+// NewIndex uses a MarkdownChunker of 1000 characters, it works for plain
+// text too
+func NewIndex(embedder embedding.Embedder, store Store) *Index {
+	return &Index{
+		Embedder: embedder,
+		Store:    store,
+		Chunker:  NewMarkdownChunker(1000, 100),
+	}
+}
+
+// AddText replaces the chunks of source, the chunk ids are source#index
+func (ix *Index) AddText(
+	ctx context.Context,
+	source string,
+	text string,
+	metadata map[string]string,
+) (embedding.Usage, error) {
+	chunks := ix.Chunker.Chunk(text)
+	inputs := make([]string, len(chunks))
+	for i, c := range chunks {
+		inputs[i] = c.Text
+	}
+	opts := append(ix.EmbedOptions[:len(ix.EmbedOptions):len(ix.EmbedOptions)],
+		embedding.WithTaskType(embedding.TaskRetrievalDocument))
+	vectors, usage, err := ix.Embedder.Embed(ctx, inputs, opts...)
+	if err != nil {
+		return usage, fmt.Errorf("error embedding %s: %w", source, err)
+	}
+
+	docs := make([]Document, len(chunks))
+	for i, c := range chunks {
+		md := make(map[string]string, len(metadata)+2)
+		for k, v := range metadata {
+			md[k] = v
+		}
+		md[MetadataSource] = source
+		if c.Heading != "" {
+			md[MetadataHeading] = c.Heading
+		}
+		docs[i] = Document{
+			ID:       fmt.Sprintf("%s#%d", source, i),
+			Text:     c.Text,
+			Metadata: md,
+			Vector:   vectors[i],
+		}
+	}
+	err = ix.Store.DeleteWhere(ctx, MetadataEquals(map[string]string{MetadataSource: source}))
+	if err != nil {
+		return usage, err
+	}
+	return usage, ix.Store.Add(ctx, docs...)
+}
+
+// Query returns the k chunks closest to the query
+func (ix *Index) Query(
+	ctx context.Context,
+	query string,
+	k int,
+	opts ...func(*SearchParams),
+) ([]Match, error) {
+	embedOpts := append(ix.EmbedOptions[:len(ix.EmbedOptions):len(ix.EmbedOptions)],
+		embedding.WithTaskType(embedding.TaskRetrievalQuery))
+	vectors, _, err := ix.Embedder.Embed(ctx, []string{query}, embedOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("error embedding query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("got %d embeddings for the query", len(vectors))
+	}
+	return ix.Store.Search(ctx, vectors[0], k, opts...)
+}
@@ -0,0 +1,79 @@
+package retrieval
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/embedding"
+)
+
+// wordEmbedder counts a few words, enough to rank offline
+type wordEmbedder struct {
+	taskTypes []string
+}
+
+var vocabulary = []string{"cat", "dog", "go", "install"}
+
+func (e *wordEmbedder) Embed(
+	ctx context.Context,
+	inputs []string,
+	opts ...func(*embedding.EmbedParams),
+) ([][]float32, embedding.Usage, error) {
+	e.taskTypes = append(e.taskTypes, embedding.NewEmbedParams(opts...).TaskType)
+	vectors := make([][]float32, len(inputs))
+	for i, input := range inputs {
+		v := make([]float32, len(vocabulary)+1)
+		v[len(vocabulary)] = 0.01
+		for j, w := range vocabulary {
+			v[j] = float32(strings.Count(strings.ToLower(input), w))
+		}
+		vectors[i] = v
+	}
+	return vectors, embedding.Usage{InputTokens: len(inputs)}, nil
+}
+
+func TestIndex(t *testing.T) {
+	ctx := context.Background()
+	embedder := &wordEmbedder{}
+	store := NewMemoryStore()
+	ix := NewIndex(embedder, store)
+	ix.Chunker = NewMarkdownChunker(200, 0)
+
+	_, err := ix.AddText(ctx, "pets.md", "# Cat\n\nThe cat sleeps.\n\n# Dog\n\nThe dog barks.\n\n# Old\n\nGone.",
+		map[string]string{"team": "a"})
+	assert.NoError(t, err)
+	_, err = ix.AddText(ctx, "go.md", "# Install\n\nRun go install.", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, store.Len())
+
+	// A new version replaces the chunks of the source
+	_, err = ix.AddText(ctx, "pets.md", "# Cat\n\nThe cat sleeps.\n\n# Dog\n\nThe dog barks.",
+		map[string]string{"team": "a"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, store.Len())
+
+	matches, err := ix.Query(ctx, "where is the dog?", 1)
+	assert.NoError(t, err)
+	if assert.Len(t, matches, 1) {
+		assert.Equal(t, "pets.md#1", matches[0].ID)
+		assert.Equal(t, "Dog", matches[0].Metadata[MetadataHeading])
+		assert.Equal(t, "a", matches[0].Metadata["team"])
+	}
+	assert.Equal(t, embedding.TaskRetrievalDocument, embedder.taskTypes[0])
+	assert.Equal(t, embedding.TaskRetrievalQuery, embedder.taskTypes[len(embedder.taskTypes)-1])
+
+	msg := chat.NewUserMessage("where is the dog?")
+	withContext := WithContext(msg, matches)
+	assert.Len(t, msg.Content, 1)
+	if assert.Len(t, withContext.Content, 3) {
+		assert.Equal(t, ContextInstruction, withContext.Content[0].Text)
+		assert.Equal(t, "[1] pets.md > Dog\n# Dog\n\nThe dog barks.", withContext.Content[1].Text)
+		assert.Equal(t, "where is the dog?", withContext.Content[2].Text)
+	}
+	assert.Equal(t, "[1] pets.md > Dog", Citations(matches)[0].String())
+
+	assert.Equal(t, msg.Content, WithContext(msg, nil).Content)
+}
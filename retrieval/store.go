@@ -0,0 +1,218 @@
+// Package retrieval is a small in-process vector index: stores, chunkers
+// and the helpers adding the retrieved chunks to a chat message.
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+type Document struct {
+	ID       string            `json:"id"`
+	Text     string            `json:"text"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Vector   []float32         `json:"vector"`
+}
+
+type Match struct {
+	Document
+	Score float32
+}
+
+// Similarity scores two vectors, higher is closer
+type Similarity func(a, b []float32) float32
+
+// Cosine similarity, in [-1, 1]
+func Cosine(a, b []float32) float32 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(na) * math.Sqrt(nb)))
+}
+
+// DotProduct is the cosine similarity for normalized vectors, OpenAI and
+// Gemini embeddings are normalized
+func DotProduct(a, b []float32) float32 {
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return float32(dot)
+}
+
+// Filter selects the documents by metadata
+type Filter func(metadata map[string]string) bool
+
+// MetadataEquals matches the documents having all the key values
+func MetadataEquals(kv map[string]string) Filter {
+	return func(metadata map[string]string) bool {
+		for k, v := range kv {
+			if metadata[k] != v {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+type SearchParams struct {
+	Filter   Filter
+	MinScore *float32
+}
+
+func WithFilter(filter Filter) func(*SearchParams) {
+	return func(p *SearchParams) {
+		p.Filter = filter
+	}
+}
+
+// WithMinScore drops the matches scoring below score
+func WithMinScore(score float32) func(*SearchParams) {
+	return func(p *SearchParams) {
+		p.MinScore = &score
+	}
+}
+
+type Store interface {
+	// Add inserts or replaces the documents by ID
+	Add(ctx context.Context, docs ...Document) error
+	Delete(ctx context.Context, ids ...string) error
+	// DeleteWhere removes the documents matching the filter
+	DeleteWhere(ctx context.Context, filter Filter) error
+	// Search returns the k best matches, best first
+	Search(ctx context.Context, vector []float32, k int, opts ...func(*SearchParams)) ([]Match, error)
+}
+
+type StoreOption func(*MemoryStore)
+
+// WithSimilarity sets the similarity, Cosine by default
+func WithSimilarity(s Similarity) StoreOption {
+	return func(m *MemoryStore) {
+		m.similarity = s
+	}
+}
+
+// MemoryStore is a brute force store, fine up to a few hundred thousands
+// documents
+type MemoryStore struct {
+	mu         sync.RWMutex
+	docs       map[string]Document
+	similarity Similarity
+	dims       int
+}
+
+func NewMemoryStore(opts ...StoreOption) *MemoryStore {
+	m := &MemoryStore{
+		docs:       make(map[string]Document),
+		similarity: Cosine,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func (m *MemoryStore) Add(ctx context.Context, docs ...Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.add(docs...)
+}
+
+func (m *MemoryStore) add(docs ...Document) error {
+	for _, d := range docs {
+		if d.ID == "" {
+			return fmt.Errorf("document without id")
+		}
+		dims := m.dims
+		if dims == 0 {
+			dims = len(d.Vector)
+		}
+		if len(d.Vector) == 0 || len(d.Vector) != dims {
+			return fmt.Errorf("document %s: vector of %d dimensions, expected %d",
+				d.ID, len(d.Vector), dims)
+		}
+		m.dims = dims
+		m.docs[d.ID] = d
+	}
+	return nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, ids ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, id := range ids {
+		delete(m.docs, id)
+	}
+	return nil
+}
+
+func (m *MemoryStore) DeleteWhere(ctx context.Context, filter Filter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteWhere(filter)
+	return nil
+}
+
+func (m *MemoryStore) deleteWhere(filter Filter) {
+	for id, d := range m.docs {
+		if filter(d.Metadata) {
+			delete(m.docs, id)
+		}
+	}
+}
+
+func (m *MemoryStore) Search(
+	ctx context.Context,
+	vector []float32,
+	k int,
+	opts ...func(*SearchParams),
+) ([]Match, error) {
+	params := &SearchParams{}
+	for _, opt := range opts {
+		opt(params)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.dims != 0 && len(vector) != m.dims {
+		return nil, fmt.Errorf("query vector of %d dimensions, expected %d", len(vector), m.dims)
+	}
+
+	matches := make([]Match, 0, len(m.docs))
+	for _, d := range m.docs {
+		if params.Filter != nil && !params.Filter(d.Metadata) {
+			continue
+		}
+		score := m.similarity(vector, d.Vector)
+		if params.MinScore != nil && score < *params.MinScore {
+			continue
+		}
+		matches = append(matches, Match{Document: d, Score: score})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	if k > 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches, nil
+}
+
+// Len returns the number of documents
+func (m *MemoryStore) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.docs)
+}
@@ -0,0 +1,79 @@
+package retrieval
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testDocs = []Document{
+	{ID: "x", Text: "x axis", Vector: []float32{1, 0}, Metadata: map[string]string{"lang": "en"}},
+	{ID: "y", Text: "y axis", Vector: []float32{0, 1}, Metadata: map[string]string{"lang": "fr"}},
+	{ID: "xy", Text: "diagonal", Vector: []float32{2, 2}, Metadata: map[string]string{"lang": "en"}},
+}
+
+func TestMemoryStore_Search(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	assert.NoError(t, s.Add(ctx, testDocs...))
+	assert.Equal(t, 3, s.Len())
+
+	matches, err := s.Search(ctx, []float32{1, 0.1}, 2)
+	assert.NoError(t, err)
+	if assert.Len(t, matches, 2) {
+		assert.Equal(t, "x", matches[0].ID)
+		assert.Equal(t, "xy", matches[1].ID)
+		assert.InDelta(t, 0.995, matches[0].Score, 0.001)
+	}
+
+	matches, err = s.Search(ctx, []float32{1, 0.1}, 10,
+		WithFilter(MetadataEquals(map[string]string{"lang": "fr"})))
+	assert.NoError(t, err)
+	if assert.Len(t, matches, 1) {
+		assert.Equal(t, "y", matches[0].ID)
+	}
+
+	matches, err = s.Search(ctx, []float32{1, 0}, 10, WithMinScore(0.5))
+	assert.NoError(t, err)
+	assert.Len(t, matches, 2)
+
+	_, err = s.Search(ctx, []float32{1, 0, 0}, 1)
+	assert.Error(t, err)
+	assert.Error(t, s.Add(ctx, Document{ID: "z", Vector: []float32{1}}))
+
+	assert.NoError(t, s.Delete(ctx, "x"))
+	assert.NoError(t, s.DeleteWhere(ctx, MetadataEquals(map[string]string{"lang": "fr"})))
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestMemoryStore_DotProduct(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(WithSimilarity(DotProduct))
+	assert.NoError(t, s.Add(ctx, testDocs...))
+
+	// Not normalized, the longer vector wins
+	matches, err := s.Search(ctx, []float32{1, 0.1}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "xy", matches[0].ID)
+	assert.InDelta(t, 2.2, matches[0].Score, 0.001)
+}
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "index", "store.json")
+
+	s, err := NewFileStore(path)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Add(ctx, testDocs...))
+	assert.NoError(t, s.Delete(ctx, "y"))
+
+	s, err = NewFileStore(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, s.Len())
+	matches, err := s.Search(ctx, []float32{1, 0}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "x", matches[0].ID)
+	assert.Equal(t, "en", matches[0].Metadata["lang"])
+}
@@ -0,0 +1,344 @@
+package llmhaven
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/http/config"
+	llmerrors "github.com/y0ug/llmhaven/http/errors"
+	"github.com/y0ug/llmhaven/http/streaming"
+)
+
+// ErrNoBackend is returned by the Router when every backend is ejected
+var ErrNoBackend = errors.New("no backend available")
+
+// RateLimitReporter is implemented by the providers keeping the rate limits
+// of their last response
+type RateLimitReporter interface {
+	RateLimits() config.RateLimit
+}
+
+// RouterBackend is a provider of the Router, usually the same provider with
+// another API key or endpoint
+type RouterBackend struct {
+	Name     string
+	Provider chat.Provider
+	Weight   int // used by Weighted, 1 when unset
+
+	inFlight     int64 // atomic, read by InFlight without the lock
+	requests     int64
+	failures     int64
+	lastError    string
+	ejectedUntil time.Time
+	current      int // smooth weighted round robin state
+}
+
+// InFlight returns the number of requests being sent to the backend
+func (b *RouterBackend) InFlight() int64 {
+	return atomic.LoadInt64(&b.inFlight)
+}
+
+// hasCapacity is false when the last rate limits have no request or token
+// left and their reset is not passed
+func (b *RouterBackend) hasCapacity(now time.Time) bool {
+	reporter, ok := b.Provider.(RateLimitReporter)
+	if !ok {
+		return true
+	}
+	rl := reporter.RateLimits()
+	return !rl.Exhausted(now)
+}
+
+// RouterStrategy picks the backend of a request, backends is never empty.
+// It's called with the lock of the Router held.
+type RouterStrategy interface {
+	Pick(backends []*RouterBackend) *RouterBackend
+}
+
+// RouterStrategyFunc is a function implementing RouterStrategy
+type RouterStrategyFunc func(backends []*RouterBackend) *RouterBackend
+
+func (f RouterStrategyFunc) Pick(backends []*RouterBackend) *RouterBackend {
+	return f(backends)
+}
+
+// RoundRobin picks the backends in turn
+func RoundRobin() RouterStrategy {
+	next := 0
+	return RouterStrategyFunc(func(backends []*RouterBackend) *RouterBackend {
+		b := backends[next%len(backends)]
+		next++
+		return b
+	})
+}
+
+// LeastInFlight picks the backend with the fewest requests being sent
+func LeastInFlight() RouterStrategy {
+	return RouterStrategyFunc(func(backends []*RouterBackend) *RouterBackend {
+		best := backends[0]
+		for _, b := range backends[1:] {
+			if b.InFlight() < best.InFlight() {
+				best = b
+			}
+		}
+		return best
+	})
+}
+
+// Weighted spreads the requests by backend weight with the smooth weighted
+// round robin of nginx, a backend of weight 2 gets twice the requests
+func Weighted() RouterStrategy {
+	return RouterStrategyFunc(func(backends []*RouterBackend) *RouterBackend {
+		total := 0
+		var best *RouterBackend
+		for _, b := range backends {
+			w := max(b.Weight, 1)
+			b.current += w
+			total += w
+			if best == nil || b.current > best.current {
+				best = b
+			}
+		}
+		best.current -= total
+		return best
+	})
+}
+
+type RouterOption func(*Router)
+
+// WithRouterStrategy sets how the backend is picked, RoundRobin by default
+func WithRouterStrategy(strategy RouterStrategy) RouterOption {
+	return func(r *Router) {
+		r.strategy = strategy
+	}
+}
+
+// WithEjectDuration sets how long a backend answering 401, 403 or 429 is
+// ejected, 30s by default. On 429 the reset of its rate limits is used when
+// known.
+func WithEjectDuration(d time.Duration) RouterOption {
+	return func(r *Router) {
+		r.ejectDuration = d
+	}
+}
+
+// Router spreads the requests over several backends. A backend rejecting
+// its key (401, 403) or rate limited (429) is ejected for a while and the
+// request is sent to another one. Backends without capacity left in their
+// rate limits are only used when no other is.
+type Router struct {
+	backends      []*RouterBackend
+	strategy      RouterStrategy
+	ejectDuration time.Duration
+	now           func() time.Time
+
+	mu sync.Mutex
+}
+
+var _ chat.Provider = (*Router)(nil)
+
+func NewRouter(backends []RouterBackend, opts ...RouterOption) *Router {
+	r := &Router{
+		strategy:      RoundRobin(),
+		ejectDuration: 30 * time.Second,
+		now:           time.Now,
+	}
+	for i := range backends {
+		b := backends[i]
+		if b.Name == "" {
+			b.Name = fmt.Sprintf("backend-%d", i)
+		}
+		r.backends = append(r.backends, &b)
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// acquire picks a backend not in tried and counts the request in flight
+func (r *Router) acquire(tried map[*RouterBackend]bool) (*RouterBackend, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	var available, withCapacity []*RouterBackend
+	for _, b := range r.backends {
+		if tried[b] || now.Before(b.ejectedUntil) {
+			continue
+		}
+		available = append(available, b)
+		if b.hasCapacity(now) {
+			withCapacity = append(withCapacity, b)
+		}
+	}
+	if len(available) == 0 {
+		return nil, ErrNoBackend
+	}
+	if len(withCapacity) > 0 {
+		available = withCapacity
+	}
+	b := r.strategy.Pick(available)
+	atomic.AddInt64(&b.inFlight, 1)
+	b.requests++
+	return b, nil
+}
+
+// release ends the request and reports if the error ejected the backend
+func (r *Router) release(b *RouterBackend, err error) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	atomic.AddInt64(&b.inFlight, -1)
+	if err == nil {
+		return false
+	}
+	b.failures++
+	b.lastError = err.Error()
+
+	var d time.Duration
+	switch llmerrors.StatusCode(err) {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		d = r.ejectDuration
+	case http.StatusTooManyRequests:
+		d = r.ejectDuration
+		if reporter, ok := b.Provider.(RateLimitReporter); ok {
+			rl := reporter.RateLimits()
+			if reset := max(rl.ResetRequests, rl.ResetTokens); reset > 0 {
+				d = reset
+			}
+		}
+	default:
+		return false
+	}
+	b.ejectedUntil = r.now().Add(d)
+	return true
+}
+
+func (r *Router) Send(ctx context.Context, params chat.ChatParams) (*chat.ChatResponse, error) {
+	tried := map[*RouterBackend]bool{}
+	var errs []error
+	for {
+		b, err := r.acquire(tried)
+		if err != nil {
+			return nil, errors.Join(append(errs, err)...)
+		}
+		tried[b] = true
+		resp, err := b.Provider.Send(ctx, params)
+		if !r.release(b, err) {
+			if resp != nil {
+				resp.Provider = b.Name
+			}
+			return resp, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", b.Name, err))
+	}
+}
+
+// Stream is sent to another backend only when the stream can't be opened,
+// the backend is counted in flight until the stream is closed
+func (r *Router) Stream(
+	ctx context.Context,
+	params chat.ChatParams,
+) (streaming.Streamer[chat.EventStream], error) {
+	tried := map[*RouterBackend]bool{}
+	var errs []error
+	for {
+		b, err := r.acquire(tried)
+		if err != nil {
+			return nil, errors.Join(append(errs, err)...)
+		}
+		tried[b] = true
+		stream, err := b.Provider.Stream(ctx, params)
+		if err == nil {
+			return &routerStream{Streamer: stream, router: r, backend: b}, nil
+		}
+		if !r.release(b, err) {
+			return nil, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", b.Name, err))
+	}
+}
+
+type routerStream struct {
+	streaming.Streamer[chat.EventStream]
+	router  *Router
+	backend *RouterBackend
+	once    sync.Once
+}
+
+func (s *routerStream) Current() chat.EventStream {
+	evt := s.Streamer.Current()
+	if evt.Message != nil {
+		evt.Message.Provider = s.backend.Name
+	}
+	return evt
+}
+
+// Next releases the backend at the end of the stream, Close does when the
+// stream isn't read to the end
+func (s *routerStream) Next() bool {
+	if s.Streamer.Next() {
+		return true
+	}
+	s.release()
+	return false
+}
+
+func (s *routerStream) Close() error {
+	s.release()
+	return s.Streamer.Close()
+}
+
+func (s *routerStream) release() {
+	s.once.Do(func() {
+		s.router.release(s.backend, s.Streamer.Err())
+	})
+}
+
+// BackendHealth is the state of a backend of the Router
+type BackendHealth struct {
+	Name              string     `json:"name"`
+	Healthy           bool       `json:"healthy"`
+	EjectedUntil      *time.Time `json:"ejected_until,omitempty"`
+	InFlight          int64      `json:"in_flight"`
+	Requests          int64      `json:"requests"`
+	Failures          int64      `json:"failures"`
+	LastError         string     `json:"last_error,omitempty"`
+	RemainingRequests int        `json:"remaining_requests,omitempty"`
+	RemainingTokens   int        `json:"remaining_tokens,omitempty"`
+}
+
+// Health returns the state of every backend, in the order of NewRouter
+func (r *Router) Health() []BackendHealth {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	health := make([]BackendHealth, 0, len(r.backends))
+	for _, b := range r.backends {
+		h := BackendHealth{
+			Name:      b.Name,
+			Healthy:   !now.Before(b.ejectedUntil),
+			InFlight:  b.InFlight(),
+			Requests:  b.requests,
+			Failures:  b.failures,
+			LastError: b.lastError,
+		}
+		if !h.Healthy {
+			until := b.ejectedUntil
+			h.EjectedUntil = &until
+		}
+		if reporter, ok := b.Provider.(RateLimitReporter); ok {
+			rl := reporter.RateLimits()
+			h.RemainingRequests = rl.RemainingRequests
+			h.RemainingTokens = rl.RemainingTokens
+		}
+		health = append(health, h)
+	}
+	return health
+}
@@ -0,0 +1,199 @@
+package llmhaven
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y0ug/llmhaven/budget"
+	"github.com/y0ug/llmhaven/chat"
+	"github.com/y0ug/llmhaven/http/config"
+	"github.com/y0ug/llmhaven/modelinfo"
+	"go.uber.org/mock/gomock"
+)
+
+// limitedProvider reports fixed rate limits
+type limitedProvider struct {
+	*chat.MockProvider
+	remaining int
+	reset     time.Duration
+	updatedAt time.Time
+}
+
+func (p *limitedProvider) RateLimits() config.RateLimit {
+	return config.RateLimit{
+		LimitRequests:     100,
+		RemainingRequests: p.remaining,
+		ResetRequests:     p.reset,
+		UpdatedAt:         p.updatedAt,
+	}
+}
+
+func TestRouter_Strategies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	a, b := chat.NewMockProvider(ctrl), chat.NewMockProvider(ctrl)
+	a.EXPECT().Send(gomock.Any(), gomock.Any()).Return(&chat.ChatResponse{}, nil).AnyTimes()
+	b.EXPECT().Send(gomock.Any(), gomock.Any()).Return(&chat.ChatResponse{}, nil).AnyTimes()
+	backends := []RouterBackend{
+		{Name: "a", Provider: a, Weight: 2},
+		{Name: "b", Provider: b},
+	}
+
+	served := func(r *Router, n int) []string {
+		var names []string
+		for i := 0; i < n; i++ {
+			resp, err := r.Send(context.Background(), chat.ChatParams{})
+			assert.NoError(t, err)
+			names = append(names, resp.Provider)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"a", "b", "a", "b"}, served(NewRouter(backends), 4))
+	assert.Equal(t, []string{"a", "b", "a", "a", "b", "a"},
+		served(NewRouter(backends, WithRouterStrategy(Weighted())), 6))
+
+	r := NewRouter(backends, WithRouterStrategy(LeastInFlight()))
+	first, _ := r.acquire(nil)
+	second, _ := r.acquire(nil)
+	assert.Equal(t, "a", first.Name)
+	assert.Equal(t, "b", second.Name)
+	r.release(second, nil)
+	third, _ := r.acquire(nil)
+	assert.Equal(t, "b", third.Name)
+}
+
+func TestRouter_Eject(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	a := &limitedProvider{MockProvider: chat.NewMockProvider(ctrl), remaining: 0, reset: time.Minute}
+	b := chat.NewMockProvider(ctrl)
+	now := time.Now()
+	r := NewRouter([]RouterBackend{{Name: "a", Provider: a}, {Name: "b", Provider: b}},
+		WithEjectDuration(10*time.Second))
+	r.now = func() time.Time { return now }
+
+	// a has no capacity left, b is used
+	b.EXPECT().Send(gomock.Any(), gomock.Any()).Return(&chat.ChatResponse{}, nil)
+	resp, err := r.Send(context.Background(), chat.ChatParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, "b", resp.Provider)
+
+	// b key is revoked, the request goes to a which is rate limited
+	a.remaining = 10
+	gomock.InOrder(
+		b.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil, apiError(http.StatusUnauthorized)),
+		a.EXPECT().Send(gomock.Any(), gomock.Any()).Return(nil, apiError(http.StatusTooManyRequests)),
+	)
+	r.strategy = RouterStrategyFunc(func(backends []*RouterBackend) *RouterBackend {
+		return backends[len(backends)-1]
+	})
+	_, err = r.Send(context.Background(), chat.ChatParams{})
+	assert.ErrorIs(t, err, ErrNoBackend)
+	assert.ErrorContains(t, err, "b: Unauthorized")
+
+	health := r.Health()
+	if assert.Len(t, health, 2) {
+		assert.False(t, health[0].Healthy)
+		// The reset of the rate limits is used on 429
+		assert.Equal(t, now.Add(time.Minute), *health[0].EjectedUntil)
+		assert.Equal(t, 10, health[0].RemainingRequests)
+		assert.False(t, health[1].Healthy)
+		assert.Equal(t, now.Add(10*time.Second), *health[1].EjectedUntil)
+		assert.Equal(t, int64(1), health[1].Failures)
+		assert.Equal(t, int64(2), health[1].Requests)
+	}
+
+	// Other errors don't eject
+	now = now.Add(11 * time.Second)
+	b.EXPECT().Stream(gomock.Any(), gomock.Any()).Return(nil, apiError(http.StatusBadRequest))
+	_, err = r.Stream(context.Background(), chat.ChatParams{})
+	assert.Error(t, err)
+	assert.True(t, r.Health()[1].Healthy)
+	assert.Equal(t, int64(0), r.Health()[1].InFlight)
+	assert.Nil(t, r.Health()[1].EjectedUntil)
+}
+
+func TestRouter_WrappedBackends(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	a := &limitedProvider{MockProvider: chat.NewMockProvider(ctrl), remaining: 0, reset: time.Minute}
+	b := chat.NewMockProvider(ctrl)
+	prices := &modelinfo.LitellmModelInfoMap{}
+	guard, err := budget.New(modelinfo.NewCostProvider(a, prices), prices,
+		budget.WithStore(budget.NewMemoryStore()))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	r := NewRouter([]RouterBackend{{Name: "a", Provider: guard}, {Name: "b", Provider: b}})
+
+	// The rate limits of a are seen through the cost and budget wrappers
+	b.EXPECT().Send(gomock.Any(), gomock.Any()).Return(&chat.ChatResponse{}, nil)
+	resp, err := r.Send(context.Background(), chat.ChatParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, "b", resp.Provider)
+}
+
+func TestRouter_StreamRelease(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	a := chat.NewMockProvider(ctrl)
+	r := NewRouter([]RouterBackend{{Name: "a", Provider: a}})
+
+	a.EXPECT().Stream(gomock.Any(), gomock.Any()).Return(&sliceStream{events: []chat.EventStream{
+		{Type: "text_delta", Message: &chat.ChatResponse{}},
+	}}, nil)
+	stream, err := r.Stream(context.Background(), chat.ChatParams{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, int64(1), r.Health()[0].InFlight)
+
+	// Released once read to the end, without Close
+	for stream.Next() {
+		assert.Equal(t, "a", stream.Current().Message.Provider)
+	}
+	assert.Equal(t, int64(0), r.Health()[0].InFlight)
+	assert.NoError(t, stream.Close())
+	assert.Equal(t, int64(0), r.Health()[0].InFlight)
+	assert.Equal(t, int64(1), r.Health()[0].Requests)
+
+	// InFlight can be read while requests are sent
+	a.EXPECT().Send(gomock.Any(), gomock.Any()).Times(10).DoAndReturn(
+		func(context.Context, chat.ChatParams) (*chat.ChatResponse, error) {
+			return &chat.ChatResponse{}, nil
+		})
+	backend := r.backends[0]
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Send(context.Background(), chat.ChatParams{})
+			backend.InFlight()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(0), backend.InFlight())
+}
+
+func TestRouter_CapacityBackAfterReset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	now := time.Now()
+	a := &limitedProvider{MockProvider: chat.NewMockProvider(ctrl), reset: time.Minute, updatedAt: now}
+	b := chat.NewMockProvider(ctrl)
+	r := NewRouter([]RouterBackend{{Name: "a", Provider: a}, {Name: "b", Provider: b}},
+		WithRouterStrategy(LeastInFlight()))
+	r.now = func() time.Time { return now }
+
+	b.EXPECT().Send(gomock.Any(), gomock.Any()).Return(&chat.ChatResponse{}, nil)
+	resp, err := r.Send(context.Background(), chat.ChatParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, "b", resp.Provider)
+
+	// The reset of a passed, its requests are back
+	r.now = func() time.Time { return now.Add(2 * time.Minute) }
+	a.EXPECT().Send(gomock.Any(), gomock.Any()).Return(&chat.ChatResponse{}, nil)
+	resp, err = r.Send(context.Background(), chat.ChatParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, "a", resp.Provider)
+}
@@ -0,0 +1,76 @@
+package tokenizer
+
+import (
+	"encoding/json"
+
+	"github.com/y0ug/llmhaven/chat"
+)
+
+// Overheads of the chat format, from the OpenAI cookbook. The tools are
+// rendered by the API in a format we don't see, their count is an
+// approximation.
+const (
+	tokensPerMessage = 3
+	tokensReply      = 3 // every reply is primed with <|start|>assistant<|message|>
+	tokensPerTool    = 7
+	tokensTools      = 12
+	// A high detail 1024x1024 image, the real count depends on the size
+	tokensPerImage = 765
+)
+
+// CountChat counts the input tokens of a chat request: messages, system
+// prompt, tools and response format
+func CountChat(e *Encoding, params chat.ChatParams) int {
+	n := tokensReply
+	for _, m := range params.Messages {
+		n += tokensPerMessage + e.Count(m.Role)
+		for _, c := range m.Content {
+			n += countContent(e, c)
+		}
+	}
+
+	if len(params.Tools) > 0 {
+		n += tokensTools
+		for _, t := range params.Tools {
+			n += tokensPerTool + e.Count(t.Name)
+			if t.Description != nil {
+				n += e.Count(*t.Description)
+			}
+			n += countJSON(e, t.InputSchema)
+		}
+	}
+	if f := params.ResponseFormat; f != nil {
+		n += e.Count(f.Name) + e.Count(f.Description) + countJSON(e, f.Schema)
+	}
+	return n
+}
+
+func countContent(e *Encoding, c *chat.MessageContent) int {
+	switch c.Type {
+	case chat.ContentTypeText:
+		return e.Count(c.Text)
+	case chat.ContentTypeToolUse:
+		if len(c.Input) == 0 {
+			return e.Count(c.Name) + e.Count(string(c.InputJson))
+		}
+		return e.Count(c.Name) + e.Count(string(c.Input))
+	case chat.ContentTypeToolResult:
+		return e.Count(c.Content)
+	case chat.ContentTypeImage:
+		return tokensPerImage
+	}
+	// Thinking isn't sent back, documents and audio are converted by the
+	// API and can't be counted here
+	return 0
+}
+
+func countJSON(e *Encoding, v interface{}) int {
+	if v == nil {
+		return 0
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return e.Count(string(b))
+}
@@ -0,0 +1,209 @@
+package tokenizer
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	Cl100kBase = "cl100k_base"
+	O200kBase  = "o200k_base"
+)
+
+// space is \s of tiktoken, the Unicode white spaces. \s of Go is ASCII only.
+const space = `\s\v\x{85}\p{Z}`
+
+// Patterns without the \s+(?!\S) branch, see Encoding.split
+var patterns = map[string]string{
+	Cl100kBase: joinPattern(
+		`(?i:'s|'t|'re|'ve|'m|'ll|'d)`,
+		`[^\r\n\p{L}\p{N}]?\p{L}+`,
+		`\p{N}{1,3}`,
+		` ?[^`+space+`\p{L}\p{N}]+[\r\n]*`,
+		`[`+space+`]*[\r\n]+`,
+		`[`+space+`]+`,
+	),
+	O200kBase: joinPattern(
+		`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?`,
+		`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?`,
+		`\p{N}{1,3}`,
+		` ?[^`+space+`\p{L}\p{N}]+[\r\n/]*`,
+		`[`+space+`]*[\r\n]+`,
+		`[`+space+`]+`,
+	),
+}
+
+// Rank files published by OpenAI and their sha256
+var rankURLs = map[string]struct{ url, hash string }{
+	Cl100kBase: {
+		"https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken",
+		"223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7",
+	},
+	O200kBase: {
+		"https://openaipublic.blob.core.windows.net/encodings/o200k_base.tiktoken",
+		"446a9538cb6c348e3516120d7c08b09f57c36495e2acfffe59a5bf8b0cfb1a2d",
+	},
+}
+
+//go:generate go run gen_ranks.go
+
+// The rank files gzipped by gen_ranks.go, they are used when Dir doesn't
+// have the encoding
+//
+//go:embed ranks
+var rankFiles embed.FS
+
+var embeddedRanks fs.FS = rankFiles
+
+// ErrNoRanks is returned when the rank file of an encoding is neither
+// embedded nor in Dir
+var ErrNoRanks = errors.New("tokenizer rank file not found")
+
+// Dir returns the directory overriding the embedded rank files,
+// LLMHAVEN_TOKENIZER_DIR or llmhaven/tokenizer in the user cache directory
+func Dir() string {
+	if dir := os.Getenv("LLMHAVEN_TOKENIZER_DIR"); dir != "" {
+		return dir
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "llmhaven", "tokenizer")
+}
+
+// ForModel returns the encoding of an OpenAI model, o200k_base for the
+// unknown ones. Other models use their own vocabulary, the count is then an
+// approximation.
+func ForModel(model string) string {
+	model = model[strings.LastIndex(model, "/")+1:]
+	switch {
+	case strings.HasPrefix(model, "gpt-4o"), strings.HasPrefix(model, "gpt-4."):
+		return O200kBase
+	case strings.HasPrefix(model, "gpt-4"), strings.HasPrefix(model, "gpt-3.5"),
+		strings.HasPrefix(model, "text-embedding-"):
+		return Cl100kBase
+	}
+	return O200kBase
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[string]*Encoding{}
+)
+
+// Get returns the encoding named name, its rank file is loaded on the first
+// call from Dir or the embedded ones
+func Get(name string) (*Encoding, error) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if e, ok := cache[name]; ok {
+		return e, nil
+	}
+	pattern, ok := patterns[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding %s", name)
+	}
+
+	r, err := openRanks(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	ranks, err := LoadRanks(r)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %w", name, err)
+	}
+	e, err := NewEncoding(name, pattern, ranks)
+	if err != nil {
+		return nil, err
+	}
+	cache[name] = e
+	return e, nil
+}
+
+// openRanks opens the rank file of name in Dir, or the embedded one
+func openRanks(name string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(Dir(), name+".tiktoken"))
+	if err == nil {
+		return f, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	ef, err := embeddedRanks.Open("ranks/" + name + ".tiktoken.gz")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNoRanks, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	gz, err := gzip.NewReader(ef)
+	if err != nil {
+		ef.Close()
+		return nil, fmt.Errorf("error loading %s: %w", name, err)
+	}
+	return &gzipFile{Reader: gz, file: ef}, nil
+}
+
+type gzipFile struct {
+	*gzip.Reader
+	file fs.File
+}
+
+func (f *gzipFile) Close() error {
+	f.Reader.Close()
+	return f.file.Close()
+}
+
+// Download fetches the rank file of the encoding to Dir, overriding the
+// embedded one. The content is checked against its known hash.
+func Download(ctx context.Context, name string) error {
+	file, ok := rankURLs[name]
+	if !ok {
+		return fmt.Errorf("unknown encoding %s", name)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, file.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error downloading %s: %s", name, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != file.hash {
+		return fmt.Errorf("error downloading %s: hash mismatch", name)
+	}
+
+	dir := Dir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, name+".tiktoken")
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
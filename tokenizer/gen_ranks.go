@@ -0,0 +1,57 @@
+//go:build ignore
+
+// gen_ranks downloads the rank files of the encodings and writes them
+// gzipped to ranks/ to be embedded
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/y0ug/llmhaven/tokenizer"
+)
+
+func main() {
+	dir, err := os.MkdirTemp("", "tokenizer")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("LLMHAVEN_TOKENIZER_DIR", dir)
+
+	for _, name := range []string{tokenizer.Cl100kBase, tokenizer.O200kBase} {
+		if err := tokenizer.Download(context.Background(), name); err != nil {
+			log.Fatal(err)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name+".tiktoken"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := writeGzip(filepath.Join("ranks", name+".tiktoken.gz"), data); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("%s: %d bytes", name, len(data))
+	}
+}
+
+func writeGzip(path string, data []byte) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewWriterLevel(f, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := gz.Write(data); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
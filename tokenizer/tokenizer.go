@@ -0,0 +1,186 @@
+// Package tokenizer is an offline byte pair encoding tokenizer reading the
+// tiktoken rank files (cl100k_base, o200k_base).
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Encoding splits the text with its pattern then merges the bytes of the
+// pieces by rank
+type Encoding struct {
+	Name    string
+	pattern *regexp.Regexp
+	ranks   map[string]int
+}
+
+// NewEncoding creates an encoding from its ranks. pattern must not use the
+// \s+(?!\S) lookahead of tiktoken, Encode emulates it.
+func NewEncoding(name string, pattern string, ranks map[string]int) (*Encoding, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern of %s: %w", name, err)
+	}
+	return &Encoding{Name: name, pattern: re, ranks: ranks}, nil
+}
+
+// LoadRanks reads a tiktoken file, a base64 token and its rank per line
+func LoadRanks(r io.Reader) (map[string]int, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		token, rank, ok := bytes.Cut(line, []byte(" "))
+		if !ok {
+			return nil, fmt.Errorf("line %d: missing rank", n)
+		}
+		b, err := base64.StdEncoding.DecodeString(string(token))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		r, err := strconv.Atoi(string(rank))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		ranks[string(b)] = r
+	}
+	return ranks, scanner.Err()
+}
+
+// Encode returns the tokens of text, special tokens like <|endoftext|> are
+// encoded as ordinary text
+func (e *Encoding) Encode(text string) []int {
+	var tokens []int
+	for _, piece := range e.split(text) {
+		if rank, ok := e.ranks[piece]; ok {
+			tokens = append(tokens, rank)
+			continue
+		}
+		tokens = append(tokens, e.bytePairEncode([]byte(piece))...)
+	}
+	return tokens
+}
+
+// Count returns the number of tokens of text
+func (e *Encoding) Count(text string) int {
+	return len(e.Encode(text))
+}
+
+// split returns the pieces matched by the pattern. Go regexp has no
+// lookahead, \s+(?!\S) is emulated: a run of spaces followed by a non space
+// leaves its last space to the next piece.
+func (e *Encoding) split(text string) []string {
+	var pieces []string
+	for len(text) > 0 {
+		loc := e.pattern.FindStringIndex(text)
+		if loc == nil {
+			return append(pieces, text)
+		}
+		if loc[0] > 0 {
+			pieces = append(pieces, text[:loc[0]])
+		}
+		end := loc[1]
+		if end == loc[0] {
+			// Empty match, skip a rune
+			_, size := utf8.DecodeRuneInString(text[end:])
+			end += size
+		}
+		m := text[loc[0]:end]
+		if end < len(text) && isSpaceRun(m) {
+			if _, size := utf8.DecodeLastRuneInString(m); size < len(m) {
+				end -= size
+				m = m[:len(m)-size]
+			}
+		}
+		pieces = append(pieces, m)
+		text = text[end:]
+	}
+	return pieces
+}
+
+// isSpaceRun reports if s is only spaces without new line, the match of the
+// \s+ branch
+func isSpaceRun(s string) bool {
+	for _, r := range s {
+		if !unicode.IsSpace(r) || r == '\n' || r == '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// bytePairEncode merges the pair of lowest rank until no pair is in the
+// ranks, like tiktoken
+func (e *Encoding) bytePairEncode(piece []byte) []int {
+	type part struct {
+		start int
+		rank  int
+	}
+	rankOf := func(b []byte) int {
+		if r, ok := e.ranks[string(b)]; ok {
+			return r
+		}
+		return math.MaxInt
+	}
+
+	parts := make([]part, len(piece)+1)
+	for i := range parts {
+		parts[i] = part{start: i, rank: math.MaxInt}
+	}
+	for i := 0; i < len(parts)-2; i++ {
+		parts[i].rank = rankOf(piece[parts[i].start:parts[i+2].start])
+	}
+	// rank of the pair starting at i once the pair i+1 is merged in it
+	mergedRank := func(i int) int {
+		if i+3 < len(parts) {
+			return rankOf(piece[parts[i].start:parts[i+3].start])
+		}
+		return math.MaxInt
+	}
+
+	for len(parts) > 1 {
+		minRank, idx := math.MaxInt, -1
+		for i := 0; i < len(parts)-1; i++ {
+			if parts[i].rank < minRank {
+				minRank, idx = parts[i].rank, i
+			}
+		}
+		if idx < 0 {
+			break
+		}
+		parts[idx].rank = mergedRank(idx)
+		if idx > 0 {
+			parts[idx-1].rank = mergedRank(idx - 1)
+		}
+		parts = append(parts[:idx+1], parts[idx+2:]...)
+	}
+
+	tokens := make([]int, 0, len(parts)-1)
+	for i := 0; i < len(parts)-1; i++ {
+		b := piece[parts[i].start:parts[i+1].start]
+		if r, ok := e.ranks[string(b)]; ok {
+			tokens = append(tokens, r)
+			continue
+		}
+		// Only with incomplete ranks, the real files have every byte
+		tokens = append(tokens, -1)
+	}
+	return tokens
+}
+
+func joinPattern(parts ...string) string {
+	return strings.Join(parts, "|")
+}
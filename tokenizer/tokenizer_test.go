@@ -0,0 +1,210 @@
+package tokenizer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/y0ug/llmhaven/chat"
+)
+
+// testRanks has every byte and a few merges
+func testRanks() map[string]int {
+	ranks := map[string]int{}
+	for i := 0; i < 256; i++ {
+		ranks[string([]byte{byte(i)})] = i
+	}
+	ranks["he"] = 256
+	ranks["ll"] = 257
+	ranks["hell"] = 258
+	ranks[" w"] = 259
+	return ranks
+}
+
+func testEncoding(t *testing.T) *Encoding {
+	e, err := NewEncoding(O200kBase, patterns[O200kBase], testRanks())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return e
+}
+
+func TestLoadRanks(t *testing.T) {
+	var buf bytes.Buffer
+	for token, rank := range map[string]int{"a": 0, "he": 1, " world": 2} {
+		fmt.Fprintf(&buf, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+	}
+	ranks, err := LoadRanks(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"a": 0, "he": 1, " world": 2}, ranks)
+
+	_, err = LoadRanks(bytes.NewBufferString("YQ==\n"))
+	assert.Error(t, err)
+}
+
+func TestEncoding_Split(t *testing.T) {
+	e := testEncoding(t)
+	assert.Equal(t, []string{"hello", " world"}, e.split("hello world"))
+	// The last space of a run goes with the next word
+	assert.Equal(t, []string{"hello", "  ", " world"}, e.split("hello   world"))
+	assert.Equal(t, []string{"hello", "  "}, e.split("hello  "))
+	assert.Equal(t, []string{"a", "\n\n", "b"}, e.split("a\n\nb"))
+	assert.Equal(t, []string{"123", "4", " ok", "!"}, e.split("1234 ok!"))
+}
+
+func TestEncoding_Encode(t *testing.T) {
+	e := testEncoding(t)
+	assert.Equal(t, []int{258, 'o'}, e.Encode("hello"))
+	assert.Equal(t, []int{258, 'o', 259, 'o', 'r', 'l', 'd'}, e.Encode("hello world"))
+	assert.Equal(t, 0, e.Count(""))
+	// Multi bytes runes fall back to their bytes
+	assert.Equal(t, 2, e.Count("é"))
+}
+
+func TestForModel(t *testing.T) {
+	assert.Equal(t, O200kBase, ForModel("gpt-4o-mini"))
+	assert.Equal(t, O200kBase, ForModel("openai/gpt-4.1"))
+	assert.Equal(t, Cl100kBase, ForModel("gpt-4-turbo"))
+	assert.Equal(t, Cl100kBase, ForModel("gpt-3.5-turbo"))
+	assert.Equal(t, Cl100kBase, ForModel("text-embedding-3-small"))
+	assert.Equal(t, O200kBase, ForModel("o3-mini"))
+	assert.Equal(t, O200kBase, ForModel("llama3"))
+}
+
+func writeRanks(w io.Writer, ranks map[string]int) {
+	for token, rank := range ranks {
+		fmt.Fprintf(w, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+	}
+}
+
+// useRanks replaces the embedded rank files and empties the cache
+func useRanks(t *testing.T, files fs.FS) {
+	saved := embeddedRanks
+	embeddedRanks = files
+	resetCache := func() {
+		cacheMu.Lock()
+		defer cacheMu.Unlock()
+		cache = map[string]*Encoding{}
+	}
+	resetCache()
+	t.Cleanup(func() {
+		embeddedRanks = saved
+		resetCache()
+	})
+}
+
+func TestGet(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("LLMHAVEN_TOKENIZER_DIR", dir)
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	writeRanks(zw, testRanks())
+	zw.Close()
+	useRanks(t, fstest.MapFS{"ranks/" + O200kBase + ".tiktoken.gz": {Data: gz.Bytes()}})
+
+	_, err := Get(Cl100kBase)
+	assert.ErrorIs(t, err, ErrNoRanks)
+
+	// Embedded
+	e, err := Get(O200kBase)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []int{258, 'o'}, e.Encode("hello"))
+	}
+
+	// Dir overrides the embedded ranks
+	ranks := testRanks()
+	ranks["hello"] = 260
+	var buf bytes.Buffer
+	writeRanks(&buf, ranks)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, Cl100kBase+".tiktoken"), buf.Bytes(), 0o644))
+	e, err = Get(Cl100kBase)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []int{260}, e.Encode("hello"))
+	}
+
+	_, err = Get("p50k_base")
+	assert.Error(t, err)
+}
+
+func TestGet_OpenAIRanks(t *testing.T) {
+	t.Setenv("LLMHAVEN_TOKENIZER_DIR", t.TempDir())
+	useRanks(t, rankFiles)
+
+	// Tokens of tiktoken
+	tests := []struct {
+		encoding string
+		text     string
+		want     []int
+	}{
+		{Cl100kBase, "hello world", []int{15339, 1917}},
+		{Cl100kBase, "tiktoken is great!", []int{83, 1609, 5963, 374, 2294, 0}},
+		{Cl100kBase, "antidisestablishmentarianism", []int{519, 85342, 34500, 479, 8997, 2191}},
+		{Cl100kBase, "hello   world", []int{15339, 256, 1917}},
+		{Cl100kBase, "a  \n  b", []int{64, 2355, 220, 293}},
+		{Cl100kBase, "trailing   ", []int{376, 14612, 262}},
+		{Cl100kBase, "hello \u00a0 world", []int{15339, 17529, 1917}},
+		{Cl100kBase, "x \u2003y", []int{87, 220, 378, 225, 88}},
+		{Cl100kBase, "I'm HTTPServer's", []int{40, 2846, 10339, 5592, 596}},
+		{Cl100kBase, "12345", []int{4513, 1774}},
+		{Cl100kBase, "func main() {\n\tfmt.Println(\"hi\")\n}\n", []int{2900, 1925, 368, 341, 11254, 12701, 446, 6151, 1158, 534}},
+		{Cl100kBase, "こんにちは世界", []int{90115, 3574, 244, 98220}},
+		{O200kBase, "hello world", []int{24912, 2375}},
+		{O200kBase, "tiktoken is great!", []int{83, 8251, 2488, 382, 2212, 0}},
+		{O200kBase, "antidisestablishmentarianism", []int{493, 129901, 376, 160388, 21203, 2367}},
+		{O200kBase, "hello   world", []int{24912, 256, 2375}},
+		{O200kBase, "a  \n  b", []int{64, 4066, 220, 287}},
+		{O200kBase, "trailing   ", []int{371, 24408, 271}},
+		{O200kBase, "hello \u00a0 world", []int{24912, 35753, 2375}},
+		{O200kBase, "x \u2003y", []int{87, 220, 33203, 88}},
+		{O200kBase, "I'm HTTPServer's", []int{15390, 21929, 6444, 885}},
+		{O200kBase, "12345", []int{7633, 2548}},
+		{O200kBase, "func main() {\n\tfmt.Println(\"hi\")\n}\n", []int{5652, 2758, 416, 405, 24728, 28250, 568, 3686, 1896, 739}},
+		{O200kBase, "こんにちは世界", []int{95839, 28428}},
+	}
+	for _, tt := range tests {
+		e, err := Get(tt.encoding)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, tt.want, e.Encode(tt.text), "%s %q", tt.encoding, tt.text)
+	}
+}
+
+func TestEmbeddedRanks_Hash(t *testing.T) {
+	for name, file := range rankURLs {
+		f, err := rankFiles.Open("ranks/" + name + ".tiktoken.gz")
+		if !assert.NoError(t, err, name) {
+			continue
+		}
+		gz, err := gzip.NewReader(f)
+		if assert.NoError(t, err, name) {
+			h := sha256.New()
+			_, err = io.Copy(h, gz)
+			assert.NoError(t, err, name)
+			assert.Equal(t, file.hash, hex.EncodeToString(h.Sum(nil)), name)
+		}
+		f.Close()
+	}
+}
+
+func TestCountChat(t *testing.T) {
+	e := testEncoding(t)
+	params := chat.NewChatParams(chat.WithMessages(chat.NewUserMessage("hello")))
+	// reply + message + role + content
+	assert.Equal(t, 3+3+e.Count("user")+2, CountChat(e, *params))
+
+	base := CountChat(e, *params)
+	params.Update(chat.WithTools(chat.Tool{Name: "hello"}))
+	assert.Equal(t, base+12+7+2, CountChat(e, *params))
+}